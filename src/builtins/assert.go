@@ -0,0 +1,118 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+	"walrus/typechecker"
+)
+
+// AssertionError is the error of a failed assertion, walrus test tells failed
+// tests from tests which could not run by it
+type AssertionError struct {
+	Message string
+}
+
+func (e *AssertionError) Error() string {
+	return e.Message
+}
+
+var assertSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{boolType, strType},
+	IsVariadic: true,
+	ReturnType: voidType,
+}
+
+var assertEqSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{anyType, anyType},
+	ReturnType: voidType,
+}
+
+var assertErrSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{ast.FunctionType{Kind: ast.T_FN}, strType},
+	IsVariadic: true,
+	ReturnType: voidType,
+}
+
+// NativeAssert fails unless its condition is true, assert(cond) or assert(cond, "message")
+func NativeAssert(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	if args[0].(typechecker.BooleanValue).Value {
+		return typechecker.MakeVOID(), nil
+	}
+
+	message := "condition is false"
+
+	if len(args) > 1 {
+		message = joinStrings(args[1:])
+	}
+
+	return nil, failed(0, message)
+}
+
+// NativeAssertEq fails unless a value equals the expected one, assertEq(got, want)
+func NativeAssertEq(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	got, want := args[0], args[1]
+
+	if typechecker.Equal(got, want) {
+		return typechecker.MakeVOID(), nil
+	}
+
+	return nil, failed(0, fmt.Sprintf("expected %s but got %s", describe(want), describe(got)))
+}
+
+// NativeAssertErr fails unless calling a function raises an error,
+// assertErr(f) or assertErr(f, "part of the message")
+func NativeAssertErr(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	_, err := typechecker.TryCall(args[0])
+
+	if err == nil {
+		return nil, failed(0, "expected an error but the call returned")
+	}
+
+	if len(args) == 1 {
+		return typechecker.MakeVOID(), nil
+	}
+
+	text := err.Error()
+
+	var message *parser.ErrorMessage
+
+	if errors.As(err, &message) {
+		text = strings.TrimSpace(message.Text)
+	}
+
+	want := joinStrings(args[1:])
+
+	if !strings.Contains(text, want) {
+		return nil, failed(1, fmt.Sprintf("expected an error containing %q but got %q", want, text))
+	}
+
+	return typechecker.MakeVOID(), nil
+}
+
+// failed blames the argument the assertion is about
+func failed(arg int, message string) error {
+	return &typechecker.ArgumentError{Index: arg, Err: &AssertionError{Message: message}}
+}
+
+// describe renders a value with its type, 1 and 1.0 print alike
+func describe(value typechecker.RuntimeValue) string {
+	return fmt.Sprintf("%s (%s)", typechecker.FormatValue(value), typechecker.GetRuntimeType(value))
+}
+
+func joinStrings(args []typechecker.RuntimeValue) string {
+
+	parts := make([]string, len(args))
+
+	for i, arg := range args {
+		parts[i] = arg.(typechecker.StringValue).Value
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,128 @@
+package builtins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type FsMode string
+
+const (
+	FS_READ  FsMode = "read"
+	FS_WRITE FsMode = "write"
+)
+
+type fsRoot struct {
+	dir      string
+	writable bool
+}
+
+// FsCapability decides which paths the fs natives may touch. Access is granted
+// per root directory, read-only or read-write, and everything else is denied.
+// A nil capability denies everything.
+type FsCapability struct {
+	roots []fsRoot
+}
+
+func NewFsCapability() *FsCapability {
+	return &FsCapability{}
+}
+
+// AllowRead grants read access to dir and everything below it
+func (c *FsCapability) AllowRead(dir string) error {
+	return c.allow(dir, false)
+}
+
+// AllowWrite grants read and write access to dir and everything below it
+func (c *FsCapability) AllowWrite(dir string) error {
+	return c.allow(dir, true)
+}
+
+func (c *FsCapability) allow(dir string, writable bool) error {
+
+	resolved, err := resolvePath(dir)
+
+	if err != nil {
+		return err
+	}
+
+	c.roots = append(c.roots, fsRoot{dir: resolved, writable: writable})
+
+	return nil
+}
+
+// Check returns an error unless the path lies below a root allowing the mode.
+// Symbolic links are resolved first, so a link can not lead out of a root.
+func (c *FsCapability) Check(path string, mode FsMode) error {
+
+	denied := fmt.Errorf("%s access to '%s' is not allowed", mode, path)
+
+	if c == nil {
+		return denied
+	}
+
+	resolved, err := resolvePath(path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, root := range c.roots {
+		if mode == FS_WRITE && !root.writable {
+			continue
+		}
+		if isWithin(root.dir, resolved) {
+			return nil
+		}
+	}
+
+	return denied
+}
+
+func isWithin(root string, path string) bool {
+
+	rel, err := filepath.Rel(root, path)
+
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath returns the absolute path with symbolic links resolved. For a
+// path that does not exist yet, like a file about to be written, the links
+// of its closest existing parent are resolved.
+func resolvePath(path string) (string, error) {
+
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	missing := ""
+
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(abs)
+
+		if parent == abs {
+			return filepath.Join(abs, missing), nil
+		}
+
+		missing = filepath.Join(filepath.Base(abs), missing)
+		abs = parent
+	}
+}
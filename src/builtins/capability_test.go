@@ -0,0 +1,146 @@
+package builtins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+// sandbox makes a directory with a read-only root, a writable root and a
+// directory outside both, ro/out links to the outside one
+func sandbox(t *testing.T) (string, *FsCapability) {
+
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"ro/sub", "rw", "outside"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"ro/a.txt", "rw/b.txt", "outside/secret.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dir, "ro", "out")); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "ro", "sub"), filepath.Join(dir, "rw", "in")); err != nil {
+		t.Fatal(err)
+	}
+
+	capability := NewFsCapability()
+
+	if err := capability.AllowRead(filepath.Join(dir, "ro")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := capability.AllowWrite(filepath.Join(dir, "rw")); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, capability
+}
+
+func TestFsCapabilityCheck(t *testing.T) {
+
+	dir, capability := sandbox(t)
+
+	for _, test := range []struct {
+		name    string
+		path    string
+		mode    FsMode
+		allowed bool
+	}{
+		{"read in a read-only root", "ro/a.txt", FS_READ, true},
+		{"the root itself", "ro", FS_READ, true},
+		{"write in a read-only root", "ro/a.txt", FS_WRITE, false},
+		{"read in a writable root", "rw/b.txt", FS_READ, true},
+		{"write in a writable root", "rw/b.txt", FS_WRITE, true},
+		{"write a new file", "rw/new.txt", FS_WRITE, true},
+		{"write below new directories", "rw/x/y/new.txt", FS_WRITE, true},
+		{"write a new file in a read-only root", "ro/new.txt", FS_WRITE, false},
+		{"outside every root", "outside/secret.txt", FS_READ, false},
+		{"the parent of a root", ".", FS_READ, false},
+		{"a link out of a root", "ro/out/secret.txt", FS_READ, false},
+		{"a new file behind a link out of a root", "ro/out/new.txt", FS_WRITE, false},
+		{"a link into a read-only root", "rw/in", FS_WRITE, false},
+		{"read through a link into a root", "rw/in", FS_READ, true},
+		{"dot dot out of a root", "ro/../outside/secret.txt", FS_READ, false},
+		{"dot dot into another root", "rw/../ro/a.txt", FS_READ, true},
+		{"dot dot into a read-only root", "rw/../ro/new.txt", FS_WRITE, false},
+		{"a sibling sharing the prefix of a root", "rooted", FS_READ, false},
+	} {
+		err := capability.Check(filepath.Join(dir, filepath.FromSlash(test.path)), test.mode)
+
+		if test.allowed && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+
+		if !test.allowed && err == nil {
+			t.Errorf("%s: %s access to %s is allowed", test.name, test.mode, test.path)
+		}
+	}
+}
+
+func TestNilFsCapability(t *testing.T) {
+
+	var capability *FsCapability
+
+	for _, mode := range []FsMode{FS_READ, FS_WRITE} {
+		if err := capability.Check(t.TempDir(), mode); err == nil {
+			t.Errorf("a nil capability allows %s access", mode)
+		}
+	}
+
+	if err := NewFsCapability().Check(t.TempDir(), FS_READ); err == nil {
+		t.Error("a capability without roots allows read access")
+	}
+}
+
+func TestWalkAndGlobOutsideRoots(t *testing.T) {
+
+	dir, capability := sandbox(t)
+
+	visited := 0
+
+	visitor := typechecker.MakeNativeFUNCTION(func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+		visited++
+		return typechecker.MakeBOOL(true), nil
+	}, typechecker.NativeSignature{Parameters: []ast.Type{anyType}, ReturnType: boolType})
+
+	for _, root := range []string{"outside", ".", "ro/.."} {
+		if _, err := NativeWalk(capability)(typechecker.MakeSTRING(filepath.Join(dir, root)), visitor); err == nil {
+			t.Errorf("walked %s outside the roots", root)
+		}
+	}
+
+	if visited != 0 {
+		t.Errorf("the visitor was called %d times outside the roots", visited)
+	}
+
+	for _, pattern := range []string{"outside/*", "*/*.txt", "**/secret.txt", "ro/out/*"} {
+		if _, err := NativeGlob(capability)(typechecker.MakeSTRING(filepath.ToSlash(filepath.Join(dir, pattern)))); err == nil {
+			t.Errorf("glob %s matched outside the roots", pattern)
+		}
+	}
+
+	// ** does not follow the link out of the root
+	result, err := NativeGlob(capability)(typechecker.MakeSTRING(filepath.ToSlash(filepath.Join(dir, "ro/**/*.txt"))))
+
+	if err != nil {
+		t.Fatalf("glob inside a root: %v", err)
+	}
+
+	if matches := result.(typechecker.ArrayValue).Values; len(matches) != 1 || matches[0].(typechecker.StructInstance).Fields["name"].(typechecker.StringValue).Value != "a.txt" {
+		t.Errorf("glob inside a root matched %v, want a.txt", matches)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package builtins
+
+import (
+	"io/fs"
+	"time"
+)
+
+// changeTime falls back to the modification time where the platform does not expose ctime
+func changeTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
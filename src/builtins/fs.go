@@ -0,0 +1,314 @@
+package builtins
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+// FileEntry is the struct handed to walk visitors and returned by glob
+var fileEntryStruct = typechecker.StructValue{
+	Fields: map[string]ast.Property{
+		"path":  {Name: "path", IsPublic: true, ReadOnly: true, Type: ast.StringType{Kind: ast.T_STRING}},
+		"name":  {Name: "name", IsPublic: true, ReadOnly: true, Type: ast.StringType{Kind: ast.T_STRING}},
+		"size":  {Name: "size", IsPublic: true, ReadOnly: true, Type: ast.IntegerType{Kind: ast.T_INTEGER64, BitSize: 64, IsSigned: true}},
+		"isDir": {Name: "isDir", IsPublic: true, ReadOnly: true, Type: ast.BoolType{Kind: ast.T_BOOLEAN}},
+		"mtime": {Name: "mtime", IsPublic: true, ReadOnly: true, Type: instantType},
+		"ctime": {Name: "ctime", IsPublic: true, ReadOnly: true, Type: instantType},
+	},
+	Methods: map[string]ast.FunctionType{},
+	Type:    "FileEntry",
+}
+
+// FsModule returns the core::fs module, every native checks its paths against the capability
+func FsModule(capability *FsCapability) typechecker.ModuleValue {
+
+	// entries carry their times as core::time instants
+	structs := timeStructs()
+	structs["FileEntry"] = fileEntryStruct
+
+	return typechecker.MakeMODULE("core::fs", map[string]typechecker.RuntimeValue{
+		"walk": typechecker.MakeNativeFUNCTION(NativeWalk(capability), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, ast.FunctionType{Kind: ast.T_FN}},
+			ReturnType: ast.VoidType{Kind: ast.T_VOID},
+		}),
+		"glob": typechecker.MakeNativeFUNCTION(NativeGlob(capability), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: ast.ArrayType{Kind: ast.T_ARRAY, ElementType: "FileEntry"},
+		}),
+		"stat": typechecker.MakeNativeFUNCTION(NativeStat(capability), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: ast.StructType{Kind: "FileEntry"},
+		}),
+		"read": typechecker.MakeNativeFUNCTION(NativeRead(capability), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: strType,
+		}),
+		"readFile": typechecker.MakeNativeFUNCTION(resultOf(NativeRead(capability)), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: ast.ResultOf(strType, strType),
+		}),
+		"write": typechecker.MakeNativeFUNCTION(NativeWrite(capability), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, strType},
+			ReturnType: voidType,
+		}),
+		"writeFile": typechecker.MakeNativeFUNCTION(resultOf(NativeWrite(capability)), typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, strType},
+			ReturnType: ast.ResultOf(voidType, strType),
+		}),
+	}, structs)
+}
+
+func makeFileEntry(filePath string, info fs.FileInfo) typechecker.StructInstance {
+	return typechecker.StructInstance{
+		StructName: "FileEntry",
+		Fields: map[string]typechecker.RuntimeValue{
+			"path":  typechecker.MakeSTRING(filePath),
+			"name":  typechecker.MakeSTRING(info.Name()),
+			"size":  typechecker.MakeINT(info.Size(), 64, true),
+			"isDir": typechecker.MakeBOOL(info.IsDir()),
+			"mtime": MakeInstant(info.ModTime()),
+			"ctime": MakeInstant(changeTime(info)),
+		},
+	}
+}
+
+func NativeStat(capability *FsCapability) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		filePath := stringArg(args, 0)
+
+		if err := capability.Check(filePath, FS_READ); err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(filePath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return makeFileEntry(filePath, info), nil
+	}
+}
+
+// NativeRead returns the contents of a file as a string
+func NativeRead(capability *FsCapability) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		filePath := stringArg(args, 0)
+
+		if err := capability.Check(filePath, FS_READ); err != nil {
+			return nil, err
+		}
+
+		bytes, err := os.ReadFile(filePath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return typechecker.MakeSTRING(string(bytes)), nil
+	}
+}
+
+// resultOf makes a native return a Result, Ok with its value or Err with the
+// message of its error. fs.readFile is fs.read returning a Result.
+func resultOf(call typechecker.FunctionCall) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		value, err := call(args...)
+
+		if err != nil {
+			return typechecker.MakeERR(typechecker.MakeSTRING(err.Error())), nil
+		}
+
+		return typechecker.MakeOK(value), nil
+	}
+}
+
+// NativeWrite creates or truncates a file and writes the string to it
+func NativeWrite(capability *FsCapability) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		filePath := stringArg(args, 0)
+
+		if err := capability.Check(filePath, FS_WRITE); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(filePath, []byte(stringArg(args, 1)), 0644); err != nil {
+			return nil, err
+		}
+
+		return typechecker.MakeVOID(), nil
+	}
+}
+
+// NativeWalk calls the visitor for every entry under root, the root included.
+// A visitor returning false for a directory skips everything below it.
+func NativeWalk(capability *FsCapability) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		root := stringArg(args, 0)
+
+		// the walk does not follow links, so everything it visits lies below the root
+		if err := capability.Check(root, FS_READ); err != nil {
+			return nil, err
+		}
+
+		err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			result, err := typechecker.CallFunction(args[1], makeFileEntry(filePath, info))
+			if err != nil {
+				return err
+			}
+
+			if skip, ok := result.(typechecker.BooleanValue); ok && d.IsDir() && !skip.Value {
+				return filepath.SkipDir
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return typechecker.MakeVOID(), nil
+	}
+}
+
+// NativeGlob returns the entries matching a pattern like "src/**/*.go" in lexical order
+func NativeGlob(capability *FsCapability) typechecker.FunctionCall {
+	return func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+		pattern := stringArg(args, 0)
+
+		if err := capability.Check(filepath.FromSlash(globRoot(path.Clean(filepath.ToSlash(pattern)))), FS_READ); err != nil {
+			return nil, err
+		}
+
+		matches, err := Glob(pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values := []typechecker.RuntimeValue{}
+
+		for _, match := range matches {
+			if err := capability.Check(match, FS_READ); err != nil {
+				return nil, err
+			}
+			info, err := os.Lstat(match)
+			if err != nil {
+				continue
+			}
+			values = append(values, makeFileEntry(match, info))
+		}
+
+		return typechecker.ArrayValue{
+			Values: values,
+			Type:   ast.T_ARRAY,
+		}, nil
+	}
+}
+
+// Glob expands a pattern in the filepath.Match syntax where a "**" segment
+// also matches any number of nested directories
+func Glob(pattern string) ([]string, error) {
+
+	pattern = path.Clean(filepath.ToSlash(pattern))
+
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(filepath.FromSlash(pattern))
+	}
+
+	var matches []string
+
+	err := filepath.WalkDir(globRoot(pattern), func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if MatchGlob(pattern, filepath.ToSlash(filePath)) {
+			matches = append(matches, filePath)
+		}
+		return nil
+	})
+
+	sort.Strings(matches)
+
+	return matches, err
+}
+
+// MatchGlob reports whether name matches the pattern segment by segment
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+
+	for len(pattern) > 0 {
+
+		if pattern[0] == "**" {
+			// try to match the rest of the pattern after skipping any number of segments
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// globRoot returns the leading directories of a pattern that contain no wildcards
+func globRoot(pattern string) string {
+
+	segments := strings.Split(pattern, "/")
+
+	var root []string
+
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		root = append(root, segment)
+	}
+
+	switch {
+	case len(root) == 0:
+		return "."
+	case len(root) == 1 && root[0] == "":
+		return "/"
+	default:
+		return strings.Join(root, "/")
+	}
+}
@@ -0,0 +1,156 @@
+package builtins
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+// tree makes files with contents below a temporary directory
+func tree(t *testing.T, files map[string]string) string {
+
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+var files = map[string]string{
+	"z.txt":     "zz",
+	"a.txt":     "hello",
+	"b/c.txt":   "x",
+	"b/d/e.txt": "long contents",
+	"b/d/f.go":  "package f",
+}
+
+// relative returns the paths below dir with slashes
+func relative(t *testing.T, dir string, paths []string) []string {
+
+	t.Helper()
+
+	rel := []string{}
+
+	for _, path := range paths {
+		r, err := filepath.Rel(dir, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+
+	return rel
+}
+
+func TestMatchGlob(t *testing.T) {
+
+	for _, test := range []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/main.go", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/c", false},
+		{"a/**", "a", true},
+		{"a/*.go", "a/b/c.go", false},
+		{"a/?.go", "a/b.go", true},
+		{"a/[", "a/[", false},
+	} {
+		if got := MatchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+
+	for pattern, want := range map[string]string{
+		"src/**/*.go": "src",
+		"*.go":        ".",
+		"/tmp/*/x":    "/tmp",
+		"/*":          "/",
+		"a/b/c":       "a/b/c",
+		"a/b?/c":      "a",
+		"a/[bc]/d":    "a",
+	} {
+		if got := globRoot(pattern); got != want {
+			t.Errorf("globRoot(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+
+	dir := tree(t, files)
+
+	for _, test := range []struct {
+		pattern string
+		want    []string
+	}{
+		// ** matches no directory as well as nested ones, the matches come in lexical order
+		{"**/*.txt", []string{"a.txt", "b/c.txt", "b/d/e.txt", "z.txt"}},
+		{"b/**", []string{"b", "b/c.txt", "b/d", "b/d/e.txt", "b/d/f.go"}},
+		{"**/d/*.go", []string{"b/d/f.go"}},
+		{"b/**/c.txt", []string{"b/c.txt"}},
+		{"*.txt", []string{"a.txt", "z.txt"}},
+		{"**/*.rs", []string{}},
+	} {
+		matches, err := Glob(filepath.ToSlash(dir) + "/" + test.pattern)
+
+		if err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+
+		if got := relative(t, dir, matches); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s matched %v, want %v", test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestNativeWalk(t *testing.T) {
+
+	dir := tree(t, files)
+
+	capability := NewFsCapability()
+
+	if err := capability.AllowRead(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+
+	// the visitor skips everything below d
+	visitor := typechecker.MakeNativeFUNCTION(func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+		entry := args[0].(typechecker.StructInstance)
+		visited = append(visited, entry.Fields["path"].(typechecker.StringValue).Value)
+		return typechecker.MakeBOOL(entry.Fields["name"].(typechecker.StringValue).Value != "d"), nil
+	}, typechecker.NativeSignature{Parameters: []ast.Type{anyType}, ReturnType: boolType})
+
+	if _, err := NativeWalk(capability)(typechecker.MakeSTRING(dir), visitor); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{".", "a.txt", "b", "b/c.txt", "b/d", "z.txt"}
+
+	if got := relative(t, dir, visited); !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+}
@@ -2,44 +2,141 @@ package builtins
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"walrus/frontend/ast"
 	"walrus/typechecker"
 )
 
-func NativePrint(args ...typechecker.RuntimeValue) typechecker.RuntimeValue {
+func NativePrint(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return printTo(os.Stdout, args)
+}
 
-	//if no arguments
-	if len(args) == 0 {
-		fmt.Println()
-		return typechecker.MakeVOID()
-	}
+// Print returns a print native writing to w, hosts capture what a program prints with it.
+// Each call writes its line at once.
+func Print(w io.Writer) typechecker.NativeFunctionValue {
+	return typechecker.MakeNativeFUNCTION(func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+		return printTo(w, args)
+	}, printSignature)
+}
+
+var printSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{anyType},
+	IsVariadic: true,
+	ReturnType: voidType,
+}
+
+func printTo(w io.Writer, args []typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	var line strings.Builder
 
 	for _, arg := range args {
 		val, err := typechecker.CastToStringValue(arg)
 
 		if err != nil {
-			continue
+			return nil, err
 		}
 
-		//colorize
-		fmt.Print(val.Value)
+		line.WriteString(val.Value)
+	}
+
+	line.WriteString("\n")
+
+	if _, err := io.WriteString(w, line.String()); err != nil {
+		return nil, err
 	}
-	fmt.Println()
-	return typechecker.MakeVOID()
+
+	return typechecker.MakeVOID(), nil
 }
 
-func NativeTime(args ...typechecker.RuntimeValue) typechecker.RuntimeValue {
+func NativeTime(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
 	t := time.Now().Unix()
-	return typechecker.MakeINT(t, 64, true)
+	return typechecker.MakeINT(t, 64, true), nil
 }
 
-func NativeLen(args ...typechecker.RuntimeValue) typechecker.RuntimeValue {
+func NativeLen(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
 	switch a := args[0].(type) {
 	case typechecker.ArrayValue:
 		size := len(a.Values)		
-		return typechecker.MakeINT(int64(size), 64, true)
+		return typechecker.MakeINT(int64(size), 64, true), nil
+	case typechecker.StringValue:
+		return typechecker.MakeINT(int64(len(a.Value)), 64, true), nil
+	case typechecker.MapValue:
+		return typechecker.MakeINT(int64(a.Len()), 64, true), nil
 	default:
-		panic("invalid argument for len()")
+		return nil, fmt.Errorf("cannot take length of '%s'", typechecker.GetRuntimeType(a))
 	}
-}
\ No newline at end of file
+}
+
+var appendSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_ANY}, anyType},
+	IsVariadic: true,
+	ReturnType: ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_ANY},
+}
+
+// NativeAppend returns a new array with the values after the elements of an array, append(xs, 4, 5).
+// The array itself is left alone, the new one counts against the memory limit like a literal.
+func NativeAppend(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	array := args[0].(typechecker.ArrayValue)
+
+	values := make([]typechecker.RuntimeValue, 0, len(array.Values)+len(args)-1)
+	values = append(values, array.Values...)
+	values = append(values, args[1:]...)
+
+	return typechecker.ArrayValue{Values: values, Type: ast.T_ARRAY}, nil
+}
+
+var sortSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{
+		ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_ANY},
+		ast.FunctionType{
+			Kind:       ast.T_FN,
+			ReturnType: boolType,
+			Parameters: []ast.FunctionParameter{{Type: anyType}, {Type: anyType}},
+		},
+	},
+	ReturnType: voidType,
+}
+
+// NativeSort sorts an array in place with a less function, sort(values, fn(a: i32, b: i32) -> bool { ret a < b; }).
+// The sort is stable, elements the function does not order keep their order.
+func NativeSort(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	values := args[0].(typechecker.ArrayValue).Values
+
+	var failure error
+
+	sort.SliceStable(values, func(i, j int) bool {
+
+		if failure != nil {
+			return false
+		}
+
+		result, err := typechecker.CallFunction(args[1], values[i], values[j])
+
+		if err != nil {
+			failure = err
+			return false
+		}
+
+		less, ok := result.(typechecker.BooleanValue)
+
+		if !ok {
+			failure = &typechecker.ArgumentError{Index: 1, Err: fmt.Errorf("the less function must return a bool but returned '%s'", typechecker.GetRuntimeType(result))}
+			return false
+		}
+
+		return less.Value
+	})
+
+	if failure != nil {
+		return nil, failure
+	}
+
+	return typechecker.MakeVOID(), nil
+}
@@ -0,0 +1,82 @@
+package builtins
+
+import (
+	"sort"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+// the natives take maps of every type
+var anyMapType = ast.MapType{Kind: ast.T_MAP, KeyType: anyType, ValueType: anyType}
+
+var anyArrayType = ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_ANY}
+
+var mapKeySignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{anyMapType, anyType},
+	ReturnType: boolType,
+}
+
+var keysSignature = typechecker.NativeSignature{
+	Parameters: []ast.Type{anyMapType},
+	ReturnType: anyArrayType,
+}
+
+// NativeHas tells if a map has a key, has(m, key) is key in m
+func NativeHas(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	m := args[0].(typechecker.MapValue)
+
+	key, err := m.CheckKey(args[1])
+
+	if err != nil {
+		return nil, &typechecker.ArgumentError{Index: 1, Err: err}
+	}
+
+	_, ok := m.Get(key)
+
+	return typechecker.MakeBOOL(ok), nil
+}
+
+// NativeDelete removes a key from a map, it returns false when the map did not have it
+func NativeDelete(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	m := args[0].(typechecker.MapValue)
+
+	key, err := m.CheckKey(args[1])
+
+	if err != nil {
+		return nil, &typechecker.ArgumentError{Index: 1, Err: err}
+	}
+
+	return typechecker.MakeBOOL(m.Delete(key)), nil
+}
+
+// NativeKeys returns the keys of a map in the order they were inserted
+func NativeKeys(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.ArrayValue{
+		Values: args[0].(typechecker.MapValue).Keys(),
+		Type:   ast.T_ARRAY,
+	}, nil
+}
+
+// NativeSortedKeys returns the keys of a map in ascending order, strings are
+// compared byte by byte and false comes before true
+func NativeSortedKeys(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+
+	keys := args[0].(typechecker.MapValue).Keys()
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if a, ok := keys[i].(typechecker.StringValue); ok {
+			return a.Value < keys[j].(typechecker.StringValue).Value
+		}
+		a, _ := typechecker.GetNumericValue(keys[i])
+		b, _ := typechecker.GetNumericValue(keys[j])
+		return a < b
+	})
+
+	return typechecker.ArrayValue{
+		Values: keys,
+		Type:   ast.T_ARRAY,
+	}, nil
+}
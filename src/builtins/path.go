@@ -0,0 +1,119 @@
+package builtins
+
+import (
+	"path/filepath"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+var strType = ast.StringType{Kind: ast.T_STRING}
+var boolType = ast.BoolType{Kind: ast.T_BOOLEAN}
+var strArrayType = ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_STRING}
+
+// PathModule returns the core::path module, a thin layer over path/filepath
+func PathModule() typechecker.ModuleValue {
+
+	// most helpers take a single path and return a string
+	unary := func(call typechecker.FunctionCall) typechecker.NativeFunctionValue {
+		return typechecker.MakeNativeFUNCTION(call, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: strType,
+		})
+	}
+
+	return typechecker.MakeMODULE("core::path", map[string]typechecker.RuntimeValue{
+		"join": typechecker.MakeNativeFUNCTION(NativePathJoin, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			IsVariadic: true,
+			ReturnType: strType,
+		}),
+		"base":  unary(NativePathBase),
+		"dir":   unary(NativePathDir),
+		"ext":   unary(NativePathExt),
+		"stem":  unary(NativePathStem),
+		"abs":   unary(NativePathAbs),
+		"clean": unary(NativePathClean),
+		"rel": typechecker.MakeNativeFUNCTION(NativePathRel, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, strType},
+			ReturnType: strType,
+		}),
+		"split": typechecker.MakeNativeFUNCTION(NativePathSplit, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType},
+			ReturnType: strArrayType,
+		}),
+		"match": typechecker.MakeNativeFUNCTION(NativePathMatch, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, strType},
+			ReturnType: boolType,
+		}),
+	}, nil)
+}
+
+// stringArg reads an argument the signature already guaranteed to be a string
+func stringArg(args []typechecker.RuntimeValue, i int) string {
+	return args[i].(typechecker.StringValue).Value
+}
+
+func NativePathJoin(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	parts := make([]string, len(args))
+	for i := range args {
+		parts[i] = stringArg(args, i)
+	}
+	return typechecker.MakeSTRING(filepath.Join(parts...)), nil
+}
+
+func NativePathBase(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeSTRING(filepath.Base(stringArg(args, 0))), nil
+}
+
+func NativePathDir(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeSTRING(filepath.Dir(stringArg(args, 0))), nil
+}
+
+func NativePathExt(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeSTRING(filepath.Ext(stringArg(args, 0))), nil
+}
+
+// NativePathStem returns the file name without its extension, "a/report.tar.gz" -> "report.tar"
+func NativePathStem(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	base := filepath.Base(stringArg(args, 0))
+	return typechecker.MakeSTRING(strings.TrimSuffix(base, filepath.Ext(base))), nil
+}
+
+func NativePathAbs(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	abs, err := filepath.Abs(stringArg(args, 0))
+	if err != nil {
+		return nil, err
+	}
+	return typechecker.MakeSTRING(abs), nil
+}
+
+func NativePathRel(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	rel, err := filepath.Rel(stringArg(args, 0), stringArg(args, 1))
+	if err != nil {
+		return nil, err
+	}
+	return typechecker.MakeSTRING(rel), nil
+}
+
+func NativePathClean(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeSTRING(filepath.Clean(stringArg(args, 0))), nil
+}
+
+// NativePathSplit splits a path into its directory and file name, ["a/b/", "c.txt"]
+func NativePathSplit(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	dir, file := filepath.Split(stringArg(args, 0))
+	return typechecker.ArrayValue{
+		Values: []typechecker.RuntimeValue{typechecker.MakeSTRING(dir), typechecker.MakeSTRING(file)},
+		Type:   ast.T_ARRAY,
+	}, nil
+}
+
+func NativePathMatch(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	matched, err := filepath.Match(stringArg(args, 0), stringArg(args, 1))
+	if err != nil {
+		return nil, err
+	}
+	return typechecker.MakeBOOL(matched), nil
+}
@@ -0,0 +1,109 @@
+package builtins
+
+import (
+	"walrus/frontend/ast"
+	"walrus/tc"
+	"walrus/typechecker"
+)
+
+var anyType = ast.AnyType{Kind: ast.T_ANY}
+var voidType = ast.VoidType{Kind: ast.T_VOID}
+
+// Globals returns the natives available in every program without an import
+func Globals() map[string]typechecker.NativeFunctionValue {
+	return map[string]typechecker.NativeFunctionValue{
+		"print": typechecker.MakeNativeFUNCTION(NativePrint, printSignature),
+		"time": typechecker.MakeNativeFUNCTION(NativeTime, typechecker.NativeSignature{
+			ReturnType: i64Type,
+		}),
+		"len": typechecker.MakeNativeFUNCTION(NativeLen, typechecker.NativeSignature{
+			Parameters: []ast.Type{anyType},
+			ReturnType: i64Type,
+		}),
+		"append":     typechecker.MakeNativeFUNCTION(NativeAppend, appendSignature),
+		"sort":       typechecker.MakeNativeFUNCTION(NativeSort, sortSignature),
+		"has":        typechecker.MakeNativeFUNCTION(NativeHas, mapKeySignature),
+		"delete":     typechecker.MakeNativeFUNCTION(NativeDelete, mapKeySignature),
+		"keys":       typechecker.MakeNativeFUNCTION(NativeKeys, keysSignature),
+		"sortedKeys": typechecker.MakeNativeFUNCTION(NativeSortedKeys, keysSignature),
+		"assert":     typechecker.MakeNativeFUNCTION(NativeAssert, assertSignature),
+		"assertEq":   typechecker.MakeNativeFUNCTION(NativeAssertEq, assertEqSignature),
+		"assertErr":  typechecker.MakeNativeFUNCTION(NativeAssertErr, assertErrSignature),
+	}
+}
+
+// Modules returns the native modules by their import path, the fs natives are limited by the capability
+func Modules(fsCapability *FsCapability) map[string]typechecker.ModuleValue {
+	return map[string]typechecker.ModuleValue{
+		"core::fs":   FsModule(fsCapability),
+		"core::path": PathModule(),
+		"core::time": TimeModule(),
+	}
+}
+
+// Declare fills an environment with the builtin constants, natives and modules
+func Declare(env *typechecker.Environment, fsCapability *FsCapability) {
+
+	env.DeclareVariable("true", typechecker.MakeBOOL(true), true)
+	env.DeclareVariable("false", typechecker.MakeBOOL(false), true)
+	env.DeclareVariable("null", typechecker.MakeNULL(), true)
+
+	for name, native := range Globals() {
+		env.DeclareNativeFn(name, native)
+	}
+
+	// Result<T, E: error> is the value of the natives that may fail
+	result := ast.ResultEnum()
+	env.DeclareEnum(typechecker.EnumValue{
+		Name:       string(result.Kind),
+		TypeParams: result.TypeParams,
+		Variants:   result.Variants,
+		Type:       ast.T_ENUM,
+	})
+	env.DeclareImpl(ast.T_STRING, ast.ERROR_TRAIT)
+
+	for name, module := range Modules(fsCapability) {
+		env.DeclareModule(name, module)
+	}
+}
+
+// DeclareTypes gives the static checker the same builtins with their declared signatures
+func DeclareTypes(env *tc.TypeEnv) {
+
+	env.DeclareVar("true", ast.BoolType{Kind: ast.T_BOOLEAN}, true)
+	env.DeclareVar("false", ast.BoolType{Kind: ast.T_BOOLEAN}, true)
+	env.DeclareVar("null", ast.NullType{Kind: ast.T_NULL}, true)
+
+	for name, native := range Globals() {
+		env.DeclareVar(name, native.Signature.FunctionType(), true)
+	}
+
+	result := ast.ResultEnum()
+	env.DeclareEnum(string(result.Kind), result)
+	env.DeclareVar(string(result.Kind), result, true)
+	env.DeclareTrait(ast.ERROR_TRAIT)
+	env.DeclareImpl(ast.T_STRING, ast.ERROR_TRAIT)
+
+	// only the signatures are used, so no capability is needed
+	for name, module := range Modules(nil) {
+		env.DeclareModule(name, ModuleType(module))
+	}
+}
+
+// ModuleType builds the static type of a module from the signatures of its natives
+func ModuleType(module typechecker.ModuleValue) ast.ModuleType {
+
+	members := map[string]ast.Type{}
+
+	for member, value := range module.Members {
+		if native, ok := value.(typechecker.NativeFunctionValue); ok {
+			members[member] = native.Signature.FunctionType()
+		}
+	}
+
+	return ast.ModuleType{
+		Kind:    ast.T_MODULE,
+		Name:    module.Name,
+		Members: members,
+	}
+}
@@ -0,0 +1,230 @@
+package builtins
+
+import (
+	"time"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+var i32Type = ast.IntegerType{Kind: ast.T_INTEGER32, BitSize: 32, IsSigned: true}
+var i64Type = ast.IntegerType{Kind: ast.T_INTEGER64, BitSize: 64, IsSigned: true}
+var f64Type = ast.FloatType{Kind: ast.T_FLOAT64, BitSize: 64}
+var instantType = ast.StructType{Kind: "Instant"}
+var durationType = ast.StructType{Kind: "Duration"}
+
+func publicField(name string, fieldType ast.Type) ast.Property {
+	return ast.Property{Name: name, IsPublic: true, ReadOnly: true, Type: fieldType}
+}
+
+// Instant is a point in time, nanos since the unix epoch in the named zone.
+// The offset is in seconds east of UTC, it keeps the zone of a time parsed
+// with a fixed offset like +06:00, which has no name to load it by.
+var instantStruct = typechecker.StructValue{
+	Fields: map[string]ast.Property{
+		"nanos":   publicField("nanos", i64Type),
+		"unix":    publicField("unix", i64Type),
+		"zone":    publicField("zone", strType),
+		"offset":  publicField("offset", i32Type),
+		"year":    publicField("year", i32Type),
+		"month":   publicField("month", i32Type),
+		"day":     publicField("day", i32Type),
+		"hour":    publicField("hour", i32Type),
+		"minute":  publicField("minute", i32Type),
+		"second":  publicField("second", i32Type),
+		"weekday": publicField("weekday", strType),
+	},
+	Methods:   map[string]ast.FunctionType{},
+	Type:      "Instant",
+	OrderedBy: "nanos",
+}
+
+// Duration is the elapsed time between two instants
+var durationStruct = typechecker.StructValue{
+	Fields: map[string]ast.Property{
+		"nanos":   publicField("nanos", i64Type),
+		"seconds": publicField("seconds", f64Type),
+		"text":    publicField("text", strType),
+	},
+	Methods:   map[string]ast.FunctionType{},
+	Type:      "Duration",
+	OrderedBy: "nanos",
+}
+
+// named layouts accepted by format and parse besides the go reference layout
+var namedLayouts = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"RFC1123":  time.RFC1123,
+	"DateTime": "2006-01-02 15:04:05",
+	"Date":     "2006-01-02",
+	"Time":     "15:04:05",
+	"Kitchen":  time.Kitchen,
+}
+
+func timeStructs() map[string]typechecker.StructValue {
+	return map[string]typechecker.StructValue{
+		"Instant":  instantStruct,
+		"Duration": durationStruct,
+	}
+}
+
+// TimeModule returns the core::time module
+func TimeModule() typechecker.ModuleValue {
+
+	// the duration constructors all take a count of the unit
+	unit := func(size time.Duration) typechecker.NativeFunctionValue {
+		return typechecker.MakeNativeFUNCTION(func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+			return MakeDuration(time.Duration(args[0].(typechecker.IntegerValue).Value) * size), nil
+		}, typechecker.NativeSignature{
+			Parameters: []ast.Type{i64Type},
+			ReturnType: durationType,
+		})
+	}
+
+	return typechecker.MakeMODULE("core::time", map[string]typechecker.RuntimeValue{
+		"now": typechecker.MakeNativeFUNCTION(NativeNow, typechecker.NativeSignature{
+			ReturnType: instantType,
+		}),
+		"unix": typechecker.MakeNativeFUNCTION(NativeUnix, typechecker.NativeSignature{
+			Parameters: []ast.Type{i64Type},
+			ReturnType: instantType,
+		}),
+		"format": typechecker.MakeNativeFUNCTION(NativeFormat, typechecker.NativeSignature{
+			Parameters: []ast.Type{instantType, strType},
+			ReturnType: strType,
+		}),
+		"parse": typechecker.MakeNativeFUNCTION(NativeParse, typechecker.NativeSignature{
+			Parameters: []ast.Type{strType, strType},
+			ReturnType: instantType,
+		}),
+		"inZone": typechecker.MakeNativeFUNCTION(NativeInZone, typechecker.NativeSignature{
+			Parameters: []ast.Type{instantType, strType},
+			ReturnType: instantType,
+		}),
+		"add": typechecker.MakeNativeFUNCTION(NativeAdd, typechecker.NativeSignature{
+			Parameters: []ast.Type{instantType, durationType},
+			ReturnType: instantType,
+		}),
+		"sub": typechecker.MakeNativeFUNCTION(NativeSub, typechecker.NativeSignature{
+			Parameters: []ast.Type{instantType, instantType},
+			ReturnType: durationType,
+		}),
+		"since": typechecker.MakeNativeFUNCTION(NativeSince, typechecker.NativeSignature{
+			Parameters: []ast.Type{instantType},
+			ReturnType: durationType,
+		}),
+		"millis":  unit(time.Millisecond),
+		"seconds": unit(time.Second),
+		"minutes": unit(time.Minute),
+		"hours":   unit(time.Hour),
+		"days":    unit(24 * time.Hour),
+	}, timeStructs())
+}
+
+// MakeInstant converts a go time into an Instant struct instance
+func MakeInstant(t time.Time) typechecker.StructInstance {
+
+	_, offset := t.Zone()
+
+	return typechecker.StructInstance{
+		StructName: "Instant",
+		Fields: map[string]typechecker.RuntimeValue{
+			"nanos":   typechecker.MakeINT(t.UnixNano(), 64, true),
+			"unix":    typechecker.MakeINT(t.Unix(), 64, true),
+			"zone":    typechecker.MakeSTRING(t.Location().String()),
+			"offset":  typechecker.MakeINT(int64(offset), 32, true),
+			"year":    typechecker.MakeINT(int64(t.Year()), 32, true),
+			"month":   typechecker.MakeINT(int64(t.Month()), 32, true),
+			"day":     typechecker.MakeINT(int64(t.Day()), 32, true),
+			"hour":    typechecker.MakeINT(int64(t.Hour()), 32, true),
+			"minute":  typechecker.MakeINT(int64(t.Minute()), 32, true),
+			"second":  typechecker.MakeINT(int64(t.Second()), 32, true),
+			"weekday": typechecker.MakeSTRING(t.Weekday().String()),
+		},
+	}
+}
+
+// ToTime converts an Instant back into a go time in its zone. A zone that
+// can not be loaded by its name, like the unnamed one of a parsed +06:00,
+// is rebuilt from the offset.
+func ToTime(instant typechecker.RuntimeValue) time.Time {
+
+	fields := instant.(typechecker.StructInstance).Fields
+
+	t := time.Unix(0, fields["nanos"].(typechecker.IntegerValue).Value)
+
+	zone := fields["zone"].(typechecker.StringValue).Value
+	offset := int(fields["offset"].(typechecker.IntegerValue).Value)
+
+	// an empty name loads UTC, whatever the offset is
+	if location, err := time.LoadLocation(zone); err == nil && zone != "" {
+		return t.In(location)
+	}
+
+	return t.In(time.FixedZone(zone, offset))
+}
+
+// MakeDuration converts a go duration into a Duration struct instance
+func MakeDuration(d time.Duration) typechecker.StructInstance {
+	return typechecker.StructInstance{
+		StructName: "Duration",
+		Fields: map[string]typechecker.RuntimeValue{
+			"nanos":   typechecker.MakeINT(int64(d), 64, true),
+			"seconds": typechecker.MakeFLOAT(d.Seconds(), 64),
+			"text":    typechecker.MakeSTRING(d.String()),
+		},
+	}
+}
+
+func toDuration(duration typechecker.RuntimeValue) time.Duration {
+	return time.Duration(duration.(typechecker.StructInstance).Fields["nanos"].(typechecker.IntegerValue).Value)
+}
+
+func layout(name string) string {
+	if goLayout, ok := namedLayouts[name]; ok {
+		return goLayout
+	}
+	return name
+}
+
+func NativeNow(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return MakeInstant(time.Now()), nil
+}
+
+func NativeUnix(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return MakeInstant(time.Unix(args[0].(typechecker.IntegerValue).Value, 0)), nil
+}
+
+// NativeFormat formats an instant with a go reference layout or a named one like "RFC3339"
+func NativeFormat(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeSTRING(ToTime(args[0]).Format(layout(stringArg(args, 1)))), nil
+}
+
+func NativeParse(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	t, err := time.Parse(layout(stringArg(args, 0)), stringArg(args, 1))
+	if err != nil {
+		return nil, err
+	}
+	return MakeInstant(t), nil
+}
+
+// NativeInZone converts an instant to a zone like "UTC", "Local" or "Asia/Dhaka"
+func NativeInZone(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	location, err := time.LoadLocation(stringArg(args, 1))
+	if err != nil {
+		return nil, err
+	}
+	return MakeInstant(ToTime(args[0]).In(location)), nil
+}
+
+func NativeAdd(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return MakeInstant(ToTime(args[0]).Add(toDuration(args[1]))), nil
+}
+
+func NativeSub(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return MakeDuration(ToTime(args[0]).Sub(ToTime(args[1]))), nil
+}
+
+func NativeSince(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return MakeDuration(time.Since(ToTime(args[0]))), nil
+}
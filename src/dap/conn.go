@@ -0,0 +1,70 @@
+package dap
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"walrus/framing"
+)
+
+// conn reads and writes messages framed by a Content-Length header, the
+// framing the language server uses too. Every message it writes gets the next
+// sequence number.
+type conn struct {
+	*framing.Conn
+	mu  sync.Mutex
+	seq int
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{Conn: framing.NewConn(in, out)}
+}
+
+// write numbers a response or an event and sends it, the program goroutine
+// sends events while the server answers requests
+func (c *conn) write(msg message) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+
+	msg.setSeq(c.seq)
+
+	body, err := json.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Write(body)
+}
+
+func (c *conn) reply(req *request, body interface{}) error {
+	return c.write(&response{
+		protocolMessage: protocolMessage{Type: "response"},
+		RequestSeq:      req.Seq,
+		Success:         true,
+		Command:         req.Command,
+		Body:            body,
+	})
+}
+
+func (c *conn) replyError(req *request, text string) error {
+	return c.write(&response{
+		protocolMessage: protocolMessage{Type: "response"},
+		RequestSeq:      req.Seq,
+		Command:         req.Command,
+		Message:         text,
+		Body:            errorBody{Error: errorDetail{ID: 1, Format: text}},
+	})
+}
+
+func (c *conn) event(name string, body interface{}) error {
+	return c.write(&event{
+		protocolMessage: protocolMessage{Type: "event"},
+		Event:           name,
+		Body:            body,
+	})
+}
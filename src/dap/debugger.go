@@ -0,0 +1,155 @@
+package dap
+
+import (
+	"sync"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+// how the program runs until it stops again
+type stepMode int
+
+const (
+	// until a breakpoint
+	runMode stepMode = iota
+	// to the next statement, inside a call if the statement makes one
+	stepInMode
+	// to the next statement of the same frame or of a caller
+	stepOverMode
+	// to the next statement of a caller
+	stepOutMode
+	// to the next statement, asked while the program runs
+	pauseMode
+)
+
+// debugger is the typechecker.Debugger of a launched program. It is called on
+// the goroutine evaluating the program and blocks it while the program is stopped,
+// the server reads the stack of the stopped program meanwhile.
+type debugger struct {
+	mu sync.Mutex
+	// breakpoint ids by line
+	breakpoints map[int]int
+	mode        stepMode
+	// the stack depth the last step began at
+	depth int
+	// the program does not stop twice on a line of a frame, e.g. on the
+	// block of a one line loop, until it leaves the line
+	line, lineDepth int
+	// tells the server why the program stopped
+	stopped func(reason string, breakpoint int)
+	resume  chan struct{}
+	// set while the program is stopped
+	paused bool
+	stack  []typechecker.Frame
+	// where the program stopped
+	stopLine int
+}
+
+func newDebugger(stopped func(reason string, breakpoint int)) *debugger {
+	return &debugger{
+		breakpoints: map[int]int{},
+		stopped:     stopped,
+		resume:      make(chan struct{}),
+	}
+}
+
+func (d *debugger) Statement(stmt ast.Node, env *typechecker.Environment) {
+
+	start, _ := stmt.GetPos()
+
+	stack := env.Stack()
+	depth := len(stack)
+
+	d.mu.Lock()
+
+	if start.Line == d.line && depth == d.lineDepth {
+		d.mu.Unlock()
+		return
+	}
+
+	d.line = 0
+
+	reason := ""
+
+	switch d.mode {
+	case stepInMode:
+		reason = "step"
+	case stepOverMode:
+		if depth <= d.depth {
+			reason = "step"
+		}
+	case stepOutMode:
+		if depth < d.depth {
+			reason = "step"
+		}
+	case pauseMode:
+		reason = "pause"
+	}
+
+	id, hit := d.breakpoints[start.Line]
+
+	if hit && reason == "" {
+		reason = "breakpoint"
+	} else {
+		id = 0
+	}
+
+	if reason == "" {
+		d.mu.Unlock()
+		return
+	}
+
+	d.paused, d.stack, d.stopLine = true, stack, start.Line
+
+	d.mu.Unlock()
+
+	d.stopped(reason, id)
+
+	<-d.resume
+}
+
+// continueWith resumes a stopped program, false when it was not stopped
+func (d *debugger) continueWith(mode stepMode) bool {
+
+	d.mu.Lock()
+
+	if !d.paused {
+		d.mu.Unlock()
+		return false
+	}
+
+	depth := len(d.stack)
+
+	d.mode, d.depth = mode, depth
+	d.line, d.lineDepth = d.stopLine, depth
+	d.paused, d.stack = false, nil
+
+	d.mu.Unlock()
+
+	d.resume <- struct{}{}
+
+	return true
+}
+
+// pause stops a running program at its next statement
+func (d *debugger) pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.paused {
+		d.mode = pauseMode
+	}
+}
+
+func (d *debugger) setBreakpoints(breakpoints map[int]int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = breakpoints
+}
+
+// frames returns the stack of the stopped program, nil while it runs
+func (d *debugger) frames() []typechecker.Frame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stack
+}
@@ -0,0 +1,141 @@
+package dap
+
+import (
+	"fmt"
+	"sort"
+
+	"walrus/builtins"
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+	"walrus/typechecker"
+)
+
+// run evaluates the launched program and tells the client when it ended
+func (s *Server) run() {
+
+	exitCode := 0
+
+	if err := s.evaluate(); err != nil {
+		s.conn.event("output", OutputEvent{Category: "stderr", Output: err.Error() + "\n" + parser.FormatTrace(typechecker.StackTrace(err))})
+		exitCode = 1
+	}
+
+	s.conn.event("exited", ExitedEvent{ExitCode: exitCode})
+	s.conn.event("terminated", nil)
+}
+
+// evaluate runs the program in a fresh global scope, runtime errors are returned
+func (s *Server) evaluate() (err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	prog := s.program
+
+	fsCapability := builtins.NewFsCapability()
+
+	for _, dir := range prog.args.AllowRead {
+		if err := fsCapability.AllowRead(dir); err != nil {
+			return fmt.Errorf("allowRead: %s", err)
+		}
+	}
+
+	for _, dir := range prog.args.AllowWrite {
+		if err := fsCapability.AllowWrite(dir); err != nil {
+			return fmt.Errorf("allowWrite: %s", err)
+		}
+	}
+
+	globals := typechecker.NewEnvironment(nil, prog.parser)
+	builtins.Declare(globals, fsCapability)
+
+	// stdout carries the protocol, what the program prints goes to the client
+	// before a later stopped event
+	globals.SetVariable("print", builtins.Print(output{s.conn}), true)
+
+	env := typechecker.NewEnvironment(globals, prog.parser)
+
+	if !prog.args.NoDebug {
+		env.SetDebugger(s.debugger)
+	}
+
+	typechecker.Evaluate(prog.ast, env)
+
+	return nil
+}
+
+// output sends each write of the program as an output event
+type output struct {
+	conn *conn
+}
+
+func (o output) Write(p []byte) (int, error) {
+	if err := o.conn.event("output", OutputEvent{Category: "stdout", Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// onStop tells the client the program stopped, called on the program goroutine
+func (s *Server) onStop(reason string, breakpoint int) {
+
+	stopped := StoppedEvent{Reason: reason, ThreadID: threadID, AllThreadsStopped: true}
+
+	if breakpoint != 0 {
+		stopped.HitBreakpointIDs = []int{breakpoint}
+	}
+
+	s.conn.event("stopped", stopped)
+}
+
+// statementLine moves a breakpoint to the first line on or after it a statement starts on
+func (p *program) statementLine(line int) (int, bool) {
+
+	i := sort.SearchInts(p.lines, line)
+
+	if i == len(p.lines) {
+		return 0, false
+	}
+
+	return p.lines[i], true
+}
+
+// statementLines returns the sorted lines the statements of a program start
+// on, the statements the evaluator hands to the debugger
+func statementLines(program ast.ProgramStmt) []int {
+
+	seen := map[int]bool{}
+
+	record := func(stmts []ast.Node) {
+		for _, stmt := range stmts {
+			start, _ := stmt.GetPos()
+			seen[start.Line] = true
+		}
+	}
+
+	record(program.Contents)
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if block, ok := node.(ast.BlockStmt); ok {
+			record(block.Items)
+		}
+		return true
+	})
+
+	lines := make([]int, 0, len(seen))
+
+	for line := range seen {
+		lines = append(lines, line)
+	}
+
+	sort.Ints(lines)
+
+	return lines
+}
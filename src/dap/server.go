@@ -0,0 +1,504 @@
+// Package dap implements a debug adapter for walrus over stdio.
+// The program runs on its own goroutine, the debugger installed on its
+// environment stops it before statements, and the server answers requests for
+// the stack and the variables of the stopped program meanwhile.
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"walrus/builtins"
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+	"walrus/tc"
+	"walrus/typechecker"
+	"walrus/utils"
+)
+
+// the evaluator runs a program on a single thread
+const threadID = 1
+
+type Server struct {
+	conn *conn
+	log  io.Writer
+	// the launched program, nil until launch
+	program *program
+	// set once the client sent its breakpoints and the program may start
+	configured bool
+	debugger   *debugger
+	// variables references of the stopped program
+	refs *references
+	// the next breakpoint id
+	breakpointID int
+}
+
+// program is a parsed and checked source waiting to run
+type program struct {
+	path   string
+	ast    ast.ProgramStmt
+	parser *parser.Parser
+	args   LaunchArguments
+	// lines a statement starts on, breakpoints are moved to them
+	lines []int
+}
+
+// NewServer creates a server reading requests from in and writing to out,
+// errors of the connection go to log
+func NewServer(in io.Reader, out io.Writer, log io.Writer) *Server {
+	s := &Server{
+		conn: newConn(in, out),
+		log:  log,
+		refs: &references{},
+	}
+	s.debugger = newDebugger(s.onStop)
+	return s
+}
+
+// Run serves until the client disconnects or the input ends
+func (s *Server) Run() error {
+
+	for {
+
+		body, err := s.conn.Read()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var req request
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			fmt.Fprintf(s.log, "walrus dap: %s\n", err)
+			continue
+		}
+
+		if req.Command == "disconnect" || req.Command == "terminate" {
+			if req.Command == "terminate" {
+				s.conn.event("terminated", nil)
+			}
+			s.conn.reply(&req, nil)
+			return nil
+		}
+
+		s.handle(&req)
+	}
+}
+
+func (s *Server) handle(req *request) {
+
+	// a handler failing on some input should not take the server down
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Fprintf(s.log, "walrus dap: %s: %v\n", req.Command, recovered)
+			s.conn.replyError(req, fmt.Sprint(recovered))
+		}
+	}()
+
+	handler, ok := handlers[req.Command]
+
+	if !ok {
+		s.conn.replyError(req, "command not supported: "+req.Command)
+		return
+	}
+
+	body, err := handler(s, req.Arguments)
+
+	if err != nil {
+		s.conn.replyError(req, err.Error())
+		return
+	}
+
+	s.conn.reply(req, body)
+
+	// events following a request come after its reply: the client waits for
+	// the launch to send its breakpoints, and a stopped event must not
+	// overtake the reply of the request resuming the program
+	switch req.Command {
+	case "launch":
+		s.conn.event("initialized", nil)
+	case "configurationDone":
+		go s.run()
+	}
+
+	if mode, ok := resumeModes[req.Command]; ok {
+		s.refs = &references{}
+		s.debugger.continueWith(mode)
+	}
+}
+
+type handler func(s *Server, args json.RawMessage) (interface{}, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":        (*Server).initialize,
+		"launch":            (*Server).launch,
+		"setBreakpoints":    (*Server).setBreakpoints,
+		"configurationDone": (*Server).configurationDone,
+		"threads":           (*Server).threads,
+		"stackTrace":        (*Server).stackTrace,
+		"scopes":            (*Server).scopes,
+		"variables":         (*Server).variables,
+		"continue":          (*Server).continueRequest,
+		"next":              (*Server).step,
+		"stepIn":            (*Server).step,
+		"stepOut":           (*Server).step,
+		"pause":             (*Server).pause,
+	}
+}
+
+// the requests resuming a stopped program and how it runs until it stops again
+var resumeModes = map[string]stepMode{
+	"continue": runMode,
+	"next":     stepOverMode,
+	"stepIn":   stepInMode,
+	"stepOut":  stepOutMode,
+}
+
+func (s *Server) initialize(args json.RawMessage) (interface{}, error) {
+
+	var a InitializeArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	if (a.LinesStartAt1 != nil && !*a.LinesStartAt1) || (a.ColumnsStartAt1 != nil && !*a.ColumnsStartAt1) {
+		return nil, fmt.Errorf("lines and columns start at 1")
+	}
+
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+// launch parses and checks the program, the client sends its breakpoints
+// after the initialized event and starts the program with configurationDone
+func (s *Server) launch(args json.RawMessage) (interface{}, error) {
+
+	var a LaunchArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	if s.program != nil {
+		return nil, fmt.Errorf("a program is already launched")
+	}
+
+	if a.Program == "" {
+		return nil, fmt.Errorf("no program to launch")
+	}
+
+	path, err := filepath.Abs(a.Program)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prog, err := load(path, string(bytes))
+
+	if err != nil {
+		return nil, err
+	}
+
+	prog.args = a
+
+	s.program = prog
+
+	if a.StopOnEntry && !a.NoDebug {
+		s.debugger.mode = stepInMode
+	}
+
+	return nil, nil
+}
+
+// load parses and checks a source like the cli does before running it
+func load(path string, source string) (prog *program, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	p, err := parser.NewParserFromSource(source, path, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.PanicOnError = true
+
+	tree := p.Parse()
+
+	globalTypes := tc.NewTypeEnv(nil)
+	builtins.DeclareTypes(globalTypes)
+
+	if _, err := tc.CheckType(tree, tc.NewTypeEnv(globalTypes)); err != nil {
+		if typeErr, ok := err.(tc.TypeError); ok {
+			return nil, parser.MakeError(p, typeErr.Start.Line, path, typeErr.Start, typeErr.End, typeErr.Message)
+		}
+		return nil, err
+	}
+
+	return &program{
+		path:   path,
+		ast:    tree,
+		parser: p,
+		lines:  statementLines(tree),
+	}, nil
+}
+
+func (s *Server) setBreakpoints(args json.RawMessage) (interface{}, error) {
+
+	var a SetBreakpointsArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	if s.program == nil {
+		return nil, fmt.Errorf("no program launched")
+	}
+
+	requested := a.Lines
+
+	if a.Breakpoints != nil {
+		requested = nil
+		for _, bp := range a.Breakpoints {
+			requested = append(requested, bp.Line)
+		}
+	}
+
+	path, _ := filepath.Abs(a.Source.Path)
+
+	byLine := map[int]int{}
+	breakpoints := []Breakpoint{}
+
+	for _, line := range requested {
+
+		s.breakpointID++
+
+		bp := Breakpoint{ID: s.breakpointID, Source: &a.Source, Line: line}
+
+		switch moved, ok := s.program.statementLine(line); {
+		case path != s.program.path:
+			bp.Message = "not part of the launched program"
+		case !ok:
+			bp.Message = "no statement on or after this line"
+		default:
+			bp.Verified, bp.Line = true, moved
+			if _, exists := byLine[moved]; !exists {
+				byLine[moved] = bp.ID
+			}
+		}
+
+		breakpoints = append(breakpoints, bp)
+	}
+
+	// breakpoints of other files are not kept, the program is a single file
+	if path == s.program.path {
+		s.debugger.setBreakpoints(byLine)
+	}
+
+	return SetBreakpointsResponse{Breakpoints: breakpoints}, nil
+}
+
+func (s *Server) configurationDone(args json.RawMessage) (interface{}, error) {
+
+	if s.program == nil {
+		return nil, fmt.Errorf("no program launched")
+	}
+
+	if s.configured {
+		return nil, fmt.Errorf("the program already started")
+	}
+
+	s.configured = true
+
+	return nil, nil
+}
+
+func (s *Server) threads(args json.RawMessage) (interface{}, error) {
+	return ThreadsResponse{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+// stackTrace lists the frames innermost first. A frame is at the statement it
+// evaluates, or at the call which made the frame above it.
+func (s *Server) stackTrace(args json.RawMessage) (interface{}, error) {
+
+	var a StackTraceArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	stack, err := s.stack()
+
+	if err != nil {
+		return nil, err
+	}
+
+	frames := []StackFrame{}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+
+		var at ast.Node = stack[i].Stmt
+
+		if i+1 < len(stack) && stack[i+1].Call != nil {
+			at = stack[i+1].Call
+		}
+
+		frame := StackFrame{ID: len(stack) - i, Name: stack[i].Name, Source: s.source()}
+
+		if at != nil {
+			start, end := at.GetPos()
+			frame.Line, frame.Column = start.Line, start.Column
+			frame.EndLine, frame.EndColumn = end.Line, end.Column
+		}
+
+		frames = append(frames, frame)
+	}
+
+	total := len(frames)
+
+	if a.StartFrame > 0 {
+		frames = frames[utils.Min(a.StartFrame, len(frames)):]
+	}
+
+	if a.Levels > 0 && a.Levels < len(frames) {
+		frames = frames[:a.Levels]
+	}
+
+	return StackTraceResponse{StackFrames: frames, TotalFrames: total}, nil
+}
+
+// scopes walks from the innermost scope of a frame through its parents to the globals,
+// scopes which declare nothing are left out
+func (s *Server) scopes(args json.RawMessage) (interface{}, error) {
+
+	var a ScopesArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	stack, err := s.stack()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if a.FrameID < 1 || a.FrameID > len(stack) {
+		return nil, fmt.Errorf("unknown frame %d", a.FrameID)
+	}
+
+	frame := stack[len(stack)-a.FrameID]
+
+	scopes := []Scope{}
+
+	for env := frame.Env; env != nil; env = env.Parent() {
+
+		scope := Scope{Name: "Locals", PresentationHint: "locals"}
+
+		switch {
+		case env.Parent() == nil:
+			// the natives and modules every program sees
+			scope = Scope{Name: "Globals", Expensive: true}
+		case env.Parent().Parent() == nil:
+			scope = Scope{Name: "Program"}
+		case env != frame.Env:
+			if len(env.Variables()) == 0 {
+				continue
+			}
+			scope = Scope{Name: "Enclosing"}
+		}
+
+		scope.VariablesReference = s.refs.scope(env)
+
+		scopes = append(scopes, scope)
+	}
+
+	return ScopesResponse{Scopes: scopes}, nil
+}
+
+func (s *Server) variables(args json.RawMessage) (interface{}, error) {
+
+	var a VariablesArguments
+
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.stack(); err != nil {
+		return nil, err
+	}
+
+	variables, err := s.refs.variables(a.VariablesReference)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if variables == nil {
+		variables = []Variable{}
+	}
+
+	return VariablesResponse{Variables: variables}, nil
+}
+
+func (s *Server) continueRequest(args json.RawMessage) (interface{}, error) {
+
+	if _, err := s.stack(); err != nil {
+		return nil, err
+	}
+
+	return ContinueResponse{AllThreadsContinued: true}, nil
+}
+
+func (s *Server) step(args json.RawMessage) (interface{}, error) {
+	_, err := s.stack()
+	return nil, err
+}
+
+func (s *Server) pause(args json.RawMessage) (interface{}, error) {
+	s.debugger.pause()
+	return nil, nil
+}
+
+// stack returns the frames of the stopped program
+func (s *Server) stack() ([]typechecker.Frame, error) {
+
+	stack := s.debugger.frames()
+
+	if stack == nil {
+		return nil, fmt.Errorf("the program is not stopped")
+	}
+
+	return stack, nil
+}
+
+func (s *Server) source() *Source {
+	return &Source{Name: filepath.Base(s.program.path), Path: s.program.path}
+}
@@ -0,0 +1,313 @@
+package dap_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"walrus/dap"
+	"walrus/framing/framingtest"
+)
+
+// message is a response or an event of the server
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client speaks the debug adapter protocol to a server, it keeps the events
+// read while waiting for responses
+type client struct {
+	*framingtest.Client
+	t *testing.T
+	// the events read while waiting for responses, oldest first
+	events []message
+	seq    int
+}
+
+// start runs a server, a program left stopped blocks on the debugger until
+// the input closes at the end of the test
+func start(t *testing.T) *client {
+
+	t.Helper()
+
+	return &client{
+		Client: framingtest.Start(t, func(in io.Reader, out io.Writer) error {
+			return dap.NewServer(in, out, io.Discard).Run()
+		}),
+		t: t,
+	}
+}
+
+// next returns the next message of the server
+func (c *client) next() message {
+	c.t.Helper()
+	var msg message
+	c.Next(&msg)
+	return msg
+}
+
+// send sends a request and returns its response, events read meanwhile are kept
+func (c *client) send(command string, args interface{}) message {
+
+	c.t.Helper()
+
+	c.seq++
+
+	c.Send(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+
+	for {
+		msg := c.next()
+
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("%s: response to %s %d, want %d", command, msg.Command, msg.RequestSeq, c.seq)
+		}
+
+		return msg
+	}
+}
+
+// request sends a request which has to succeed and decodes its body into result
+func (c *client) request(command string, args interface{}, result interface{}) {
+
+	c.t.Helper()
+
+	msg := c.send(command, args)
+
+	if !msg.Success {
+		c.t.Fatalf("%s failed: %s", command, msg.Message)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(msg.Body, result); err != nil {
+			c.t.Fatalf("%s: %v in %s", command, err, msg.Body)
+		}
+	}
+}
+
+// event waits for the next event with a name, the events before it are dropped
+func (c *client) event(name string, body interface{}) {
+
+	c.t.Helper()
+
+	for {
+		var msg message
+
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.next()
+		}
+
+		if msg.Type != "event" || msg.Event != name {
+			continue
+		}
+
+		if body != nil {
+			json.Unmarshal(msg.Body, body)
+		}
+
+		return
+	}
+}
+
+// stopped waits for the program to stop and returns why and its stack
+func (c *client) stopped() (dap.StoppedEvent, []dap.StackFrame) {
+
+	c.t.Helper()
+
+	var stopped dap.StoppedEvent
+	c.event("stopped", &stopped)
+
+	var trace dap.StackTraceResponse
+	c.request("stackTrace", dap.StackTraceArguments{ThreadID: 1}, &trace)
+
+	return stopped, trace.StackFrames
+}
+
+// variables returns the values of the variables behind a reference by name
+func (c *client) variables(ref int) map[string]dap.Variable {
+
+	c.t.Helper()
+
+	var response dap.VariablesResponse
+	c.request("variables", dap.VariablesArguments{VariablesReference: ref}, &response)
+
+	byName := map[string]dap.Variable{}
+
+	for _, variable := range response.Variables {
+		byName[variable.Name] = variable
+	}
+
+	return byName
+}
+
+// locals returns the variables of the innermost scope of a frame
+func (c *client) locals(frame int) map[string]dap.Variable {
+
+	c.t.Helper()
+
+	var response dap.ScopesResponse
+	c.request("scopes", dap.ScopesArguments{FrameID: frame}, &response)
+
+	if len(response.Scopes) == 0 {
+		c.t.Fatalf("frame %d has no scopes", frame)
+	}
+
+	return c.variables(response.Scopes[0].VariablesReference)
+}
+
+const program = `fn double(n: i32) -> i32 {
+    let twice := n * 2;
+    ret twice;
+}
+
+let xs := [1, 2];
+let y := double(20);
+print(y);
+`
+
+// launch writes the program to a file and launches it with breakpoints on lines
+func (c *client) launch(lines ...int) dap.SetBreakpointsResponse {
+
+	c.t.Helper()
+
+	path := filepath.Join(c.t.TempDir(), "main.wal")
+
+	if err := os.WriteFile(path, []byte(program), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+
+	c.request("initialize", dap.InitializeArguments{ClientID: "test"}, nil)
+	c.request("launch", dap.LaunchArguments{Program: path}, nil)
+	c.event("initialized", nil)
+
+	var breakpoints dap.SetBreakpointsResponse
+	c.request("setBreakpoints", dap.SetBreakpointsArguments{Source: dap.Source{Path: path}, Lines: lines}, &breakpoints)
+
+	c.request("configurationDone", nil, nil)
+
+	return breakpoints
+}
+
+func TestSession(t *testing.T) {
+
+	c := start(t)
+
+	breakpoints := c.launch(7, 5, 40)
+
+	if got := breakpoints.Breakpoints; len(got) != 3 || !got[0].Verified || got[0].Line != 7 || got[1].Line != 6 || got[2].Verified {
+		t.Fatalf("breakpoints %+v, want 7 verified, 5 moved to 6 and 40 unverified", got)
+	}
+
+	stopped, frames := c.stopped()
+
+	if stopped.Reason != "breakpoint" || frames[0].Line != 6 {
+		t.Fatalf("stopped for %q on line %d, want the breakpoint on line 6", stopped.Reason, frames[0].Line)
+	}
+
+	c.request("continue", dap.ThreadArguments{ThreadID: 1}, nil)
+
+	stopped, frames = c.stopped()
+
+	if stopped.Reason != "breakpoint" || frames[0].Line != 7 || len(frames) != 1 {
+		t.Fatalf("stopped for %q in %+v, want the breakpoint on line 7", stopped.Reason, frames)
+	}
+
+	// the array declared before is expanded through its reference
+	xs, ok := c.locals(frames[0].ID)["xs"]
+
+	if !ok || xs.VariablesReference == 0 {
+		t.Fatalf("the program scope has no expandable xs: %+v", xs)
+	}
+
+	if elements := c.variables(xs.VariablesReference); len(elements) != 2 || elements["[1]"].Value != "2" {
+		t.Errorf("the elements of xs are %+v", elements)
+	}
+
+	c.request("stepIn", dap.ThreadArguments{ThreadID: 1}, nil)
+
+	stopped, frames = c.stopped()
+
+	if stopped.Reason != "step" || len(frames) != 2 || frames[0].Name != "double" || frames[0].Line != 2 {
+		t.Fatalf("step in stopped for %q in %+v, want line 2 of double", stopped.Reason, frames)
+	}
+
+	if n := c.locals(frames[0].ID)["n"]; n.Value != "20" {
+		t.Errorf("n is %+v in double, want 20", n)
+	}
+
+	c.request("next", dap.ThreadArguments{ThreadID: 1}, nil)
+
+	_, frames = c.stopped()
+
+	if frames[0].Line != 3 {
+		t.Fatalf("next stopped on line %d, want 3", frames[0].Line)
+	}
+
+	if twice := c.locals(frames[0].ID)["twice"]; twice.Value != "40" {
+		t.Errorf("twice is %+v, want 40", twice)
+	}
+
+	c.request("stepOut", dap.ThreadArguments{ThreadID: 1}, nil)
+
+	_, frames = c.stopped()
+
+	if len(frames) != 1 || frames[0].Line != 8 {
+		t.Fatalf("step out stopped in %+v, want line 8 of the program", frames)
+	}
+
+	c.request("continue", dap.ThreadArguments{ThreadID: 1}, nil)
+
+	var output dap.OutputEvent
+	c.event("output", &output)
+
+	if output.Category != "stdout" || output.Output != "40\n" {
+		t.Errorf("the program printed %+v, want 40", output)
+	}
+
+	var exited dap.ExitedEvent
+	c.event("exited", &exited)
+
+	if exited.ExitCode != 0 {
+		t.Errorf("the program exited with %d", exited.ExitCode)
+	}
+
+	c.event("terminated", nil)
+
+	c.request("disconnect", nil, nil)
+
+	if err := c.Wait(); err != nil {
+		t.Errorf("the server stopped with %v", err)
+	}
+}
+
+func TestNotStopped(t *testing.T) {
+
+	c := start(t)
+
+	c.request("initialize", dap.InitializeArguments{ClientID: "test"}, nil)
+
+	for _, command := range []string{"stackTrace", "variables", "continue", "next"} {
+		if msg := c.send(command, dap.ThreadArguments{ThreadID: 1}); msg.Success {
+			t.Errorf("%s succeeded without a stopped program", command)
+		}
+	}
+
+	if msg := c.send("launch", dap.LaunchArguments{Program: filepath.Join(t.TempDir(), "missing.wal")}); msg.Success {
+		t.Error("launched a program which does not exist")
+	}
+}
@@ -0,0 +1,127 @@
+package dap
+
+import (
+	"fmt"
+	"sort"
+
+	"walrus/typechecker"
+)
+
+// references hands out the variablesReference numbers of a stopped program.
+// A number names a scope or a value with children, it is valid until the program resumes.
+type references struct {
+	scopes []*typechecker.Environment
+	values []typechecker.RuntimeValue
+}
+
+// scope numbers are odd and value numbers even, zero means no children
+func (r *references) scope(env *typechecker.Environment) int {
+	r.scopes = append(r.scopes, env)
+	return 2*len(r.scopes) - 1
+}
+
+func (r *references) value(value typechecker.RuntimeValue) int {
+	if !hasChildren(value) {
+		return 0
+	}
+	r.values = append(r.values, value)
+	return 2 * len(r.values)
+}
+
+func (r *references) variables(ref int) ([]Variable, error) {
+
+	if ref > 0 && ref%2 == 1 && (ref+1)/2 <= len(r.scopes) {
+		return r.scopeVariables(r.scopes[(ref+1)/2-1]), nil
+	}
+
+	if ref > 0 && ref%2 == 0 && ref/2 <= len(r.values) {
+		return r.children(r.values[ref/2-1]), nil
+	}
+
+	return nil, fmt.Errorf("unknown variables reference %d", ref)
+}
+
+func (r *references) scopeVariables(env *typechecker.Environment) []Variable {
+
+	values := env.Variables()
+
+	names := make([]string, 0, len(values))
+
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	variables := make([]Variable, len(names))
+
+	for i, name := range names {
+		variables[i] = r.variable(name, values[name])
+	}
+
+	return variables
+}
+
+// children are the elements of an array, the entries of a map in the order
+// they were inserted and the fields of a struct instance, sorted by name
+func (r *references) children(value typechecker.RuntimeValue) []Variable {
+
+	var variables []Variable
+
+	switch v := value.(type) {
+	case typechecker.ArrayValue:
+		for i, element := range v.Values {
+			variables = append(variables, r.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	case typechecker.MapValue:
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
+			variables = append(variables, r.variable("["+typechecker.FormatValue(key)+"]", value))
+		}
+	case typechecker.StructInstance:
+		names := make([]string, 0, len(v.Fields))
+		for name := range v.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			variables = append(variables, r.variable(name, v.Fields[name]))
+		}
+	}
+
+	return variables
+}
+
+// variable renders a value the way the repl prints it
+func (r *references) variable(name string, value typechecker.RuntimeValue) Variable {
+
+	variable := Variable{
+		Name:               name,
+		Value:              typechecker.FormatValue(value),
+		Type:               string(typechecker.GetRuntimeType(value)),
+		VariablesReference: r.value(value),
+	}
+
+	switch v := value.(type) {
+	case typechecker.ArrayValue:
+		variable.IndexedVariables = len(v.Values)
+	case typechecker.MapValue:
+		variable.NamedVariables = v.Len()
+	case typechecker.StructInstance:
+		variable.NamedVariables = len(v.Fields)
+	}
+
+	return variable
+}
+
+func hasChildren(value typechecker.RuntimeValue) bool {
+	switch v := value.(type) {
+	case typechecker.ArrayValue:
+		return len(v.Values) > 0
+	case typechecker.MapValue:
+		return v.Len() > 0
+	case typechecker.StructInstance:
+		return len(v.Fields) > 0
+	}
+	return false
+}
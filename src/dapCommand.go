@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"walrus/dap"
+)
+
+// runDap serves the debug adapter protocol over stdin and stdout, walrus dap.
+// The program to debug and the directories it may touch come with the launch request.
+func runDap(args []string) {
+
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	flags.Parse(args)
+
+	// stdout carries the protocol, what the program prints is sent as output events
+	if err := dap.NewServer(os.Stdin, os.Stdout, os.Stderr).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "walrus dap: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"walrus/formatter"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/utils"
+)
+
+// runFmt formats files in place, walrus fmt [--check | --diff] [file or dir ...].
+// Without paths it formats stdin to stdout. --check and --diff write nothing and
+// exit with 1 when a file is not formatted, so they can guard CI.
+func runFmt(args []string) {
+
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list the files which are not formatted")
+	diff := flags.Bool("diff", false, "print the changes formatting would make")
+	flags.Parse(args)
+
+	failed := false
+
+	format := func(name string, source string) (string, bool) {
+
+		formatted, err := formatter.Format(name, source)
+
+		if err != nil {
+			reportError(err)
+			failed = true
+			return source, false
+		}
+
+		if formatted == source {
+			return formatted, false
+		}
+
+		if *check {
+			fmt.Println(name)
+			failed = true
+		}
+
+		if *diff {
+			fmt.Print(formatter.Diff(name, source, formatted))
+			failed = true
+		}
+
+		return formatted, true
+	}
+
+	if flags.NArg() == 0 {
+
+		source, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+
+		formatted, _ := format("<stdin>", string(source))
+
+		if !*check && !*diff {
+			fmt.Print(formatted)
+		}
+	}
+
+	for _, path := range flags.Args() {
+
+		files, err := walFiles(path)
+
+		if err != nil {
+			reportError(err)
+			failed = true
+			continue
+		}
+
+		for _, file := range files {
+
+			source, err := os.ReadFile(file)
+
+			if err != nil {
+				reportError(err)
+				failed = true
+				continue
+			}
+
+			formatted, changed := format(file, string(source))
+
+			if !changed || *check || *diff {
+				continue
+			}
+
+			if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+				reportError(err)
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// walFiles returns the path if it is a file, or every .wal file below it if it is a directory
+func walFiles(path string) ([]string, error) {
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(file, ".wal") {
+			files = append(files, file)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// reportError prints an error with its source line when it has one
+func reportError(err error) {
+	switch e := err.(type) {
+	case *parser.ErrorMessage:
+		fmt.Fprintln(os.Stderr, strings.TrimRight(e.Report(), "\n"))
+	case *lexer.LexError:
+		fmt.Fprintln(os.Stderr, strings.TrimRight(e.Report, "\n"))
+	default:
+		fmt.Fprintln(os.Stderr, utils.Colorize(utils.RED, fmt.Sprintf("Error: %s", err)))
+	}
+}
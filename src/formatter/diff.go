@@ -0,0 +1,163 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"walrus/utils"
+)
+
+// lines of context around each change
+const diffContext = 3
+
+type edit struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Diff returns a unified diff from before to after, empty when they are equal
+func Diff(name string, before string, after string) string {
+
+	if before == after {
+		return ""
+	}
+
+	edits := diffLines(splitLines(before), splitLines(after))
+
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+
+	// line numbers before each edit, counted from 1
+	oldLine, newLine := 1, 1
+
+	for i := 0; i < len(edits); {
+
+		if edits[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// a hunk runs from some context before a change to some context after
+		// the last change closer than twice the context to the previous one
+		start := i
+		for start > 0 && i-start < diffContext && edits[start-1].kind == ' ' {
+			start--
+		}
+
+		end := i
+		for end < len(edits) {
+			if edits[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].kind == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*diffContext {
+				end += utils.Min(diffContext, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+
+		for _, e := range edits[start:end] {
+			if e.kind != '+' {
+				oldCount++
+			}
+			if e.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hunkOld, oldCount, hunkNew, newCount)
+
+		for _, e := range edits[start:end] {
+			out.WriteString(string(e.kind) + e.line + "\n")
+		}
+
+		for _, e := range edits[i:end] {
+			if e.kind != '+' {
+				oldLine++
+			}
+			if e.kind != '-' {
+				newLine++
+			}
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+// splitLines keeps a missing newline at the end of the text as a marker on the last line
+func splitLines(text string) []string {
+
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	if !strings.HasSuffix(text, "\n") {
+		lines[len(lines)-1] += "\n\\ No newline at end of file"
+	}
+
+	return lines
+}
+
+// diffLines finds the edits turning a into b through their longest common subsequence
+func diffLines(a []string, b []string) []edit {
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = utils.Max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+
+	return edits
+}
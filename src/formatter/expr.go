@@ -0,0 +1,292 @@
+package formatter
+
+import (
+	"sort"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+// atom binds tighter than every operator, literals and prefix expressions never need parentheses
+const atom = parser.PRIMARY + 1
+
+// precedence is the binding power an expression was parsed with
+func precedence(node ast.Node) parser.BINDING_POWER {
+	switch n := node.(type) {
+	case ast.BinaryExpr:
+		return parser.GetBP(n.Operator.Kind)
+	case ast.AssignmentExpr:
+		return parser.ASSIGNMENT
+	case ast.FunctionCallExpr, ast.TryExpr:
+		return parser.CALL
+	case ast.PropertyExpr, ast.ArrayIndexAccess:
+		return parser.MEMBER
+	default:
+		return atom
+	}
+}
+
+// openPrecedence is the weakest operator an expression leaves open on its right.
+// As a left operand it needs parentheses when that operator would take the
+// operator after it, like a + b in (a + b) * c or -a in (-a).b
+func openPrecedence(node ast.Node) parser.BINDING_POWER {
+	switch n := node.(type) {
+	case ast.BinaryExpr:
+		return parser.GetBP(n.Operator.Kind)
+	case ast.AssignmentExpr:
+		return parser.ASSIGNMENT
+	case ast.UnaryExpr:
+		return parser.UNARY
+	default:
+		return atom
+	}
+}
+
+// left prints the left operand of an operator with the binding power bp
+func (p *printer) left(node ast.Node, bp parser.BINDING_POWER) {
+	if openPrecedence(node) < bp {
+		p.write("(")
+		p.expr(node, parser.DEFAULT_BP)
+		p.write(")")
+		return
+	}
+	p.expr(node, parser.DEFAULT_BP)
+}
+
+// expr prints an expression in a place parsed with the binding power bp.
+// The parser drops parentheses, so they are added back where the expression
+// would otherwise end early or bind to its neighbours.
+func (p *printer) expr(node ast.Node, bp parser.BINDING_POWER) {
+
+	p.inlineBefore(ast.StartOf(node))
+	defer p.inlineAfter(endOf(node))
+
+	if precedence(node) <= bp {
+		p.write("(")
+		p.expr(node, parser.DEFAULT_BP)
+		p.write(")")
+		return
+	}
+
+	switch n := node.(type) {
+	case ast.NumericLiteral:
+		p.write(n.Value)
+	case ast.StringLiteral:
+		p.write("\"" + n.Value + "\"")
+	case ast.CharacterLiteral:
+		p.write("'" + n.Value + "'")
+	case ast.BooleanLiteral:
+		if n.Value {
+			p.write("true")
+		} else {
+			p.write("false")
+		}
+	case ast.NullLiteral:
+		p.write("null")
+	case ast.IdentifierExpr:
+		p.write(n.Identifier)
+	case ast.BinaryExpr:
+		operatorBP := parser.GetBP(n.Operator.Kind)
+		// operators are left associative, a right operand of the same power keeps its parentheses
+		p.left(n.Left, operatorBP)
+		if n.Operator.Kind == lexer.DOT_DOT_TOKEN {
+			p.write(n.Operator.Value)
+		} else {
+			p.write(" " + n.Operator.Value + " ")
+		}
+		p.expr(n.Right, operatorBP)
+	case ast.UnaryExpr:
+		p.write(n.Operator.Value)
+		// - -a would be read as the -- operator
+		if _, nested := n.Argument.(ast.UnaryExpr); nested {
+			p.write("(")
+			p.expr(n.Argument, parser.DEFAULT_BP)
+			p.write(")")
+			return
+		}
+		p.expr(n.Argument, parser.UNARY)
+	case ast.AssignmentExpr:
+		p.left(n.Assigne, parser.ASSIGNMENT)
+		p.write(" " + n.Operator.Value + " ")
+		p.expr(n.Value, parser.ASSIGNMENT)
+	case ast.FunctionCallExpr:
+		p.left(n.Caller, parser.CALL)
+		p.write("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(arg, parser.DEFAULT_BP)
+		}
+		for i, arg := range n.NamedArgs {
+			if i > 0 || len(n.Args) > 0 {
+				p.write(", ")
+			}
+			p.write(arg.Name.Identifier + ": ")
+			p.expr(arg.Value, parser.DEFAULT_BP)
+		}
+		p.write(")")
+	case ast.TryExpr:
+		p.left(n.Value, parser.CALL)
+		p.write("?")
+	case ast.PropertyExpr:
+		p.left(n.Object, parser.MEMBER)
+		p.write("." + n.Property.Identifier)
+	case ast.ArrayIndexAccess:
+		p.left(n.Array, parser.MEMBER)
+		p.write("[")
+		p.expr(n.Index, parser.DEFAULT_BP)
+		p.write("]")
+	case ast.ArrayLiterals:
+		p.write("[")
+		for i, element := range n.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			// elements are parsed with the highest binding power
+			p.expr(element, parser.PRIMARY)
+		}
+		p.write("]")
+	case ast.StructLiteral:
+		p.structLiteral(n)
+	case ast.MapLiteral:
+		p.mapLiteral(n)
+	case ast.MatchExpr:
+		p.matchExpr(n)
+	case ast.FunctionExpr:
+		p.write("fn")
+		p.prototype("", n.Parameters, n.ReturnType)
+		p.write(" ")
+		p.blockStmt(n.Block)
+	case ast.VariantPattern:
+		if n.EnumName != "" {
+			p.write(n.EnumName + ".")
+		}
+		p.write(n.Variant)
+		if n.Fields != nil {
+			p.write("(")
+			for i, field := range n.Fields {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.expr(field, parser.DEFAULT_BP)
+			}
+			p.write(")")
+		}
+	}
+}
+
+// matchExpr writes an arm per line, arms with a block body need no comma
+func (p *printer) matchExpr(match ast.MatchExpr) {
+
+	p.write("match ")
+	p.expr(match.Subject, parser.DEFAULT_BP)
+	p.write(" ")
+
+	var items []item
+
+	for _, arm := range match.Arms {
+		arm := arm
+		items = append(items, item{arm.StartPos, arm.EndPos, func() {
+			p.expr(arm.Pattern, parser.DEFAULT_BP)
+			if arm.Guard != nil {
+				p.write(" if ")
+				p.expr(arm.Guard, parser.DEFAULT_BP)
+			}
+			p.write(" => ")
+			if block, ok := arm.Body.(ast.BlockStmt); ok {
+				p.blockStmt(block)
+				return
+			}
+			p.expr(arm.Body, parser.DEFAULT_BP)
+			p.write(",")
+		}})
+	}
+
+	p.block(items, match.EndPos)
+}
+
+// structLiteral keeps a literal written over several lines on several lines,
+// one property per line with a trailing comma
+func (p *printer) structLiteral(literal ast.StructLiteral) {
+
+	// properties are parsed into a map, their values still know where they were written
+	names := make([]string, 0, len(literal.Properties))
+
+	for name := range literal.Properties {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return ast.StartOf(literal.Properties[names[i]]).Index < ast.StartOf(literal.Properties[names[j]]).Index
+	})
+
+	p.write(literal.StructName)
+
+	if literal.StartPos.Line == literal.EndPos.Line {
+		p.write("{")
+		for i, name := range names {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.write(name + ": ")
+			p.expr(literal.Properties[name], parser.LOGICAL)
+		}
+		p.write("}")
+		return
+	}
+
+	var items []item
+
+	for _, name := range names {
+		name, value := name, literal.Properties[name]
+		items = append(items, item{ast.StartOf(value), endOf(value), func() {
+			p.write(name + ": ")
+			p.expr(value, parser.LOGICAL)
+			p.write(",")
+		}})
+	}
+
+	p.block(items, literal.EndPos)
+}
+
+// mapLiteral prints the entries of a map on one line or one per line, as they were written
+func (p *printer) mapLiteral(literal ast.MapLiteral) {
+
+	if literal.Type != nil {
+		p.write(TypeName(literal.Type))
+	} else {
+		p.write("map")
+	}
+
+	entry := func(entry ast.MapEntry) {
+		p.expr(entry.Key, parser.LOGICAL)
+		p.write(": ")
+		p.expr(entry.Value, parser.LOGICAL)
+	}
+
+	if literal.StartPos.Line == literal.EndPos.Line {
+		p.write("{")
+		for i, e := range literal.Entries {
+			if i > 0 {
+				p.write(", ")
+			}
+			entry(e)
+		}
+		p.write("}")
+		return
+	}
+
+	var items []item
+
+	for _, e := range literal.Entries {
+		e := e
+		items = append(items, item{ast.StartOf(e.Key), endOf(e.Value), func() {
+			entry(e)
+			p.write(",")
+		}})
+	}
+
+	p.block(items, literal.EndPos)
+}
@@ -0,0 +1,374 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+const INDENT = "    "
+
+// Format returns the canonical form of a source: four space indentation,
+// braces on the line they open, single spaces around operators and at most one
+// blank line between statements. Comments are kept where they were written.
+// The source must parse, the name is only used in error reports.
+func Format(name string, source string) (formatted string, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", recovered)
+			}
+		}
+	}()
+
+	p, err := parser.NewParserFromSource(source, name, false)
+
+	if err != nil {
+		return "", err
+	}
+
+	p.PanicOnError = true
+
+	program := p.Parse()
+
+	// the parser has no use for comments, they come from the tokens
+	tokens, _, err := lexer.Tokenize(source, name, false)
+
+	if err != nil {
+		return "", err
+	}
+
+	printer := newPrinter(tokens)
+	printer.program(program)
+
+	return printer.out.String(), nil
+}
+
+// Expr returns the canonical form of an expression, like the default value
+// of a parameter shown by an editor
+func Expr(node ast.Node) string {
+	printer := newPrinter(nil)
+	printer.expr(node, parser.DEFAULT_BP)
+	return printer.out.String()
+}
+
+type printer struct {
+	out    strings.Builder
+	indent int
+	tokens []lexer.Token
+	// every comment of the source in order, next is the first one not printed yet
+	comments []lexer.Comment
+	next     int
+	// end of the token before each comment, the one the comment trails
+	after []lexer.Position
+	// source line of the last printed statement or comment, 0 at the start of a block
+	lastLine int
+	// the indentation is written with the first text of a line, blank lines stay empty
+	lineStart bool
+}
+
+func newPrinter(tokens []lexer.Token) *printer {
+
+	var comments []lexer.Comment
+	var after []lexer.Position
+
+	for i, token := range tokens {
+		for _, comment := range token.Comments {
+			comments = append(comments, comment)
+			if i > 0 {
+				after = append(after, tokens[i-1].EndPos)
+			} else {
+				after = append(after, lexer.Position{Index: -1})
+			}
+		}
+	}
+
+	return &printer{
+		tokens:   tokens,
+		comments: comments,
+		after:    after,
+	}
+}
+
+func (p *printer) write(text string) {
+	if p.lineStart {
+		p.out.WriteString(strings.Repeat(INDENT, p.indent))
+		p.lineStart = false
+	}
+	p.out.WriteString(text)
+}
+
+func (p *printer) newline() {
+	p.out.WriteString("\n")
+	p.lineStart = true
+}
+
+// item is a line of a block, a statement, a struct member or a case,
+// placed between the comments around it by its source position
+type item struct {
+	start lexer.Position
+	end   lexer.Position
+	print func()
+}
+
+// items prints one item per line in source order, each followed by a newline.
+// Comments before end which no item took are printed after the last one.
+func (p *printer) items(items []item, end lexer.Position) {
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].start.Index < items[j].start.Index
+	})
+
+	p.lastLine = 0
+
+	for _, it := range items {
+
+		p.leadingComments(it.start)
+
+		p.separate(it.start.Line)
+
+		it.print()
+
+		p.lastLine = it.end.Line
+
+		p.trailingComments(it.end, end)
+
+		p.newline()
+	}
+
+	p.leadingComments(end)
+}
+
+// block prints items between braces, one indentation level deeper
+func (p *printer) block(items []item, end lexer.Position) {
+
+	if len(items) == 0 && !p.hasCommentBefore(end) {
+		p.write("{}")
+		return
+	}
+
+	p.header(items, end)
+
+	p.newline()
+
+	p.items(items, end)
+
+	p.indent--
+
+	p.write("}")
+}
+
+// header prints the brace a block opens with. The comments left in the
+// header, like those after the parameters, and those on the line of the brace
+// stay with it: block comments before the brace, a line comment after it and
+// the other line comments on lines of their own below it.
+func (p *printer) header(items []item, end lexer.Position) {
+
+	open, ok := p.opening(end)
+
+	if !ok {
+		p.write("{")
+		p.indent++
+		return
+	}
+
+	first := end
+
+	for _, it := range items {
+		if it.start.Index < first.Index {
+			first = it.start
+		}
+	}
+
+	var after []lexer.Comment
+
+	for p.hasCommentBefore(first) && (p.comments[p.next].StartPos.Index < open.Index || p.comments[p.next].StartPos.Line == open.Line) {
+
+		comment := p.comments[p.next]
+		p.next++
+
+		if isInline(comment) && comment.StartPos.Index < open.Index {
+			p.write(comment.Text + " ")
+		} else {
+			after = append(after, comment)
+		}
+	}
+
+	p.write("{")
+
+	p.indent++
+
+	// a line comment ends the line, the comments after it go below
+	ended := false
+
+	for _, comment := range after {
+		if ended {
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.write(comment.Text)
+		ended = !isInline(comment)
+	}
+}
+
+// separate keeps one blank line where the source had one or more
+func (p *printer) separate(line int) {
+	if p.lastLine > 0 && line > p.lastLine+1 {
+		p.newline()
+	}
+}
+
+func (p *printer) hasCommentBefore(pos lexer.Position) bool {
+	return p.next < len(p.comments) && p.comments[p.next].StartPos.Index < pos.Index
+}
+
+// leadingComments prints the comments before pos on lines of their own
+func (p *printer) leadingComments(pos lexer.Position) {
+	for p.hasCommentBefore(pos) {
+
+		comment := p.comments[p.next]
+		p.next++
+
+		p.separate(comment.StartPos.Line)
+
+		p.write(comment.Text)
+		p.newline()
+
+		p.lastLine = comment.EndPos.Line
+	}
+}
+
+// trailingComments prints after an item the comments written inside it, which
+// no nested block took, and those starting on the line it ends on before the
+// block of the item closes. A comment after the brace trails the statement the
+// block belongs to.
+func (p *printer) trailingComments(end lexer.Position, blockEnd lexer.Position) {
+	for p.next < len(p.comments) {
+
+		comment := p.comments[p.next]
+
+		if comment.StartPos.Index > end.Index && (comment.StartPos.Line != end.Line || comment.StartPos.Index > blockEnd.Index) {
+			return
+		}
+
+		p.next++
+
+		p.write(" " + comment.Text)
+
+		p.lastLine = comment.EndPos.Line
+	}
+}
+
+// isInline tells if a comment can be printed in the middle of a line, a line comment ends it
+func isInline(comment lexer.Comment) bool {
+	return strings.HasPrefix(comment.Text, "/*") && !strings.Contains(comment.Text, "\n")
+}
+
+// inlineBefore prints the block comments written before pos inside the item
+// being printed, they keep their place in front of the code they precede
+func (p *printer) inlineBefore(pos lexer.Position) {
+	for p.hasCommentBefore(pos) && isInline(p.comments[p.next]) {
+		p.write(p.comments[p.next].Text + " ")
+		p.next++
+	}
+}
+
+// inlineAfter prints the block comments trailing the token ending at end,
+// fn f(x: i32 /* param */) keeps the comment after the parameter
+func (p *printer) inlineAfter(end lexer.Position) {
+	for p.next < len(p.comments) && p.after[p.next].Index == end.Index && isInline(p.comments[p.next]) {
+		p.write(" " + p.comments[p.next].Text)
+		p.next++
+	}
+}
+
+// opening finds the brace a block closing at end opens with
+func (p *printer) opening(end lexer.Position) (lexer.Position, bool) {
+
+	depth := 0
+
+	for i := len(p.tokens) - 1; i >= 0; i-- {
+
+		token := p.tokens[i]
+
+		if token.StartPos.Index >= end.Index {
+			continue
+		}
+
+		switch token.Kind {
+		case lexer.CLOSE_CURLY_TOKEN:
+			depth++
+		case lexer.OPEN_CURLY_TOKEN:
+			depth--
+			if depth == 0 {
+				return token.StartPos, true
+			}
+		}
+	}
+
+	return lexer.Position{}, false
+}
+
+func (p *printer) program(program ast.ProgramStmt) {
+
+	var items []item
+
+	if program.ModuleName != "" {
+		if start, end, ok := p.statementAt(lexer.MODULE_TOKEN, 0, -1); ok {
+			items = append(items, item{start, end, func() {
+				p.write("mod " + program.ModuleName + ";")
+			}})
+		}
+	}
+
+	for _, stmt := range program.Imports {
+		stmt := stmt
+		items = append(items, item{stmt.StartPos, stmt.EndPos, func() {
+			p.importStmt(stmt)
+		}})
+	}
+
+	items = append(items, p.statements(program.Contents)...)
+
+	eof := p.tokens[len(p.tokens)-1]
+
+	p.items(items, eof.EndPos)
+
+	// one newline ends the file, an empty file stays empty
+	text := strings.TrimSpace(p.out.String())
+
+	p.out.Reset()
+
+	if text != "" {
+		p.out.WriteString(text + "\n")
+	}
+}
+
+// statementAt finds the first statement starting with a keyword between two
+// source offsets and returns its position up to the semicolon.
+// The parser keeps no position for some statements, like mod or embed.
+func (p *printer) statementAt(keyword lexer.TOKEN_KIND, from int, to int) (lexer.Position, lexer.Position, bool) {
+
+	for i, token := range p.tokens {
+
+		if token.StartPos.Index < from || (to >= 0 && token.StartPos.Index >= to) || token.Kind != keyword {
+			continue
+		}
+
+		for _, end := range p.tokens[i:] {
+			if end.Kind == lexer.SEMI_COLON_TOKEN {
+				return token.StartPos, end.EndPos, true
+			}
+		}
+	}
+
+	return lexer.Position{}, lexer.Position{}, false
+}
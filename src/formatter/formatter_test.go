@@ -0,0 +1,179 @@
+package formatter_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"walrus/formatter"
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+)
+
+// tree returns the syntax tree of a source as json without positions, the
+// formatter moves the code but keeps its tree
+func tree(t *testing.T, name string, source string) string {
+
+	t.Helper()
+
+	p, err := parser.NewParserFromSource(source, name, false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.PanicOnError = true
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			t.Fatalf("the formatted source does not parse: %v\n%s", recovered, source)
+		}
+	}()
+
+	data, err := ast.EncodeJSON(p.Parse())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return positions.ReplaceAllString(string(data), "[]")
+}
+
+// a position is written as [line, column, index]
+var positions = regexp.MustCompile(`\[\d+, \d+, \d+\]`)
+
+// samples returns the walrus files of the code directory
+func samples(t *testing.T) []string {
+
+	t.Helper()
+
+	var samples []string
+
+	err := filepath.WalkDir("../../code", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".wal" {
+			samples = append(samples, path)
+		}
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) == 0 {
+		t.Fatal("no samples found")
+	}
+
+	return samples
+}
+
+// TestFormatSamples formats every sample which parses twice, the second time
+// changes nothing and both keep the tree of the sample
+func TestFormatSamples(t *testing.T) {
+
+	for _, sample := range samples(t) {
+
+		t.Run(strings.TrimPrefix(sample, "../../code/"), func(t *testing.T) {
+
+			source, err := os.ReadFile(sample)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			once, err := formatter.Format(sample, string(source))
+
+			if err != nil {
+				t.Skip("does not parse")
+			}
+
+			twice, err := formatter.Format(sample, once)
+
+			if err != nil {
+				t.Fatalf("the formatted source does not parse: %v", err)
+			}
+
+			if twice != once {
+				t.Fatalf("formatting again changes the source:\n%s", formatter.Diff(sample, once, twice))
+			}
+
+			if tree(t, sample, once) != tree(t, sample, string(source)) {
+				t.Fatalf("the formatted source parses to another tree:\n%s", once)
+			}
+		})
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+
+	for _, test := range []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"parameter",
+			"fn f(x: i32 /* param */) {\n    ret;\n}\n",
+			"fn f(x: i32 /* param */) {\n    ret;\n}\n",
+		},
+		{
+			"return type",
+			"fn f() -> i32 /* result */ {\nret 1;\n}\n",
+			"fn f() -> i32 /* result */ {\n    ret 1;\n}\n",
+		},
+		{
+			"header line comment",
+			"fn f(x: i32, // the x\n    y: i32) {\n    ret;\n}\n",
+			"fn f(x: i32, y: i32) { // the x\n    ret;\n}\n",
+		},
+		{
+			"brace line",
+			"fn f() { // nothing yet\n}\n",
+			"fn f() { // nothing yet\n}\n",
+		},
+		{
+			"argument",
+			"f(1 /* first */, /* second */ 2);\n",
+			"f(1 /* first */, /* second */ 2);\n",
+		},
+		{
+			"operand",
+			"let x := a + /* one */ 1; // done\n",
+			"let x := a + /* one */ 1; // done\n",
+		},
+		{
+			"after a block",
+			"fn g() { ret 1; } // after\n",
+			"fn g() {\n    ret 1;\n} // after\n",
+		},
+		{
+			"after an else",
+			"let x := 2;\nif x>1{print(\"a\");}els{print(\"b\");} // c1\n",
+			"let x := 2;\nif x > 1 {\n    print(\"a\");\n} els {\n    print(\"b\");\n} // c1\n",
+		},
+		{
+			"between branches",
+			"if x {\n    a();\n} /* then */ els {\n    b();\n}\n",
+			"if x {\n    a();\n} /* then */ els {\n    b();\n}\n",
+		},
+	} {
+		formatted, err := formatter.Format("test.wal", test.source)
+
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if formatted != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, formatted, test.want)
+			continue
+		}
+
+		if again, _ := formatter.Format("test.wal", formatted); again != formatted {
+			t.Errorf("%s: formatting again gives\n%s", test.name, again)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package formatter
+
+import (
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+func (p *printer) statements(nodes []ast.Node) []item {
+
+	items := make([]item, 0, len(nodes))
+
+	for _, node := range nodes {
+		node := node
+		items = append(items, item{ast.StartOf(node), endOf(node), func() {
+			p.stmt(node)
+		}})
+	}
+
+	return items
+}
+
+// blockStmt prints a block and the block comments after its closing brace,
+// } /* then */ els { keeps the comment before the els
+func (p *printer) blockStmt(block ast.BlockStmt) {
+	p.block(p.statements(block.Items), block.EndPos)
+	p.inlineAfter(block.EndPos)
+}
+
+func (p *printer) stmt(node ast.Node) {
+
+	switch n := node.(type) {
+	case ast.VariableDclStml:
+		p.varDecl(n)
+	case ast.FunctionDeclStmt:
+		p.write("fn ")
+		p.prototype(n.FunctionPrototype.Name.Identifier+TypeParams(n.TypeParams), n.Parameters, n.ReturnType)
+		p.write(" ")
+		p.blockStmt(n.Block)
+	case ast.TestStmt:
+		p.write("test fn " + n.Name.Identifier + "() ")
+		p.blockStmt(n.Block)
+	case ast.ReturnStmt:
+		if _, ok := n.Expression.(ast.VoidLiteral); ok || n.Expression == nil {
+			p.write("ret;")
+			return
+		}
+		p.write("ret ")
+		p.expr(n.Expression, parser.DEFAULT_BP)
+		p.write(";")
+	case ast.DeferStmt:
+		p.write("defer ")
+		p.expr(n.Call, parser.DEFAULT_BP)
+		p.write(";")
+	case ast.BreakStmt:
+		p.write("break;")
+	case ast.ContinueStmt:
+		p.write("continue;")
+	case ast.StructDeclStatement:
+		p.structDecl(n)
+	case ast.TraitDeclStatement:
+		p.traitDecl(n)
+	case ast.EnumDeclStatement:
+		p.enumDecl(n)
+	case ast.ImplementStatement:
+		p.implement(n)
+	case ast.IfStmt:
+		p.ifStmt(n)
+	case ast.ForStmt:
+		p.write("for " + n.Variable + " := ")
+		p.expr(n.Init, parser.ASSIGNMENT)
+		p.write("; ")
+		p.expr(n.Condition, parser.ASSIGNMENT)
+		p.write("; ")
+		p.expr(n.Post, parser.ASSIGNMENT)
+		p.write(" ")
+		p.blockStmt(n.Block)
+	case ast.ForeachStmt:
+		p.write("foreach " + n.Variable)
+		if n.IndexVariable != "" {
+			p.write(", " + n.IndexVariable)
+		}
+		p.write(" in ")
+		p.expr(n.Iterable, parser.ASSIGNMENT)
+		if n.WhereClause != nil {
+			p.write(" where ")
+			p.expr(n.WhereClause, parser.ASSIGNMENT)
+		}
+		p.write(" ")
+		p.blockStmt(n.Block)
+	case ast.WhileLoopStmt:
+		p.write("while ")
+		p.expr(n.Condition, parser.ASSIGNMENT)
+		p.write(" ")
+		p.blockStmt(n.Block)
+	case ast.SwitchStmt:
+		p.switchStmt(n)
+	case ast.BlockStmt:
+		p.blockStmt(n)
+	case ast.MatchExpr:
+		// a match statement ends with its braces
+		p.matchExpr(n)
+	default:
+		// everything else is an expression used as a statement
+		p.expr(node, parser.DEFAULT_BP)
+		p.write(";")
+	}
+}
+
+func (p *printer) importStmt(stmt ast.ImportStmt) {
+
+	p.write("import ")
+
+	if len(stmt.Identifiers) > 0 {
+		p.write("{ " + strings.Join(stmt.Identifiers, ", ") + " } from ")
+	}
+
+	p.write("\"" + stmt.ModuleName + "\";")
+}
+
+func (p *printer) varDecl(decl ast.VariableDclStml) {
+
+	if decl.IsConstant {
+		p.write("const ")
+	} else {
+		p.write("let ")
+	}
+
+	p.write(decl.Identifier.Identifier)
+
+	if decl.ExplicitType != nil {
+		p.write(": " + TypeName(decl.ExplicitType))
+		if decl.Value != nil {
+			p.write(" = ")
+			p.expr(decl.Value, parser.DEFAULT_BP)
+		}
+	} else {
+		p.write(" := ")
+		p.expr(decl.Value, parser.DEFAULT_BP)
+	}
+
+	p.write(";")
+}
+
+// prototype prints name(a: T, b: T) -> R, a void return type is left out
+func (p *printer) prototype(name string, params []ast.FunctionParameter, returnType ast.Type) {
+
+	p.write(name + "(")
+
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.inlineBefore(param.StartPos)
+		p.write(param.Identifier.Identifier + ": ")
+		if param.IsVariadic {
+			p.write("...")
+		}
+		p.write(TypeName(param.Type))
+		if param.DefaultVal != nil {
+			p.write(" = ")
+			p.expr(param.DefaultVal, parser.DEFAULT_BP)
+		}
+		p.inlineAfter(param.EndPos)
+	}
+
+	p.write(")")
+
+	if _, isVoid := returnType.(ast.VoidType); returnType != nil && !isVoid {
+		p.write(" -> " + TypeName(returnType))
+	}
+}
+
+// TypeParams prints the type parameters of a generic declaration, <T, U: A + B>
+func TypeParams(params []ast.TypeParam) string {
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	names := make([]string, len(params))
+
+	for i, param := range params {
+		names[i] = param.Name
+		if len(param.Bounds) > 0 {
+			names[i] += ": " + strings.Join(param.Bounds, " + ")
+		}
+	}
+
+	return "<" + strings.Join(names, ", ") + ">"
+}
+
+func (p *printer) structDecl(decl ast.StructDeclStatement) {
+
+	p.write("struct " + decl.StructName + TypeParams(decl.TypeParams) + " ")
+
+	var items []item
+
+	// embeds keep no position, their statements are found among the tokens
+	from := decl.StartPos.Index
+
+	for _, embed := range decl.Embeds {
+		embed := embed
+		start, end, ok := p.statementAt(lexer.EMBED_TOKEN, from, decl.EndPos.Index)
+		if !ok {
+			start, end = decl.StartPos, decl.StartPos
+		}
+		from = end.Index
+		items = append(items, item{start, end, func() {
+			p.write("embed " + embed + ";")
+		}})
+	}
+
+	for _, property := range decl.Properties {
+		property := property
+		items = append(items, item{property.StartPos, property.EndPos, func() {
+			p.write(access(property.IsPublic, property.IsStatic))
+			if property.ReadOnly {
+				p.write("readonly ")
+			}
+			p.write(property.Name + ": " + TypeName(property.Type) + ";")
+		}})
+	}
+
+	p.block(items, decl.EndPos)
+}
+
+// enumDecl writes a variant per line with a trailing comma
+func (p *printer) enumDecl(decl ast.EnumDeclStatement) {
+
+	p.write("enum " + decl.EnumName + TypeParams(decl.TypeParams) + " ")
+
+	var items []item
+
+	for _, variant := range decl.Variants {
+		variant := variant
+		items = append(items, item{variant.StartPos, variant.EndPos, func() {
+			p.write(variant.Name)
+			if len(variant.Fields) > 0 {
+				p.write("(")
+				for i, field := range variant.Fields {
+					if i > 0 {
+						p.write(", ")
+					}
+					p.write(field.Name + ": " + TypeName(field.Type))
+				}
+				p.write(")")
+			}
+			p.write(",")
+		}})
+	}
+
+	p.block(items, decl.EndPos)
+}
+
+func (p *printer) traitDecl(decl ast.TraitDeclStatement) {
+
+	p.write("trait " + decl.TraitName + TypeParams(decl.TypeParams) + " ")
+
+	var items []item
+
+	for name, method := range decl.Methods {
+		name, method := name, method
+		items = append(items, item{method.StartPos, method.EndPos, func() {
+			p.write(methodModifiers(method.IsPublic, method.IsStatic))
+			p.write("fn ")
+			p.prototype(name+TypeParams(method.TypeParams), method.Parameters, method.ReturnType)
+			p.write(";")
+		}})
+	}
+
+	p.block(items, decl.EndPos)
+}
+
+func (p *printer) implement(stmt ast.ImplementStatement) {
+
+	p.write("impl ")
+
+	// impl T { ... } keeps the type as its only trait
+	if len(stmt.Traits) == 1 && stmt.Traits[0] == stmt.Impliments {
+		p.write(stmt.Impliments + " ")
+	} else {
+		p.write(strings.Join(stmt.Traits, ", ") + " for " + stmt.Impliments + TypeParams(stmt.TypeParams) + " ")
+	}
+
+	var items []item
+
+	for _, method := range stmt.Methods {
+		method := method
+		items = append(items, item{method.StartPos, method.EndPos, func() {
+			p.write(methodModifiers(method.IsPublic, method.IsStatic))
+			p.write("fn ")
+			p.prototype(method.Name.Identifier+TypeParams(method.TypeParams), method.Parameters, method.ReturnType)
+			p.write(" ")
+			p.blockStmt(method.Block)
+		}})
+	}
+
+	p.block(items, stmt.EndPos)
+}
+
+// methodModifiers prints pub and static, a method is private without them
+func methodModifiers(isPublic bool, isStatic bool) string {
+
+	modifiers := ""
+
+	if isPublic {
+		modifiers = "pub "
+	}
+
+	if isStatic {
+		modifiers += "static "
+	}
+
+	return modifiers
+}
+
+// access prints the modifiers of a property, which always names its access
+func access(isPublic bool, isStatic bool) string {
+
+	modifiers := "priv "
+
+	if isPublic {
+		modifiers = "pub "
+	}
+
+	if isStatic {
+		modifiers += "static "
+	}
+
+	return modifiers
+}
+
+func (p *printer) ifStmt(stmt ast.IfStmt) {
+
+	p.write("if ")
+	p.expr(stmt.Condition, parser.ASSIGNMENT)
+	p.write(" ")
+	p.blockStmt(stmt.Block)
+	p.ifAlternate(stmt.Alternate)
+}
+
+// ifAlternate prints the elf and els blocks following an if
+func (p *printer) ifAlternate(alternate interface{}) {
+	switch alternate := alternate.(type) {
+	case ast.IfStmt:
+		p.write(" elf ")
+		p.expr(alternate.Condition, parser.ASSIGNMENT)
+		p.write(" ")
+		p.blockStmt(alternate.Block)
+		p.ifAlternate(alternate.Alternate)
+	case ast.BlockStmt:
+		p.write(" els ")
+		p.blockStmt(alternate)
+	}
+}
+
+func (p *printer) switchStmt(stmt ast.SwitchStmt) {
+
+	p.write("switch ")
+	p.expr(stmt.Discriminant, parser.ASSIGNMENT)
+	p.write(" ")
+
+	var items []item
+
+	// case a, b { ... } is parsed into a case per test sharing the position and block
+	for i := 0; i < len(stmt.Cases); {
+
+		first := stmt.Cases[i]
+
+		tests := []ast.Node{}
+
+		for ; i < len(stmt.Cases) && stmt.Cases[i].StartPos == first.StartPos; i++ {
+			if stmt.Cases[i].Test != nil {
+				tests = append(tests, stmt.Cases[i].Test)
+			}
+		}
+
+		items = append(items, item{first.StartPos, first.EndPos, func() {
+			if len(tests) == 0 {
+				p.write("default ")
+			} else {
+				p.write("case ")
+				for j, test := range tests {
+					if j > 0 {
+						p.write(", ")
+					}
+					p.expr(test, parser.ASSIGNMENT)
+				}
+				p.write(" ")
+			}
+			p.blockStmt(first.Consequent)
+		}})
+	}
+
+	p.block(items, stmt.EndPos)
+}
+
+// TypeName writes a type the way it is declared
+func TypeName(t ast.Type) string {
+	switch t := t.(type) {
+	case ast.ArrayType:
+		return "[]" + dataTypeName(t.ElementType)
+	case ast.MapType:
+		return "map[" + TypeName(t.KeyType) + "]" + TypeName(t.ValueType)
+	case ast.IntegerType, ast.FloatType:
+		return string(t.IType())
+	case ast.GenericType:
+		args := make([]string, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = TypeName(arg)
+		}
+		return string(t.Kind) + "<" + strings.Join(args, ", ") + ">"
+	case ast.FunctionType:
+		params := make([]string, len(t.Parameters))
+		for i, param := range t.Parameters {
+			params[i] = TypeName(param.Type)
+		}
+		name := "fn(" + strings.Join(params, ", ") + ")"
+		if _, isVoid := t.ReturnType.(ast.VoidType); t.ReturnType != nil && !isVoid {
+			name += " -> " + TypeName(t.ReturnType)
+		}
+		return name
+	default:
+		return dataTypeName(t.IType())
+	}
+}
+
+func dataTypeName(t ast.DATA_TYPE) string {
+	if t == ast.T_BOOLEAN {
+		return "bool"
+	}
+	return string(t)
+}
+
+// endOf is where the source of a node ends, an if statement ends with its last alternate
+func endOf(node ast.Node) lexer.Position {
+	if n, ok := node.(ast.IfStmt); ok {
+		switch alternate := n.Alternate.(type) {
+		case ast.IfStmt:
+			return endOf(alternate)
+		case ast.BlockStmt:
+			return alternate.EndPos
+		}
+	}
+	_, end := node.GetPos()
+	return end
+}
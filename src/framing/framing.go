@@ -0,0 +1,75 @@
+// Package framing reads and writes messages framed by a Content-Length
+// header, the framing both the language server and the debug adapter
+// protocols use:
+//
+//	Content-Length: 17\r\n
+//	\r\n
+//	{"seq": 1, ...}
+package framing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Conn reads the bodies of framed messages and writes bodies framed. Writes
+// may come from several goroutines, reads from one.
+type Conn struct {
+	in  *textproto.Reader
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func NewConn(in io.Reader, out io.Writer) *Conn {
+	return &Conn{
+		in:  textproto.NewReader(bufio.NewReader(in)),
+		out: out,
+	}
+}
+
+// Read returns the body of the next message, io.EOF when the input is closed
+func (c *Conn) Read() ([]byte, error) {
+
+	header, err := c.in.ReadMIMEHeader()
+
+	if err != nil {
+		if err == io.EOF || (err == io.ErrUnexpectedEOF && len(header) == 0) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+
+	if _, err := io.ReadFull(c.in.R, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// Write sends a body with its header, the messages of concurrent writes do not mix
+func (c *Conn) Write(body []byte) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+
+	_, err := c.out.Write(body)
+
+	return err
+}
@@ -0,0 +1,53 @@
+package framing_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"walrus/framing"
+)
+
+func TestRoundTrip(t *testing.T) {
+
+	var buffer bytes.Buffer
+
+	conn := framing.NewConn(&buffer, &buffer)
+
+	for _, body := range []string{`{"a": 1}`, ``, "{\"text\": \"two\\nlines é\"}"} {
+		if err := conn.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !strings.HasPrefix(buffer.String(), "Content-Length: 8\r\n\r\n{\"a\": 1}") {
+		t.Errorf("the first message is framed as %q", buffer.String())
+	}
+
+	for _, want := range []string{`{"a": 1}`, ``, "{\"text\": \"two\\nlines é\"}"} {
+		if body, err := conn.Read(); err != nil || string(body) != want {
+			t.Errorf("read %q, %v, want %q", body, err, want)
+		}
+	}
+
+	if _, err := conn.Read(); err != io.EOF {
+		t.Errorf("got %v at the end of the input, want io.EOF", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+
+	for _, input := range []string{
+		"Content-Length: x\r\n\r\n{}",
+		"Content-Length: -1\r\n\r\n{}",
+		"Content-Type: json\r\n\r\n{}",
+		"Content-Length: 10\r\n\r\n{}",
+	} {
+		conn := framing.NewConn(strings.NewReader(input), io.Discard)
+
+		if _, err := conn.Read(); err == nil || err == io.EOF {
+			t.Errorf("%q: got %v, want an error", input, err)
+		}
+	}
+}
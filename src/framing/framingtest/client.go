@@ -0,0 +1,110 @@
+// Package framingtest drives the servers of framed protocols in tests
+package framingtest
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"walrus/framing"
+)
+
+// how long a client waits for the server
+const timeout = 5 * time.Second
+
+// Client talks to a server over pipes the way an editor does
+type Client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	conn   *framing.Conn
+	bodies chan []byte
+	done   chan error
+}
+
+// Start runs a server reading in and writing out until it returns. Closing
+// the input when the test ends stops a server left waiting.
+func Start(t *testing.T, run func(in io.Reader, out io.Writer) error) *Client {
+
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &Client{
+		t:      t,
+		in:     clientOut,
+		conn:   framing.NewConn(clientIn, clientOut),
+		bodies: make(chan []byte, 16),
+		done:   make(chan error, 1),
+	}
+
+	go func() {
+		c.done <- run(serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	go func() {
+		defer close(c.bodies)
+		for {
+			body, err := c.conn.Read()
+			if err != nil {
+				return
+			}
+			c.bodies <- body
+		}
+	}()
+
+	t.Cleanup(func() { clientOut.Close() })
+
+	return c
+}
+
+// Send frames a message as json and sends it
+func (c *Client) Send(msg interface{}) {
+
+	c.t.Helper()
+
+	body, err := json.Marshal(msg)
+
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if err := c.conn.Write(body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// Next decodes the next message of the server into msg, the test fails when none comes
+func (c *Client) Next(msg interface{}) {
+
+	c.t.Helper()
+
+	select {
+	case body, ok := <-c.bodies:
+		if !ok {
+			c.t.Fatal("the server closed the connection")
+		}
+		if err := json.Unmarshal(body, msg); err != nil {
+			c.t.Fatalf("%v in %s", err, body)
+		}
+	case <-time.After(timeout):
+		c.t.Fatal("no message from the server")
+	}
+}
+
+// Wait returns what the server returned, the test fails when it does not stop
+func (c *Client) Wait() error {
+
+	c.t.Helper()
+
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(timeout):
+		c.t.Fatal("the server did not stop")
+	}
+
+	return nil
+}
@@ -26,12 +26,17 @@ const (
 	FOREACH_LOOP_STATEMENT         NODE_TYPE = "foreach loop statement"
 	FN_DECLARATION_STATEMENT       NODE_TYPE = "fn declaration statement"
 	FN_PROTOTYPE_STATEMENT         NODE_TYPE = "fn prototype statement"
+	TEST_STATEMENT                 NODE_TYPE = "test statement"
 	RETURN_STATEMENT               NODE_TYPE = "return statement"
 	BREAK_STATEMENT                NODE_TYPE = "break statement"
 	CONTINUE_STATEMENT             NODE_TYPE = "continue statement"
+	DEFER_STATEMENT                NODE_TYPE = "defer statement"
 	TRAIT_STATEMENT                NODE_TYPE = "trait statement"
 	STRUCT_STATEMENT               NODE_TYPE = "struct statement"
 	IMPLEMENTS_STATEMENT           NODE_TYPE = "implements statement"
+	ENUM_STATEMENT                 NODE_TYPE = "enum statement"
+	ENUM_VARIANT                   NODE_TYPE = "enum variant"
+	ENUM_FIELD                     NODE_TYPE = "enum field"
 
 	// Literals
 	INTEGER_LITERAL   NODE_TYPE = "integer literal"
@@ -43,6 +48,7 @@ const (
 	VOID_LITERAL      NODE_TYPE = "void literal"
 	ARRAY_LITERALS    NODE_TYPE = "array"
 	ARRAY_ACCESS      NODE_TYPE = "array access"
+	MAP_LITERAL       NODE_TYPE = "map literal"
 	STRUCT_LITERAL    NODE_TYPE = "struct literal"
 
 	PROPERTY 			NODE_TYPE = "property"
@@ -52,11 +58,18 @@ const (
 	IDENTIFIER            NODE_TYPE = "identifier"
 	BINARY_EXPRESSION     NODE_TYPE = "binary expression"
 	LOGICAL_EXPRESSION    NODE_TYPE = "logical expression"
+	MATCH_EXPRESSION      NODE_TYPE = "match expression"
+	MATCH_ARM             NODE_TYPE = "match arm"
+	VARIANT_PATTERN       NODE_TYPE = "variant pattern"
+	TRY_EXPRESSION        NODE_TYPE = "try expression"
 
 	// Functions
 	FUNCTION_PARAMETER NODE_TYPE = "function parameter"
+	TYPE_PARAMETER     NODE_TYPE = "type parameter"
 
 	FUNCTION_CALL_EXPRESSION NODE_TYPE = "function call expression"
+	NAMED_ARGUMENT           NODE_TYPE = "named argument"
+	FUNCTION_EXPRESSION      NODE_TYPE = "function expression"
 
 	// Unary Operations
 	UNARY_EXPRESSION NODE_TYPE = "unary expression"
@@ -76,3 +89,20 @@ type BaseStmt struct {
 	StartPos lexer.Position
 	EndPos   lexer.Position
 }
+
+// StartOf is where the source of a node begins, expressions built around an
+// operator start at their left operand
+func StartOf(node Node) lexer.Position {
+	switch n := node.(type) {
+	case BinaryExpr:
+		return StartOf(n.Left)
+	case AssignmentExpr:
+		return StartOf(n.Assigne)
+	case FunctionCallExpr:
+		return StartOf(n.Caller)
+	case PropertyExpr:
+		return StartOf(n.Object)
+	}
+	start, _ := node.GetPos()
+	return start
+}
@@ -132,8 +132,17 @@ func (a AssignmentExpr) GetPos() (lexer.Position, lexer.Position) {
 
 type FunctionCallExpr struct {
 	BaseStmt
-	Caller IdentifierExpr
-	Args   []Node
+	Caller    Node
+	Args      []Node
+	NamedArgs []NamedArg
+}
+
+// NamedArg is an argument given by the name of its parameter, name: value.
+// The named arguments of a call follow the positional ones.
+type NamedArg struct {
+	BaseStmt
+	Name  IdentifierExpr
+	Value Node
 }
 
 func (c FunctionCallExpr) INodeType() NODE_TYPE {
@@ -182,10 +191,34 @@ func (a ArrayLiterals) GetPos() (lexer.Position, lexer.Position) {
 	return a.StartPos, a.EndPos
 }
 
+// MapLiteral is map{"a": 1, "b": 2}, the entries keep the order they are
+// written in. Type is set when the literal names it, map[str]i32{}.
+type MapLiteral struct {
+	BaseStmt
+	Type    Type
+	Entries []MapEntry
+}
+
+// MapEntry is a key and its value in a map literal
+type MapEntry struct {
+	Key   Node
+	Value Node
+}
+
+func (m MapLiteral) INodeType() NODE_TYPE {
+	return m.Kind
+}
+func (m MapLiteral) GetPos() (lexer.Position, lexer.Position) {
+	return m.StartPos, m.EndPos
+}
+
+// ArrayIndexAccess is array[index], it reads an element of an array or the
+// value of a key in a map. The array is any expression, so accesses chain
+// like m["a"]["x"].
 type ArrayIndexAccess struct {
 	BaseStmt
-	ArrayName string
-	Index     Node
+	Array Node
+	Index Node
 }
 
 func (a ArrayIndexAccess) INodeType() NODE_TYPE {
@@ -194,3 +227,103 @@ func (a ArrayIndexAccess) INodeType() NODE_TYPE {
 func (a ArrayIndexAccess) GetPos() (lexer.Position, lexer.Position) {
 	return a.StartPos, a.EndPos
 }
+
+// FunctionExpr is an anonymous function, fn(x: i32) -> i32 { ret x * 2; }.
+// Its value is a closure over the scope it is evaluated in.
+type FunctionExpr struct {
+	BaseStmt
+	Parameters []FunctionParameter
+	ReturnType Type
+	Block      BlockStmt
+}
+
+func (f FunctionExpr) INodeType() NODE_TYPE {
+	return f.Kind
+}
+func (f FunctionExpr) GetPos() (lexer.Position, lexer.Position) {
+	return f.StartPos, f.EndPos
+}
+
+// TryExpr is value?, the value held by an Ok Result. An Err is returned from
+// the function the expression is written in.
+type TryExpr struct {
+	BaseStmt
+	Value Node
+}
+
+func (t TryExpr) INodeType() NODE_TYPE {
+	return t.Kind
+}
+func (t TryExpr) GetPos() (lexer.Position, lexer.Position) {
+	return t.StartPos, t.EndPos
+}
+
+// MatchExpr is match subject { pattern => body, ... }, its value is the body
+// of the first arm whose pattern matches the subject and whose guard holds
+type MatchExpr struct {
+	BaseStmt
+	Subject Node
+	Arms    []MatchArm
+}
+
+func (m MatchExpr) INodeType() NODE_TYPE {
+	return m.Kind
+}
+func (m MatchExpr) GetPos() (lexer.Position, lexer.Position) {
+	return m.StartPos, m.EndPos
+}
+
+// MatchArm is pattern if guard => body, Guard is nil for an arm without one.
+// A pattern is a literal, _ which matches anything, a name which binds the
+// value or names a plain variant of the enum matched, or a VariantPattern.
+type MatchArm struct {
+	BaseStmt
+	Pattern Node
+	Guard   Node
+	Body    Node
+}
+
+// VariantPattern is Variant(pattern, ...) or Enum.Variant(pattern, ...), it
+// matches a value of the variant whose payload matches the patterns in order.
+// EnumName is empty when the variant is not qualified.
+type VariantPattern struct {
+	BaseStmt
+	EnumName string
+	Variant  string
+	Fields   []Node
+}
+
+func (v VariantPattern) INodeType() NODE_TYPE {
+	return v.Kind
+}
+func (v VariantPattern) GetPos() (lexer.Position, lexer.Position) {
+	return v.StartPos, v.EndPos
+}
+
+// CallerName returns a printable name for the callee of a function call.
+// Calls through a module or an object are rendered as "object.name".
+func (c FunctionCallExpr) CallerName() string {
+	switch caller := c.Caller.(type) {
+	case IdentifierExpr:
+		return caller.Identifier
+	case PropertyExpr:
+		if object, ok := caller.Object.(IdentifierExpr); ok {
+			return object.Identifier + "." + caller.Property.Identifier
+		}
+		return caller.Property.Identifier
+	default:
+		return string(c.Caller.INodeType())
+	}
+}
+
+// IsExpression reports whether a node produces a value, as opposed to a statement
+func IsExpression(node Node) bool {
+	switch node.(type) {
+	case BinaryExpr, UnaryExpr, IdentifierExpr, AssignmentExpr, FunctionCallExpr, PropertyExpr,
+		NumericLiteral, StringLiteral, CharacterLiteral, BooleanLiteral, NullLiteral, VoidLiteral,
+		StructLiteral, ArrayLiterals, MapLiteral, ArrayIndexAccess, MatchExpr, FunctionExpr, TryExpr:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,414 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"walrus/frontend/lexer"
+)
+
+// SchemaVersion is the version of the JSON schema of syntax trees, it changes
+// when a tree written by an older walrus would no longer decode the same
+const SchemaVersion = 2
+
+// the schema name of the documents EncodeJSON writes
+const schemaName = "walrus-ast"
+
+// A syntax tree is written as
+//
+//	{"schema": "walrus-ast", "version": 2, "program": {"node": "ProgramStmt", ...}}
+//
+// Every object has a "node" member naming its Go type, the other members are
+// its fields in the order they are declared:
+//
+//   - the fields of an embedded BaseStmt are members of the object itself,
+//     other embedded structs are a member named after their type
+//   - a Node or Type field holds the object of its value, or null
+//   - a position is an array [line, column, index]
+//   - a map is an object with its keys sorted
+//
+// Decoding checks the names of the nodes and rejects unknown members, so a
+// tree written by hand or by another tool either decodes or gives an error.
+
+// EncodeJSON writes a program as a versioned JSON document, indented by two spaces
+func EncodeJSON(program ProgramStmt) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"schema":"` + schemaName + `","version":`)
+	fmt.Fprint(&buf, SchemaVersion)
+	buf.WriteString(`,"program":`)
+
+	if err := encodeValue(&buf, reflect.ValueOf(program)); err != nil {
+		return nil, err
+	}
+
+	buf.WriteString("}")
+
+	return indent(buf.Bytes())
+}
+
+// EncodeNodeJSON writes a single node the way EncodeJSON writes it inside a program
+func EncodeNodeJSON(node Node) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	if err := encodeValue(&buf, reflect.ValueOf(node)); err != nil {
+		return nil, err
+	}
+
+	return indent(buf.Bytes())
+}
+
+// a position as json.Indent spreads it over five lines
+var indentedPosition = regexp.MustCompile(`\[\s+(-?\d+),\s+(-?\d+),\s+(-?\d+)\s+\]`)
+
+// indent indents a document by two spaces, keeping positions on one line
+func indent(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	return indentedPosition.ReplaceAll(out.Bytes(), []byte("[$1, $2, $3]")), nil
+}
+
+// DecodeJSON reconstructs a program written by EncodeJSON
+func DecodeJSON(data []byte) (ProgramStmt, error) {
+
+	var document struct {
+		Schema  string
+		Version int
+		Program json.RawMessage
+	}
+
+	var program ProgramStmt
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		return program, err
+	}
+
+	if document.Schema != schemaName {
+		return program, fmt.Errorf("not a walrus syntax tree: schema is %q", document.Schema)
+	}
+
+	if document.Version != SchemaVersion {
+		return program, fmt.Errorf("unsupported syntax tree version %d, this walrus reads version %d", document.Version, SchemaVersion)
+	}
+
+	if err := decodeValue(document.Program, reflect.ValueOf(&program).Elem(), "program"); err != nil {
+		return program, err
+	}
+
+	return program, nil
+}
+
+// the types a Node, a Type or the alternate of an if can hold, by name
+var nodeTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, value := range []interface{}{
+		// statements
+		ProgramStmt{}, ModuleStmt{}, ImportStmt{}, BlockStmt{}, VariableDclStml{},
+		FunctionDeclStmt{}, TestStmt{}, ReturnStmt{}, BreakStmt{}, ContinueStmt{},
+		DeferStmt{},
+		StructDeclStatement{}, TraitDeclStatement{}, ImplementStatement{},
+		EnumDeclStatement{},
+		IfStmt{}, ForStmt{}, ForeachStmt{}, WhileLoopStmt{}, SwitchStmt{},
+		// expressions
+		BinaryExpr{}, UnaryExpr{}, IdentifierExpr{}, AssignmentExpr{}, FunctionCallExpr{},
+		PropertyExpr{}, StructLiteral{}, ArrayLiterals{}, MapLiteral{}, ArrayIndexAccess{},
+		NumericLiteral{}, StringLiteral{}, CharacterLiteral{}, BooleanLiteral{},
+		NullLiteral{}, VoidLiteral{}, MatchExpr{}, VariantPattern{}, FunctionExpr{},
+		TryExpr{},
+		// types
+		IntegerType{}, FloatType{}, BoolType{}, StringType{}, CharType{}, NullType{},
+		VoidType{}, ArrayType{}, MapType{}, StructType{}, TraitType{}, EnumType{}, FunctionType{},
+		NativeFnType{}, AnyType{}, ModuleType{}, TypeParamType{}, GenericType{},
+	} {
+		t := reflect.TypeOf(value)
+		nodeTypes[t.Name()] = t
+	}
+}
+
+var positionType = reflect.TypeOf(lexer.Position{})
+var baseStmtType = reflect.TypeOf(BaseStmt{})
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeValue(buf, v.Elem())
+	case reflect.Struct:
+		if v.Type() == positionType {
+			p := v.Interface().(lexer.Position)
+			fmt.Fprintf(buf, "[%d,%d,%d]", p.Line, p.Column, p.Index)
+			return nil
+		}
+		buf.WriteString(`{"node":`)
+		writeString(buf, v.Type().Name())
+		if err := encodeFields(buf, v); err != nil {
+			return err
+		}
+		buf.WriteString("}")
+		return nil
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		buf.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeString(buf, key)
+			buf.WriteString(":")
+			if err := encodeValue(buf, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+		return nil
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Uint8, reflect.Uint64:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+
+	return fmt.Errorf("cannot encode a %s in a syntax tree", v.Type())
+}
+
+// encodeFields writes the members of a struct after its node name
+func encodeFields(buf *bytes.Buffer, v reflect.Value) error {
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if field.Anonymous && field.Type == baseStmtType {
+			if err := encodeFields(buf, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if omitEmpty(field) && value.Len() == 0 {
+			continue
+		}
+
+		buf.WriteString(",")
+		writeString(buf, field.Name)
+		buf.WriteString(":")
+
+		if err := encodeValue(buf, value); err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// the comments of a token are left out when there are none
+func omitEmpty(field reflect.StructField) bool {
+	return strings.Contains(field.Tag.Get("json"), "omitempty")
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// decodeValue decodes into v, path names the value in errors like program.Contents[2].Value
+func decodeValue(data json.RawMessage, v reflect.Value, path string) error {
+
+	isNull := bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		name, err := nodeName(data, path)
+		if err != nil {
+			return err
+		}
+		t, ok := nodeTypes[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown node %q", path, name)
+		}
+		if !t.Implements(v.Type()) {
+			return fmt.Errorf("%s: a %s is not a %s", path, name, v.Type().Name())
+		}
+		value := reflect.New(t).Elem()
+		if err := decodeValue(data, value, path); err != nil {
+			return err
+		}
+		v.Set(value)
+		return nil
+	case reflect.Struct:
+		if v.Type() == positionType {
+			var p [3]int
+			if err := json.Unmarshal(data, &p); err != nil {
+				return fmt.Errorf("%s: a position is [line, column, index]", path)
+			}
+			v.Set(reflect.ValueOf(lexer.Position{Line: p[0], Column: p[1], Index: p[2]}))
+			return nil
+		}
+		return decodeStruct(data, v, path)
+	case reflect.Slice:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Map:
+		if isNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for key, entry := range entries {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(entry, value, fmt.Sprintf("%s[%q]", path, key)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+		return nil
+	}
+
+	if err := json.Unmarshal(data, v.Addr().Interface()); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+func nodeName(data json.RawMessage, path string) (string, error) {
+
+	var object struct {
+		Node string `json:"node"`
+	}
+
+	if err := json.Unmarshal(data, &object); err != nil || object.Node == "" {
+		return "", fmt.Errorf("%s: expected an object with a node name", path)
+	}
+
+	return object.Node, nil
+}
+
+func decodeStruct(data json.RawMessage, v reflect.Value, path string) error {
+
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &members); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	name, err := nodeName(data, path)
+
+	if err != nil {
+		return err
+	}
+
+	if name != v.Type().Name() {
+		return fmt.Errorf("%s: expected a %s but got a %s", path, v.Type().Name(), name)
+	}
+
+	delete(members, "node")
+
+	if err := decodeFields(members, v, path); err != nil {
+		return err
+	}
+
+	for member := range members {
+		return fmt.Errorf("%s: unknown member %q of %s", path, member, name)
+	}
+
+	return nil
+}
+
+// decodeFields fills the fields of a struct and removes the members it used
+func decodeFields(members map[string]json.RawMessage, v reflect.Value, path string) error {
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type == baseStmtType {
+			if err := decodeFields(members, v.Field(i), path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		member, ok := members[field.Name]
+
+		if !ok {
+			continue
+		}
+
+		delete(members, field.Name)
+
+		if err := decodeValue(member, v.Field(i), path+"."+field.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
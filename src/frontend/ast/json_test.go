@@ -0,0 +1,130 @@
+package ast_test
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+)
+
+// parse returns the tree of a sample, false when it does not parse
+func parse(t *testing.T, path string) (program ast.ProgramStmt, ok bool) {
+
+	t.Helper()
+
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := parser.NewParserFromSource(string(source), path, false)
+
+	if err != nil {
+		return program, false
+	}
+
+	p.PanicOnError = true
+
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	return p.Parse(), true
+}
+
+// samples returns the walrus files of the code directory
+func samples(t *testing.T) []string {
+
+	t.Helper()
+
+	var samples []string
+
+	err := filepath.WalkDir("../../../code", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".wal" {
+			samples = append(samples, path)
+		}
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) == 0 {
+		t.Fatal("no samples found")
+	}
+
+	return samples
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+
+	for _, sample := range samples(t) {
+
+		t.Run(strings.TrimPrefix(sample, "../../../code/"), func(t *testing.T) {
+
+			program, ok := parse(t, sample)
+
+			if !ok {
+				t.Skip("does not parse")
+			}
+
+			data, err := ast.EncodeJSON(program)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := ast.DecodeJSON(data)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(program, decoded) {
+				t.Fatalf("decoded tree differs from the parsed one")
+			}
+
+			again, err := ast.EncodeJSON(decoded)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(data, again) {
+				t.Fatalf("encoding the decoded tree gives different json")
+			}
+		})
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+
+	for _, test := range []struct {
+		name string
+		json string
+		want string
+	}{
+		{"schema", `{"schema": "other", "version": 1, "program": null}`, "not a walrus syntax tree"},
+		{"version", `{"schema": "walrus-ast", "version": 99, "program": null}`, "unsupported syntax tree version 99"},
+		{"node", `{"schema": "walrus-ast", "version": 2, "program": {"node": "BlockStmt"}}`, "expected a ProgramStmt but got a BlockStmt"},
+		{"member", `{"schema": "walrus-ast", "version": 2, "program": {"node": "ProgramStmt", "Extra": 1}}`, `unknown member "Extra"`},
+		{"unknown", `{"schema": "walrus-ast", "version": 2, "program": {"node": "ProgramStmt", "Contents": [{"node": "Nope"}]}}`, `program.Contents[0]: unknown node "Nope"`},
+		{"interface", `{"schema": "walrus-ast", "version": 2, "program": {"node": "ProgramStmt", "Contents": [{"node": "IntegerType"}]}}`, "IntegerType is not a Node"},
+	} {
+		_, err := ast.DecodeJSON([]byte(test.json))
+
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
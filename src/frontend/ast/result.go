@@ -0,0 +1,40 @@
+package ast
+
+// Result is the builtin enum of a computation that may fail, Ok holds the
+// value and Err the error. The ? operator unwraps an Ok and returns an Err
+// from the function it is written in.
+const (
+	T_RESULT    DATA_TYPE = "Result"
+	OK_VARIANT            = "Ok"
+	ERR_VARIANT           = "Err"
+	// the errors of a Result implement it, str does
+	ERROR_TRAIT = "error"
+)
+
+// ResultEnum is the declaration of enum Result<T, E: error> { Ok(value: T), Err(error: E) }
+func ResultEnum() EnumType {
+	return EnumType{
+		Kind: T_RESULT,
+		TypeParams: []TypeParam{
+			{BaseStmt: BaseStmt{Kind: TYPE_PARAMETER}, Name: "T"},
+			{BaseStmt: BaseStmt{Kind: TYPE_PARAMETER}, Name: "E", Bounds: []string{ERROR_TRAIT}},
+		},
+		Variants: []EnumVariant{
+			{
+				BaseStmt: BaseStmt{Kind: ENUM_VARIANT},
+				Name:     OK_VARIANT,
+				Fields:   []EnumField{{Name: "value", Type: TypeParamType{Kind: "T"}}},
+			},
+			{
+				BaseStmt: BaseStmt{Kind: ENUM_VARIANT},
+				Name:     ERR_VARIANT,
+				Fields:   []EnumField{{Name: "error", Type: TypeParamType{Kind: "E"}}},
+			},
+		},
+	}
+}
+
+// ResultOf is the type Result<value, err>
+func ResultOf(value Type, err Type) GenericType {
+	return GenericType{Kind: T_RESULT, TypeArgs: []Type{value, err}}
+}
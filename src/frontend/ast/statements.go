@@ -83,9 +83,49 @@ type FunctionParameter struct {
 	DefaultVal Node
 }
 
+// Arity returns the fewest and the most arguments a function with the
+// parameters takes. Parameters with a default value may be left out, the
+// most is -1 when the last parameter is variadic.
+func Arity(params []FunctionParameter) (int, int) {
+
+	required := 0
+
+	for _, param := range params {
+		if param.DefaultVal == nil && !param.IsVariadic {
+			required++
+		}
+	}
+
+	if len(params) > 0 && params[len(params)-1].IsVariadic {
+		return required, -1
+	}
+
+	return required, len(params)
+}
+
+// ParamIndex returns the index of the parameter with the name, a named
+// argument is given to it. It is -1 when no parameter has the name.
+func ParamIndex(params []FunctionParameter, name string) int {
+	for i, param := range params {
+		if param.Identifier.Identifier == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// TypeParam is a type parameter of a generic declaration, T or T: Display + Debug.
+// Bounds are the traits the type given for it must implement.
+type TypeParam struct {
+	BaseStmt
+	Name   string
+	Bounds []string
+}
+
 type FunctionPrototype struct {
 	BaseStmt
 	Name       IdentifierExpr
+	TypeParams []TypeParam
 	Parameters []FunctionParameter
 	ReturnType Type
 }
@@ -103,6 +143,20 @@ func (f FunctionDeclStmt) GetPos() (lexer.Position, lexer.Position) {
 	return f.StartPos, f.EndPos
 }
 
+// TestStmt is a test fn name() { ... }, only walrus test runs it
+type TestStmt struct {
+	BaseStmt
+	Name  IdentifierExpr
+	Block BlockStmt
+}
+
+func (t TestStmt) INodeType() NODE_TYPE {
+	return t.Kind
+}
+func (t TestStmt) GetPos() (lexer.Position, lexer.Position) {
+	return t.StartPos, t.EndPos
+}
+
 type ReturnStmt struct {
 	BaseStmt
 	Expression Node
@@ -115,6 +169,20 @@ func (r ReturnStmt) GetPos() (lexer.Position, lexer.Position) {
 	return r.StartPos, r.EndPos
 }
 
+// DeferStmt is defer call;, the call is made when the function around the
+// statement returns. The calls deferred by a function run last first.
+type DeferStmt struct {
+	BaseStmt
+	Call FunctionCallExpr
+}
+
+func (d DeferStmt) INodeType() NODE_TYPE {
+	return d.Kind
+}
+func (d DeferStmt) GetPos() (lexer.Position, lexer.Position) {
+	return d.StartPos, d.EndPos
+}
+
 type BreakStmt struct {
 	BaseStmt
 }
@@ -150,6 +218,7 @@ type Property struct {
 type StructDeclStatement struct {
 	BaseStmt
 	StructName string
+	TypeParams []TypeParam
 	Properties map[string]Property
 	Methods    map[string]FunctionType
 	Embeds     []string
@@ -163,6 +232,35 @@ func (s StructDeclStatement) GetPos() (lexer.Position, lexer.Position) {
 }
 
 
+// EnumDeclStatement is enum Name { Variant(field: T, ...), Variant, ... }
+type EnumDeclStatement struct {
+	BaseStmt
+	EnumName   string
+	TypeParams []TypeParam
+	Variants   []EnumVariant
+}
+
+func (e EnumDeclStatement) INodeType() NODE_TYPE {
+	return e.Kind
+}
+func (e EnumDeclStatement) GetPos() (lexer.Position, lexer.Position) {
+	return e.StartPos, e.EndPos
+}
+
+// EnumVariant is a variant of an enum, Fields is its payload in order and empty for a plain variant
+type EnumVariant struct {
+	BaseStmt
+	Name   string
+	Fields []EnumField
+}
+
+// EnumField is a name: Type of the payload of a variant
+type EnumField struct {
+	BaseStmt
+	Name string
+	Type Type
+}
+
 type Method struct {
 	BaseStmt
 	FunctionType
@@ -171,7 +269,8 @@ type Method struct {
 }
 type TraitDeclStatement struct {
 	BaseStmt
-	TraitName string
+	TraitName  string
+	TypeParams []TypeParam
 	Methods   map[string]Method
 }
 
@@ -193,6 +292,7 @@ type MethodImplementStmt struct {
 type ImplementStatement struct {
 	BaseStmt
 	Impliments string
+	TypeParams []TypeParam
 	Traits     []string
 	Methods    map[string]MethodImplementStmt
 }
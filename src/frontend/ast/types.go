@@ -25,9 +25,13 @@ const (
 	T_NULL      DATA_TYPE = "null"
 
 	T_ARRAY		DATA_TYPE = "array"
+	T_MAP		DATA_TYPE = "map"
 	T_STRUCT	DATA_TYPE = "struct"
+	T_ENUM		DATA_TYPE = "enum"
 	T_NATIVE_FN DATA_TYPE = "native fn"
 	T_FN		DATA_TYPE = "fn"
+	T_MODULE	DATA_TYPE = "module"
+	T_ANY		DATA_TYPE = "any"
 )
 
 type IntegerType struct {
@@ -101,6 +105,21 @@ func (a ArrayType) IType() DATA_TYPE {
 	return a.Kind
 }
 
+// MapType is map[K]V. Maps of other key or value types are other types, so
+// its name is written with them.
+type MapType struct {
+	Kind      DATA_TYPE
+	KeyType   Type
+	ValueType Type
+}
+
+func (m MapType) IType() DATA_TYPE {
+	if m.KeyType == nil || m.ValueType == nil {
+		return m.Kind
+	}
+	return DATA_TYPE("map[" + string(m.KeyType.IType()) + "]" + string(m.ValueType.IType()))
+}
+
 type StructType struct {
 	Kind DATA_TYPE
 }
@@ -118,9 +137,22 @@ func (t TraitType) IType() DATA_TYPE {
 	return t.Kind
 }
 
+// EnumType is the type of the name of an enum, Kind is the name. A value of
+// the enum has the StructType of the name, like the instances of a struct.
 type EnumType struct {
-	Kind   DATA_TYPE
-	Fields []DATA_TYPE
+	Kind       DATA_TYPE
+	TypeParams []TypeParam
+	Variants   []EnumVariant
+}
+
+// Variant returns the variant of an enum by name
+func (e EnumType) Variant(name string) (EnumVariant, bool) {
+	for _, variant := range e.Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return EnumVariant{}, false
 }
 
 func (e EnumType) IType() DATA_TYPE {
@@ -129,7 +161,8 @@ func (e EnumType) IType() DATA_TYPE {
 
 type FunctionType struct {
 	Kind       DATA_TYPE
-	ReturnType DATA_TYPE
+	TypeParams []TypeParam
+	ReturnType Type
 	Parameters []FunctionParameter
 }
 
@@ -143,3 +176,44 @@ type NativeFnType struct {
 func (n NativeFnType) IType() DATA_TYPE {
 	return n.Kind
 }
+
+// AnyType accepts a value of every type, it is used by natives like print
+type AnyType struct {
+	Kind DATA_TYPE
+}
+
+func (a AnyType) IType() DATA_TYPE {
+	return a.Kind
+}
+
+// TypeParamType is a use of a type parameter inside its generic declaration,
+// Kind is the name of the parameter
+type TypeParamType struct {
+	Kind DATA_TYPE
+}
+
+func (t TypeParamType) IType() DATA_TYPE {
+	return t.Kind
+}
+
+// GenericType is a generic struct given type arguments, Box<i32>. Kind is
+// the name of the struct, its values are instances of that struct.
+type GenericType struct {
+	Kind     DATA_TYPE
+	TypeArgs []Type
+}
+
+func (g GenericType) IType() DATA_TYPE {
+	return g.Kind
+}
+
+// ModuleType is the static type of an imported module, Members maps names to their types
+type ModuleType struct {
+	Kind    DATA_TYPE
+	Name    string
+	Members map[string]Type
+}
+
+func (m ModuleType) IType() DATA_TYPE {
+	return m.Kind
+}
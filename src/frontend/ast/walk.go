@@ -0,0 +1,588 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Visitor's Visit method is called for every node Walk meets. If the
+// visitor it returns is not nil, Walk visits the children of the node with
+// it, then calls its Visit with nil.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a tree depth first: it calls v.Visit(node), then walks the
+// children of node with the visitor Visit returned, in the order they are
+// written. Types are not nodes and are not visited. The properties of a
+// struct literal and the methods of an impl are walked in the order of their
+// names.
+func Walk(node Node, v Visitor) {
+
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	walkChildren(node, v)
+
+	v.Visit(nil)
+}
+
+func walkList[T Node](list []T, v Visitor) {
+	for _, node := range list {
+		Walk(node, v)
+	}
+}
+
+// walkOptional walks a child which may be missing, like the value of a let without one
+func walkOptional(node Node, v Visitor) {
+	if node != nil {
+		Walk(node, v)
+	}
+}
+
+func walkChildren(node Node, v Visitor) {
+
+	switch n := node.(type) {
+	case ProgramStmt:
+		walkList(n.Imports, v)
+		walkList(n.Contents, v)
+	case BlockStmt:
+		walkList(n.Items, v)
+	case VariableDclStml:
+		Walk(n.Identifier, v)
+		walkOptional(n.Value, v)
+	case FunctionDeclStmt:
+		Walk(n.Name, v)
+		for _, parameter := range n.Parameters {
+			Walk(parameter.Identifier, v)
+			walkOptional(parameter.DefaultVal, v)
+		}
+		Walk(n.Block, v)
+	case FunctionExpr:
+		for _, parameter := range n.Parameters {
+			Walk(parameter.Identifier, v)
+			walkOptional(parameter.DefaultVal, v)
+		}
+		Walk(n.Block, v)
+	case TestStmt:
+		Walk(n.Name, v)
+		Walk(n.Block, v)
+	case ReturnStmt:
+		walkOptional(n.Expression, v)
+	case DeferStmt:
+		Walk(n.Call, v)
+	case ImplementStatement:
+		for _, name := range sortedKeys(n.Methods) {
+			Walk(n.Methods[name].FunctionDeclStmt, v)
+		}
+	case IfStmt:
+		Walk(n.Condition, v)
+		Walk(n.Block, v)
+		if alternate, ok := n.Alternate.(Node); ok {
+			Walk(alternate, v)
+		}
+	case ForStmt:
+		walkOptional(n.Init, v)
+		walkOptional(n.Condition, v)
+		walkOptional(n.Post, v)
+		Walk(n.Block, v)
+	case ForeachStmt:
+		Walk(n.Iterable, v)
+		walkOptional(n.WhereClause, v)
+		Walk(n.Block, v)
+	case WhileLoopStmt:
+		Walk(n.Condition, v)
+		Walk(n.Block, v)
+	case SwitchStmt:
+		Walk(n.Discriminant, v)
+		for _, c := range n.Cases {
+			walkOptional(c.Test, v)
+			Walk(c.Consequent, v)
+		}
+	case BinaryExpr:
+		Walk(n.Left, v)
+		Walk(n.Right, v)
+	case UnaryExpr:
+		Walk(n.Argument, v)
+	case AssignmentExpr:
+		Walk(n.Assigne, v)
+		Walk(n.Value, v)
+	case FunctionCallExpr:
+		Walk(n.Caller, v)
+		walkList(n.Args, v)
+		for _, arg := range n.NamedArgs {
+			Walk(arg.Name, v)
+			Walk(arg.Value, v)
+		}
+	case StructLiteral:
+		for _, name := range sortedKeys(n.Properties) {
+			Walk(n.Properties[name], v)
+		}
+	case PropertyExpr:
+		Walk(n.Object, v)
+		Walk(n.Property, v)
+	case ArrayLiterals:
+		walkList(n.Elements, v)
+	case MapLiteral:
+		for _, entry := range n.Entries {
+			Walk(entry.Key, v)
+			Walk(entry.Value, v)
+		}
+	case ArrayIndexAccess:
+		Walk(n.Array, v)
+		Walk(n.Index, v)
+	case MatchExpr:
+		Walk(n.Subject, v)
+		for _, arm := range n.Arms {
+			Walk(arm.Pattern, v)
+			walkOptional(arm.Guard, v)
+			Walk(arm.Body, v)
+		}
+	case VariantPattern:
+		walkList(n.Fields, v)
+	case TryExpr:
+		Walk(n.Value, v)
+	case ModuleStmt, ImportStmt, BreakStmt, ContinueStmt, StructDeclStatement, TraitDeclStatement, EnumDeclStatement,
+		IdentifierExpr, NumericLiteral, StringLiteral, CharacterLiteral, BooleanLiteral, NullLiteral, VoidLiteral:
+		// no children
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node %T", node))
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a tree in the order of Walk, calling f for every node.
+// When f returns true the children of the node are inspected too, followed
+// by a call f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// ApplyFunc is called by Apply for every node, with the cursor at the node
+type ApplyFunc func(*Cursor) bool
+
+// A Cursor is where Apply is in the tree: the node, the parent holding it
+// and the field of the parent it is in.
+type Cursor struct {
+	parent Node
+	name   string
+	// the position in a list, -1 when the node is not in one
+	index int
+	// the key in a map, the property of a struct literal or the method of an impl
+	key  string
+	slot slot
+	node Node
+	// what the functions asked for
+	deleted       bool
+	before, after []Node
+}
+
+type slot int
+
+const (
+	// a field holding one node
+	field slot = iota
+	list
+	mapEntry
+)
+
+// Node is the current node, the replacement when it was replaced
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent is the node holding the current one, as it was before Apply changed it
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name is the field of the parent holding the node, like "Contents" or
+// "Cases.Test" for a node in the elements of a list field
+func (c *Cursor) Name() string { return c.name }
+
+// Index is the position of the node in its list, or of the element of a list
+// holding it like a parameter, -1 otherwise
+func (c *Cursor) Index() int { return c.index }
+
+// Key is the key of the node in its map, empty when it is not in a map
+func (c *Cursor) Key() string { return c.key }
+
+// Replace replaces the current node. A field of a concrete node type, like
+// the block of a loop, only takes a node of that type.
+func (c *Cursor) Replace(node Node) {
+	if node == nil {
+		panic("ast.Cursor.Replace: nil node, use Delete to remove a node")
+	}
+	c.node = node
+}
+
+// Delete removes the current node from its list or map
+func (c *Cursor) Delete() {
+	if c.slot == field {
+		panic(fmt.Sprintf("ast.Cursor.Delete: %s of %T is not in a list", c.name, c.parent))
+	}
+	c.deleted = true
+}
+
+// InsertBefore inserts a node in the list of the current node, before it.
+// The inserted nodes are not walked.
+func (c *Cursor) InsertBefore(node Node) {
+	c.inList("InsertBefore")
+	c.before = append(c.before, node)
+}
+
+// InsertAfter inserts a node in the list of the current node, after it.
+// The inserted nodes are not walked.
+func (c *Cursor) InsertAfter(node Node) {
+	c.inList("InsertAfter")
+	c.after = append(c.after, node)
+}
+
+func (c *Cursor) inList(method string) {
+	if c.slot != list {
+		panic(fmt.Sprintf("ast.Cursor.%s: %s of %T is not a list", method, c.name, c.parent))
+	}
+}
+
+// Apply traverses a tree in the order of Walk and returns it rewritten by
+// the functions. Nodes are values, so the nodes on the path to a change are
+// copied and the tree given is left as it was.
+//
+// pre is called before the children of a node and post after them, either
+// may be nil. When pre returns false the children and post are skipped, when
+// post returns false Apply stops and returns the tree as rewritten so far.
+// The children walked are those of the node pre left at the cursor.
+func Apply(root Node, pre, post ApplyFunc) Node {
+	a := &applier{pre: pre, post: post}
+	return a.one(nil, "", root)
+}
+
+type applier struct {
+	pre, post ApplyFunc
+	// post returned false
+	stopped bool
+}
+
+func (a *applier) apply(c *Cursor) {
+
+	if a.pre != nil && !a.pre(c) {
+		return
+	}
+
+	if !c.deleted {
+		c.node = a.children(c.node)
+	}
+
+	if a.stopped {
+		return
+	}
+
+	if a.post != nil && !a.post(c) {
+		a.stopped = true
+	}
+}
+
+// one rewrites a field holding a single node, which may be missing
+func (a *applier) one(parent Node, name string, node Node) Node {
+
+	if node == nil || a.stopped {
+		return node
+	}
+
+	c := &Cursor{parent: parent, name: name, index: -1, slot: field, node: node}
+
+	a.apply(c)
+
+	return c.node
+}
+
+// element rewrites a node held by an element of a list, like the test of a switch case
+func (a *applier) element(parent Node, name string, index int, node Node) Node {
+
+	if node == nil || a.stopped {
+		return node
+	}
+
+	c := &Cursor{parent: parent, name: name, index: index, slot: field, node: node}
+
+	a.apply(c)
+
+	return c.node
+}
+
+func as[T Node](parent Node, name string, node Node) T {
+	n, ok := node.(T)
+	if !ok {
+		var want T
+		panic(fmt.Sprintf("ast.Apply: %s of %T must be a %T, not a %T", name, parent, want, node))
+	}
+	return n
+}
+
+// applyAs rewrites a field of a concrete node type
+func applyAs[T Node](a *applier, parent Node, name string, node T) T {
+	return as[T](parent, name, a.one(parent, name, node))
+}
+
+// applyList rewrites a list, leaving out the nodes deleted and putting the inserted ones in place
+func applyList[T Node](a *applier, parent Node, name string, nodes []T) []T {
+
+	if nodes == nil {
+		return nil
+	}
+
+	rewritten := make([]T, 0, len(nodes))
+
+	for i, node := range nodes {
+
+		if a.stopped {
+			return append(rewritten, nodes[i:]...)
+		}
+
+		c := &Cursor{parent: parent, name: name, index: i, slot: list, node: node}
+
+		a.apply(c)
+
+		for _, n := range c.before {
+			rewritten = append(rewritten, as[T](parent, name, n))
+		}
+
+		if !c.deleted {
+			rewritten = append(rewritten, as[T](parent, name, c.node))
+		}
+
+		for _, n := range c.after {
+			rewritten = append(rewritten, as[T](parent, name, n))
+		}
+	}
+
+	return rewritten
+}
+
+// applyMap rewrites the nodes of a map in the order of its keys, get and put
+// take the node out of an entry and put it back
+func applyMap[V any](a *applier, parent Node, name string, entries map[string]V, get func(V) Node, put func(V, Node) V) map[string]V {
+
+	if entries == nil {
+		return nil
+	}
+
+	rewritten := make(map[string]V, len(entries))
+
+	for key, entry := range entries {
+		rewritten[key] = entry
+	}
+
+	for _, key := range sortedKeys(entries) {
+
+		if a.stopped {
+			break
+		}
+
+		c := &Cursor{parent: parent, name: name, index: -1, key: key, slot: mapEntry, node: get(entries[key])}
+
+		a.apply(c)
+
+		if c.deleted {
+			delete(rewritten, key)
+		} else {
+			rewritten[key] = put(entries[key], c.node)
+		}
+	}
+
+	return rewritten
+}
+
+// children rewrites the children of a node and returns the node holding them
+func (a *applier) children(node Node) Node {
+
+	switch n := node.(type) {
+	case ProgramStmt:
+		n.Imports = applyList(a, node, "Imports", n.Imports)
+		n.Contents = applyList(a, node, "Contents", n.Contents)
+		return n
+	case BlockStmt:
+		n.Items = applyList(a, node, "Items", n.Items)
+		return n
+	case VariableDclStml:
+		n.Identifier = applyAs(a, node, "Identifier", n.Identifier)
+		n.Value = a.one(node, "Value", n.Value)
+		return n
+	case FunctionDeclStmt:
+		n.FunctionPrototype = a.prototype(node, n.FunctionPrototype)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case FunctionExpr:
+		n.Parameters = a.parameters(node, n.Parameters)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case TestStmt:
+		n.Name = applyAs(a, node, "Name", n.Name)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case ReturnStmt:
+		n.Expression = a.one(node, "Expression", n.Expression)
+		return n
+	case DeferStmt:
+		n.Call = applyAs(a, node, "Call", n.Call)
+		return n
+	case ImplementStatement:
+		n.Methods = applyMap(a, node, "Methods", n.Methods,
+			func(m MethodImplementStmt) Node { return m.FunctionDeclStmt },
+			func(m MethodImplementStmt, fn Node) MethodImplementStmt {
+				m.FunctionDeclStmt = as[FunctionDeclStmt](node, "Methods", fn)
+				return m
+			})
+		return n
+	case IfStmt:
+		n.Condition = a.one(node, "Condition", n.Condition)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		if alternate, ok := n.Alternate.(Node); ok {
+			n.Alternate = a.one(node, "Alternate", alternate)
+		}
+		return n
+	case ForStmt:
+		n.Init = a.one(node, "Init", n.Init)
+		n.Condition = a.one(node, "Condition", n.Condition)
+		n.Post = a.one(node, "Post", n.Post)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case ForeachStmt:
+		n.Iterable = a.one(node, "Iterable", n.Iterable)
+		n.WhereClause = a.one(node, "WhereClause", n.WhereClause)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case WhileLoopStmt:
+		n.Condition = a.one(node, "Condition", n.Condition)
+		n.Block = applyAs(a, node, "Block", n.Block)
+		return n
+	case SwitchStmt:
+		n.Discriminant = a.one(node, "Discriminant", n.Discriminant)
+		if n.Cases != nil {
+			cases := make([]SwitchCase, len(n.Cases))
+			for i, c := range n.Cases {
+				c.Test = a.element(node, "Cases.Test", i, c.Test)
+				c.Consequent = as[BlockStmt](node, "Cases.Consequent", a.element(node, "Cases.Consequent", i, c.Consequent))
+				cases[i] = c
+			}
+			n.Cases = cases
+		}
+		return n
+	case BinaryExpr:
+		n.Left = a.one(node, "Left", n.Left)
+		n.Right = a.one(node, "Right", n.Right)
+		return n
+	case UnaryExpr:
+		n.Argument = a.one(node, "Argument", n.Argument)
+		return n
+	case AssignmentExpr:
+		n.Assigne = a.one(node, "Assigne", n.Assigne)
+		n.Value = a.one(node, "Value", n.Value)
+		return n
+	case FunctionCallExpr:
+		n.Caller = a.one(node, "Caller", n.Caller)
+		n.Args = applyList(a, node, "Args", n.Args)
+		if n.NamedArgs != nil {
+			named := make([]NamedArg, len(n.NamedArgs))
+			for i, arg := range n.NamedArgs {
+				arg.Name = as[IdentifierExpr](node, "NamedArgs.Name", a.element(node, "NamedArgs.Name", i, arg.Name))
+				arg.Value = a.element(node, "NamedArgs.Value", i, arg.Value)
+				named[i] = arg
+			}
+			n.NamedArgs = named
+		}
+		return n
+	case StructLiteral:
+		n.Properties = applyMap(a, node, "Properties", n.Properties,
+			func(value Node) Node { return value },
+			func(_ Node, value Node) Node { return value })
+		return n
+	case PropertyExpr:
+		n.Object = a.one(node, "Object", n.Object)
+		n.Property = applyAs(a, node, "Property", n.Property)
+		return n
+	case ArrayLiterals:
+		n.Elements = applyList(a, node, "Elements", n.Elements)
+		return n
+	case MapLiteral:
+		if n.Entries != nil {
+			entries := make([]MapEntry, len(n.Entries))
+			for i, entry := range n.Entries {
+				entry.Key = a.element(node, "Entries.Key", i, entry.Key)
+				entry.Value = a.element(node, "Entries.Value", i, entry.Value)
+				entries[i] = entry
+			}
+			n.Entries = entries
+		}
+		return n
+	case ArrayIndexAccess:
+		n.Array = a.one(node, "Array", n.Array)
+		n.Index = a.one(node, "Index", n.Index)
+		return n
+	case MatchExpr:
+		n.Subject = a.one(node, "Subject", n.Subject)
+		if n.Arms != nil {
+			arms := make([]MatchArm, len(n.Arms))
+			for i, arm := range n.Arms {
+				arm.Pattern = a.element(node, "Arms.Pattern", i, arm.Pattern)
+				arm.Guard = a.element(node, "Arms.Guard", i, arm.Guard)
+				arm.Body = a.element(node, "Arms.Body", i, arm.Body)
+				arms[i] = arm
+			}
+			n.Arms = arms
+		}
+		return n
+	case VariantPattern:
+		n.Fields = applyList(a, node, "Fields", n.Fields)
+		return n
+	case TryExpr:
+		n.Value = a.one(node, "Value", n.Value)
+		return n
+	case ModuleStmt, ImportStmt, BreakStmt, ContinueStmt, StructDeclStatement, TraitDeclStatement, EnumDeclStatement,
+		IdentifierExpr, NumericLiteral, StringLiteral, CharacterLiteral, BooleanLiteral, NullLiteral, VoidLiteral:
+		return node
+	}
+
+	panic(fmt.Sprintf("ast.Apply: unexpected node %T", node))
+}
+
+// prototype rewrites the name and the parameters of a function
+func (a *applier) prototype(parent Node, prototype FunctionPrototype) FunctionPrototype {
+
+	prototype.Name = applyAs(a, parent, "Name", prototype.Name)
+	prototype.Parameters = a.parameters(parent, prototype.Parameters)
+
+	return prototype
+}
+
+// parameters rewrites the names and the default values of function parameters
+func (a *applier) parameters(parent Node, parameters []FunctionParameter) []FunctionParameter {
+
+	if parameters == nil {
+		return nil
+	}
+
+	rewritten := make([]FunctionParameter, len(parameters))
+
+	for i, parameter := range parameters {
+		parameter.Identifier = as[IdentifierExpr](parent, "Parameters.Identifier", a.element(parent, "Parameters.Identifier", i, parameter.Identifier))
+		parameter.DefaultVal = a.element(parent, "Parameters.DefaultVal", i, parameter.DefaultVal)
+		rewritten[i] = parameter
+	}
+
+	return rewritten
+}
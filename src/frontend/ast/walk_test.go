@@ -0,0 +1,226 @@
+package ast_test
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+)
+
+var nodeInterface = reflect.TypeOf((*ast.Node)(nil)).Elem()
+var typeInterface = reflect.TypeOf((*ast.Type)(nil)).Elem()
+
+// countNodes counts the nodes in a value by reflection, what Walk should visit
+func countNodes(v reflect.Value) int {
+
+	switch v.Kind() {
+	case reflect.Interface:
+		// types are not walked, the parameters of fn(i32) -> i32 are not nodes of the tree
+		if v.IsNil() || v.Type() == typeInterface {
+			return 0
+		}
+		return countNodes(v.Elem())
+	case reflect.Struct:
+		count := 0
+		// a method of an impl is a node only through the function it embeds
+		promoted := false
+		for i := 0; i < v.NumField(); i++ {
+			count += countNodes(v.Field(i))
+			promoted = promoted || v.Type().Field(i).Anonymous && v.Field(i).Type().Implements(nodeInterface)
+		}
+		if v.Type().Implements(nodeInterface) && !promoted {
+			count++
+		}
+		return count
+	case reflect.Slice:
+		count := 0
+		for i := 0; i < v.Len(); i++ {
+			count += countNodes(v.Index(i))
+		}
+		return count
+	case reflect.Map:
+		count := 0
+		for _, key := range v.MapKeys() {
+			count += countNodes(v.MapIndex(key))
+		}
+		return count
+	}
+
+	return 0
+}
+
+func TestWalkVisitsEveryNode(t *testing.T) {
+
+	for _, sample := range samples(t) {
+
+		program, ok := parse(t, sample)
+
+		if !ok {
+			continue
+		}
+
+		var visited []ast.Node
+		depth := 0
+
+		ast.Inspect(program, func(node ast.Node) bool {
+			if node == nil {
+				depth--
+				return false
+			}
+			depth++
+			visited = append(visited, node)
+			return true
+		})
+
+		if depth != 0 {
+			t.Errorf("%s: Inspect called f(nil) %d times too few", sample, depth)
+		}
+
+		if want := countNodes(reflect.ValueOf(program)); len(visited) != want {
+			t.Errorf("%s: Inspect visited %d nodes, the tree has %d", sample, len(visited), want)
+		}
+
+		// Apply walks the same nodes in the same order and changes nothing by itself
+		var applied []ast.Node
+
+		rewritten := ast.Apply(program, func(c *ast.Cursor) bool {
+			applied = append(applied, c.Node())
+			return true
+		}, nil)
+
+		if !reflect.DeepEqual(visited, applied) {
+			t.Errorf("%s: Apply and Inspect visit different nodes", sample)
+		}
+
+		if !reflect.DeepEqual(rewritten, program) {
+			t.Errorf("%s: Apply without changes changed the tree", sample)
+		}
+	}
+}
+
+func parseSource(t *testing.T, source string) ast.ProgramStmt {
+
+	t.Helper()
+
+	p, err := parser.NewParserFromSource(source, "test.wal", false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.PanicOnError = true
+
+	return p.Parse()
+}
+
+const applySource = `let a := 1;
+let b := 2;
+if a > 1 {
+    print(1);
+} els {
+    print(b);
+}
+let p := Point{x: 1, y: 2};
+`
+
+func TestApply(t *testing.T) {
+
+	program := parseSource(t, applySource)
+
+	var names []string
+
+	result := ast.Apply(program, func(c *ast.Cursor) bool {
+		switch n := c.Node().(type) {
+		case ast.VariableDclStml:
+			if n.Identifier.Identifier == "b" {
+				c.Delete()
+				return false
+			}
+		case ast.NumericLiteral:
+			if n.Value == "1" {
+				names = append(names, c.Name()+"/"+c.Key())
+				n.Value = "10"
+				c.Replace(n)
+			}
+		case ast.FunctionCallExpr:
+			c.InsertBefore(ast.ReturnStmt{BaseStmt: ast.BaseStmt{Kind: ast.RETURN_STATEMENT}})
+		}
+		return true
+	}, nil).(ast.ProgramStmt)
+
+	sort.Strings(names)
+
+	if got := strings.Join(names, " "); got != "Args/ Properties/x Right/ Value/" {
+		t.Errorf("replaced in %s", got)
+	}
+
+	if len(result.Contents) != 3 {
+		t.Fatalf("got %d statements, want 3 after deleting let b", len(result.Contents))
+	}
+
+	if value := result.Contents[0].(ast.VariableDclStml).Value.(ast.NumericLiteral).Value; value != "10" {
+		t.Errorf("let a := %s, want 10", value)
+	}
+
+	ifStmt := result.Contents[1].(ast.IfStmt)
+
+	if items := ifStmt.Block.Items; len(items) != 2 || items[0].INodeType() != ast.RETURN_STATEMENT {
+		t.Errorf("no return inserted before print: %+v", items)
+	}
+
+	if _, ok := ifStmt.Alternate.(ast.BlockStmt); !ok {
+		t.Errorf("else block lost: %T", ifStmt.Alternate)
+	}
+
+	if x := result.Contents[2].(ast.VariableDclStml).Value.(ast.StructLiteral).Properties["x"].(ast.NumericLiteral).Value; x != "10" {
+		t.Errorf("Point.x is %s, want 10", x)
+	}
+
+	// the tree given is left as it was
+	if len(program.Contents) != 4 || program.Contents[0].(ast.VariableDclStml).Value.(ast.NumericLiteral).Value != "1" {
+		t.Errorf("Apply changed the tree given")
+	}
+}
+
+func TestApplyStops(t *testing.T) {
+
+	program := parseSource(t, "let a := 1;\nlet b := 1;\n")
+
+	result := ast.Apply(program, nil, func(c *ast.Cursor) bool {
+		if n, ok := c.Node().(ast.NumericLiteral); ok {
+			n.Value = "2"
+			c.Replace(n)
+			return false
+		}
+		return true
+	}).(ast.ProgramStmt)
+
+	value := func(i int) string {
+		return result.Contents[i].(ast.VariableDclStml).Value.(ast.NumericLiteral).Value
+	}
+
+	if value(0) != "2" || value(1) != "1" {
+		t.Errorf("got %s and %s, want only the first literal replaced", value(0), value(1))
+	}
+}
+
+func TestApplyReplaceWithWrongType(t *testing.T) {
+
+	program := parseSource(t, "while true {\n    print(1);\n}\n")
+
+	defer func() {
+		if recovered := recover(); recovered == nil || !strings.Contains(recovered.(string), "Block of ast.WhileLoopStmt must be a ast.BlockStmt") {
+			t.Errorf("got %v, want a panic about the block", recovered)
+		}
+	}()
+
+	ast.Apply(program, func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(ast.BlockStmt); ok {
+			c.Replace(ast.BreakStmt{})
+		}
+		return true
+	}, nil)
+}
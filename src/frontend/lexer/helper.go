@@ -6,16 +6,69 @@ import (
 	"walrus/utils"
 )
 
-func Highlight(line string) string {
-	words := strings.Split(line, " ")
-
-	for i, word := range words {
-		if IsKeyword(TOKEN_KIND(word)) || IsBuiltInType(TOKEN_KIND(word)) {
-			words[i] = utils.Colorize(utils.PURPLE, word)
-		} else if IsNumber(TOKEN_KIND(word)) {
-			words[i] = utils.Colorize(utils.ORANGE, word)
+// colours of the semantic kinds in the terminal, parameters stay plain
+var highlightColors = map[SEMANTIC_KIND]string{
+	SEMANTIC_KEYWORD:  utils.PURPLE,
+	SEMANTIC_TYPE:     utils.CYAN,
+	SEMANTIC_STRUCT:   utils.CYAN,
+	SEMANTIC_TRAIT:    utils.CYAN,
+	SEMANTIC_ENUM:     utils.CYAN,
+	SEMANTIC_VARIANT:  utils.ORANGE,
+	SEMANTIC_FUNCTION: utils.BLUE,
+	SEMANTIC_CONSTANT: utils.ORANGE,
+	SEMANTIC_LITERAL:  utils.ORANGE,
+	SEMANTIC_COMMENT:  utils.GREY,
+}
+
+// Highlight colours a snippet of source, like the code of a hint, by the
+// semantic kinds of its tokens
+func Highlight(source string) string {
+	return strings.Join(HighlightLines(source, nil), "\n")
+}
+
+// HighlightLines colours the lines of a source by the semantic kinds of its
+// tokens. The source is lexed whole, so the lines inside a block comment are
+// coloured as the comment. From a character no token starts with the source
+// is left plain. The resolver may be nil.
+func HighlightLines(source string, resolve Resolver) []string {
+
+	lex := createLexer(&source)
+	lex.run()
+
+	// the comments waiting for a token are classified with it
+	lex.push(NewToken(EOF_TOKEN, "", lex.Pos, lex.Pos))
+
+	var out strings.Builder
+
+	last := 0
+
+	for _, token := range Classify(lex.Tokens, resolve) {
+
+		start, end := token.StartPos.Index, token.EndPos.Index
+
+		if start < last || end > len(source) {
+			continue
 		}
+
+		out.WriteString(source[last:start])
+
+		color, ok := highlightColors[token.Kind]
+
+		// the colour is set again on each line, the lines are printed apart
+		for i, piece := range strings.Split(source[start:end], "\n") {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			if ok && piece != "" {
+				piece = utils.Colorize(color, piece)
+			}
+			out.WriteString(piece)
+		}
+
+		last = end
 	}
 
-	return strings.Join(words, " ")
-}
\ No newline at end of file
+	out.WriteString(source[last:])
+
+	return strings.Split(out.String(), "\n")
+}
@@ -2,9 +2,9 @@ package lexer
 
 import (
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 	"walrus/utils"
 )
 
@@ -30,7 +30,8 @@ func (p *Position) advance(toSkip string) *Position {
 		} else {
 			p.Column++
 		}
-		p.Index++
+		// the index is a byte offset, the source is sliced with it
+		p.Index += utf8.RuneLen(char)
 	}
 
 	return p
@@ -43,14 +44,63 @@ type Lexer struct {
 	source   *string
 	Pos      Position
 	FilePath string
+	// comments waiting for the next token
+	comments []Comment
 }
 
-func Tokenize(source, file string, debug bool) ([]Token, *[]string) {
+// LexError is returned when the source contains a character no token starts with
+type LexError struct {
+	FilePath string
+	Pos      Position
+	Char     byte
+	// the rendered report with the offending line
+	Report string
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: unexpected character '%c'", e.FilePath, e.Pos.Line, e.Pos.Column, e.Char)
+}
+
+func Tokenize(source, file string, debug bool) ([]Token, *[]string, error) {
 
 	lex := createLexer(&source)
 	lex.FilePath = file
 	lex.Lines = strings.Split(source, "\n")
 
+	if !lex.run() {
+
+		//line is from lex.Pos.Index to
+		padding := fmt.Sprintf("%d | ", lex.Pos.Line)
+
+		errStr := fmt.Sprintf("\n%s:%d:%d\n", lex.FilePath, lex.Pos.Line, lex.Pos.Column)
+		errStr += utils.Colorize(utils.GREY, padding) + HighlightLines(source, nil)[lex.Pos.Line-1] + "\n"
+		errStr += utils.Colorize(utils.BOLD_RED, (strings.Repeat(" ", (lex.Pos.Column-1)+len(padding)) + "^\n"))
+		errStr += fmt.Sprintf("At line %d: Unexpected character: '%c'", lex.Pos.Line, lex.at())
+
+		return nil, nil, &LexError{
+			FilePath: lex.FilePath,
+			Pos:      lex.Pos,
+			Char:     lex.at(),
+			Report:   errStr,
+		}
+	}
+
+	lex.push(NewToken(EOF_TOKEN, "End of file", lex.Pos, lex.Pos))
+
+	//litter.Dump(lex.Tokens)
+	if debug {
+		for _, token := range lex.Tokens {
+			token.Debug()
+		}
+	}
+
+	return lex.Tokens, &lex.Lines, nil
+}
+
+// run lexes until the end of the source or the first character no token starts
+// with, and reports whether it reached the end
+func (lex *Lexer) run() bool {
+
 	for !lex.atEOF() {
 
 		matched := false
@@ -67,30 +117,11 @@ func Tokenize(source, file string, debug bool) ([]Token, *[]string) {
 		}
 
 		if !matched {
-
-			//line is from lex.Pos.Index to
-			padding := fmt.Sprintf("%d | ", lex.Pos.Line)
-
-			errStr := fmt.Sprintf("\n%s:%d:%d\n", lex.FilePath, lex.Pos.Line, lex.Pos.Column)
-			errStr += utils.Colorize(utils.GREY, padding) + Highlight(lex.Lines[lex.Pos.Line]) + "\n"
-			errStr += utils.Colorize(utils.BOLD_RED, (strings.Repeat(" ", (lex.Pos.Column-1)+len(padding)) + "^\n"))
-			errStr += fmt.Sprintf("At line %d: Unexpected character: '%c'", lex.Pos.Line, lex.at())
-			fmt.Println(errStr)
-
-			os.Exit(-1)
-		}
-	}
-
-	lex.push(NewToken(EOF_TOKEN, "End of file", lex.Pos, lex.Pos))
-
-	//litter.Dump(lex.Tokens)
-	if debug {
-		for _, token := range lex.Tokens {
-			token.Debug()
+			return false
 		}
 	}
 
-	return lex.Tokens, &lex.Lines
+	return true
 }
 
 func (lex *Lexer) advanceN(match string) {
@@ -99,6 +130,8 @@ func (lex *Lexer) advanceN(match string) {
 }
 
 func (lex *Lexer) push(token Token) {
+	token.Comments = lex.comments
+	lex.comments = nil
 	lex.Tokens = append(lex.Tokens, token)
 }
 
@@ -138,8 +171,8 @@ func createLexer(source *string) *Lexer {
 		patterns: []regexPattern{
 			//{regexp.MustCompile(`\n`), skipHandler}, // newlines
 			{regexp.MustCompile(`\s+`), skipHandler},                          // whitespace
-			{regexp.MustCompile(`\/\/.*`), skipHandler},                       // single line comments
-			{regexp.MustCompile(`\/\*[\s\S]*?\*\/`), skipHandler},             // multi line comments
+			{regexp.MustCompile(`\/\/.*`), commentHandler},                    // single line comments
+			{regexp.MustCompile(`\/\*[\s\S]*?\*\/`), commentHandler},          // multi line comments
 			{regexp.MustCompile(`"[^"]*"`), stringHandler},                    // string literals
 			{regexp.MustCompile(`'[^']'`), characterHandler},                  // character literals
 			{regexp.MustCompile(`[0-9]+(?:\.[0-9]+)?`), numberHandler},        // decimal numbers
@@ -150,6 +183,7 @@ func createLexer(source *string) *Lexer {
 			{regexp.MustCompile(`\}`), defaultHandler(CLOSE_CURLY_TOKEN, "}")},
 			{regexp.MustCompile(`\(`), defaultHandler(OPEN_PAREN_TOKEN, "(")},
 			{regexp.MustCompile(`\)`), defaultHandler(CLOSE_PAREN_TOKEN, ")")},
+			{regexp.MustCompile(`=>`), defaultHandler(FAT_ARROW_TOKEN, "=>")},
 			{regexp.MustCompile(`==`), defaultHandler(EQUALS_TOKEN, "==")},
 			{regexp.MustCompile(`!=`), defaultHandler(NOT_EQUALS_TOKEN, "!=")},
 			{regexp.MustCompile(`=`), defaultHandler(ASSIGNMENT_TOKEN, "=")},
@@ -161,6 +195,7 @@ func createLexer(source *string) *Lexer {
 			{regexp.MustCompile(`>`), defaultHandler(GREATER_TOKEN, ">")},
 			{regexp.MustCompile(`\|\|`), defaultHandler(OR_TOKEN, "||")},
 			{regexp.MustCompile(`&&`), defaultHandler(AND_TOKEN, "&&")},
+			{regexp.MustCompile(`\.\.\.`), defaultHandler(ELLIPSIS_TOKEN, "...")},
 			{regexp.MustCompile(`\.\.`), defaultHandler(DOT_DOT_TOKEN, "..")},
 			{regexp.MustCompile(`\.`), defaultHandler(DOT_TOKEN, ".")},
 			{regexp.MustCompile(`;`), defaultHandler(SEMI_COLON_TOKEN, ";")},
@@ -182,7 +217,7 @@ func createLexer(source *string) *Lexer {
 			{regexp.MustCompile(`/`), defaultHandler(DIVIDE_TOKEN, "/")},
 			{regexp.MustCompile(`\*`), defaultHandler(TIMES_TOKEN, "*")},
 			{regexp.MustCompile(`%`), defaultHandler(MODULO_TOKEN, "%")},
-			{regexp.MustCompile(`^`), defaultHandler(POWER_TOKEN, "^")},
+			{regexp.MustCompile(`\^`), defaultHandler(POWER_TOKEN, "^")},
 		},
 	}
 	return lex
@@ -255,3 +290,19 @@ func skipHandler(lex *Lexer, regex *regexp.Regexp) {
 
 	lex.advanceN(match)
 }
+
+// commentHandler keeps a comment for the next token, the parser never sees it
+func commentHandler(lex *Lexer, regex *regexp.Regexp) {
+
+	match := regex.FindString(lex.remainder())
+
+	start := lex.Pos
+	lex.advanceN(match)
+	end := lex.Pos
+
+	lex.comments = append(lex.comments, Comment{
+		Text:     strings.TrimRight(match, " \t\r"),
+		StartPos: start,
+		EndPos:   end,
+	})
+}
@@ -0,0 +1,365 @@
+package lexer
+
+// SEMANTIC_KIND is what a token means to a reader, editors and the error
+// renderer colour tokens by it
+type SEMANTIC_KIND string
+
+const (
+	SEMANTIC_KEYWORD   SEMANTIC_KIND = "keyword"
+	SEMANTIC_TYPE      SEMANTIC_KIND = "type"
+	SEMANTIC_STRUCT    SEMANTIC_KIND = "struct"
+	SEMANTIC_TRAIT     SEMANTIC_KIND = "trait"
+	SEMANTIC_ENUM      SEMANTIC_KIND = "enum"
+	SEMANTIC_VARIANT   SEMANTIC_KIND = "enumMember"
+	SEMANTIC_FUNCTION  SEMANTIC_KIND = "function"
+	SEMANTIC_PARAMETER SEMANTIC_KIND = "parameter"
+	SEMANTIC_CONSTANT  SEMANTIC_KIND = "constant"
+	SEMANTIC_LITERAL   SEMANTIC_KIND = "literal"
+	SEMANTIC_COMMENT   SEMANTIC_KIND = "comment"
+)
+
+type SemanticToken struct {
+	Kind     SEMANTIC_KIND
+	StartPos Position
+	EndPos   Position
+}
+
+// Resolver tells what the identifier at an index of the tokens refers to.
+// The lexer only sees the syntax around a name, a resolver which knows the
+// declarations classifies uses like a parameter in a function body.
+// Returning false leaves the identifier to the syntax.
+type Resolver func(index int, token Token) (SEMANTIC_KIND, bool)
+
+// what an open bracket belongs to
+type bracket int
+
+const (
+	groupBracket bracket = iota
+	paramsBracket
+	structBracket
+	traitBracket
+	enumBracket
+)
+
+// Classify returns the semantic tokens of a token list in source order,
+// comments included. Operators, punctuation and plain variables are left out.
+// The resolver may be nil.
+func Classify(tokens []Token, resolve Resolver) []SemanticToken {
+
+	var classified []SemanticToken
+
+	// brackets open before the current token
+	var open []bracket
+
+	for i, token := range tokens {
+
+		for _, comment := range token.Comments {
+			classified = append(classified, SemanticToken{SEMANTIC_COMMENT, comment.StartPos, comment.EndPos})
+		}
+
+		kind := classify(tokens, i, open, resolve)
+
+		if kind != "" {
+			classified = append(classified, SemanticToken{kind, token.StartPos, token.EndPos})
+		}
+
+		switch token.Kind {
+		case OPEN_PAREN_TOKEN:
+			// fn name( and fn( open a parameter list, so does the payload of a variant
+			name := declaredName(tokens, i-1)
+			if kindAt(tokens, i-1) == FUNCTION_TOKEN || (kindAt(tokens, name) == IDENTIFIER_TOKEN && kindAt(tokens, name-1) == FUNCTION_TOKEN) || top(open) == enumBracket {
+				open = append(open, paramsBracket)
+			} else {
+				open = append(open, groupBracket)
+			}
+		case OPEN_CURLY_TOKEN:
+			switch kindAt(tokens, declaredName(tokens, i-1)-1) {
+			case STRUCT_TOKEN:
+				open = append(open, structBracket)
+			case TRAIT_TOKEN:
+				open = append(open, traitBracket)
+			case ENUM_TOKEN:
+				open = append(open, enumBracket)
+			default:
+				open = append(open, groupBracket)
+			}
+		case OPEN_BRACKET_TOKEN:
+			open = append(open, groupBracket)
+		case CLOSE_PAREN_TOKEN, CLOSE_CURLY_TOKEN, CLOSE_BRACKET_TOKEN:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	return classified
+}
+
+// top returns the innermost open bracket, a group outside any
+func top(open []bracket) bracket {
+	if len(open) == 0 {
+		return groupBracket
+	}
+	return open[len(open)-1]
+}
+
+func kindAt(tokens []Token, i int) TOKEN_KIND {
+	if i < 0 || i >= len(tokens) {
+		return EOF_TOKEN
+	}
+	return tokens[i].Kind
+}
+
+func classify(tokens []Token, i int, open []bracket, resolve Resolver) SEMANTIC_KIND {
+
+	token := tokens[i]
+
+	switch token.Kind {
+	case INTEGER_TOKEN, FLOATING_TOKEN, STRING_TOKEN, CHARACTER_TOKEN, TRUE_TOKEN, FALSE_TOKEN, NULL_TOKEN:
+		return SEMANTIC_LITERAL
+	case IDENTIFIER_TOKEN:
+		return classifyIdentifier(tokens, i, open, resolve)
+	case EOF_TOKEN:
+		return ""
+	}
+
+	// the value of a keyword token is the word it was written with
+	if kind, ok := reservedLookup[token.Value]; ok && kind == token.Kind {
+		return SEMANTIC_KEYWORD
+	}
+
+	return ""
+}
+
+func classifyIdentifier(tokens []Token, i int, open []bracket, resolve Resolver) SEMANTIC_KIND {
+
+	if resolve != nil {
+		if kind, ok := resolve(i, tokens[i]); ok {
+			return kind
+		}
+	}
+
+	if IsBuiltInType(TOKEN_KIND(tokens[i].Value)) {
+		return SEMANTIC_TYPE
+	}
+
+	// test fn name() starts a test, test is only a keyword there
+	if tokens[i].Value == "test" && kindAt(tokens, i+1) == FUNCTION_TOKEN {
+		return SEMANTIC_KEYWORD
+	}
+
+	// match subject { starts a match, match stays a name elsewhere like in path.match(
+	if IsMatchKeyword(tokens, i) {
+		return SEMANTIC_KEYWORD
+	}
+
+	// map[str]i32 and map{ are maps, map stays a name elsewhere like in fn map<T>(
+	if IsMapKeyword(tokens, i) {
+		return SEMANTIC_KEYWORD
+	}
+
+	// <T: Display + Debug> after the name of a generic declaration or type
+	if opening := typeArgsOpening(tokens, i); opening >= 0 && isGeneric(tokens, opening-1, open) {
+		if previous := kindAt(tokens, i-1); previous == COLON_TOKEN || previous == PLUS_TOKEN {
+			return SEMANTIC_TRAIT
+		}
+		return SEMANTIC_TYPE
+	}
+
+	if isTypePosition(tokens, i, open) {
+		return SEMANTIC_TYPE
+	}
+
+	switch kindAt(tokens, i-1) {
+	case FUNCTION_TOKEN:
+		return SEMANTIC_FUNCTION
+	case STRUCT_TOKEN, EMBED_TOKEN:
+		return SEMANTIC_STRUCT
+	case TRAIT_TOKEN:
+		return SEMANTIC_TRAIT
+	case ENUM_TOKEN:
+		return SEMANTIC_ENUM
+	case CONST_TOKEN:
+		return SEMANTIC_CONSTANT
+	}
+
+	if kind, ok := implHeader(tokens, i); ok {
+		return kind
+	}
+
+	// the variants of an enum are the names directly in its braces
+	if top(open) == enumBracket {
+		return SEMANTIC_VARIANT
+	}
+
+	switch kindAt(tokens, i+1) {
+	case OPEN_PAREN_TOKEN:
+		return SEMANTIC_FUNCTION
+	case COLON_TOKEN:
+		if len(open) > 0 && open[len(open)-1] == paramsBracket {
+			return SEMANTIC_PARAMETER
+		}
+	case OPEN_CURLY_TOKEN:
+		// Name{field: value} or Name{}
+		if kindAt(tokens, i+2) == CLOSE_CURLY_TOKEN || (kindAt(tokens, i+2) == IDENTIFIER_TOKEN && kindAt(tokens, i+3) == COLON_TOKEN) {
+			return SEMANTIC_STRUCT
+		}
+	}
+
+	return ""
+}
+
+// IsMatchKeyword tells if the identifier at i is the match of a match
+// expression, the word is only a keyword when a subject follows it
+func IsMatchKeyword(tokens []Token, i int) bool {
+	if kindAt(tokens, i) != IDENTIFIER_TOKEN || tokens[i].Value != "match" {
+		return false
+	}
+	switch kindAt(tokens, i+1) {
+	case IDENTIFIER_TOKEN, INTEGER_TOKEN, FLOATING_TOKEN, STRING_TOKEN, CHARACTER_TOKEN, TRUE_TOKEN, FALSE_TOKEN, NULL_TOKEN:
+		return true
+	}
+	return false
+}
+
+// IsMapKeyword tells if the identifier at i starts a map type or literal,
+// map[K]V or map{...}. Elsewhere map stays a name, like a function called map.
+func IsMapKeyword(tokens []Token, i int) bool {
+	if kindAt(tokens, i) != IDENTIFIER_TOKEN || tokens[i].Value != "map" {
+		return false
+	}
+	switch kindAt(tokens, i+1) {
+	case OPEN_BRACKET_TOKEN, OPEN_CURLY_TOKEN:
+		return true
+	}
+	return false
+}
+
+// isTypePosition tells if an identifier names a type: after the colon of a
+// parameter, a property or a variable declaration, or after an arrow.
+// Array types are written []T and variadic parameters ...T, the brackets and
+// the ellipsis are skipped.
+func isTypePosition(tokens []Token, i int, open []bracket) bool {
+
+	j := i - 1
+
+	for kindAt(tokens, j) == CLOSE_BRACKET_TOKEN && kindAt(tokens, j-1) == OPEN_BRACKET_TOKEN {
+		j -= 2
+	}
+
+	if kindAt(tokens, j) == ELLIPSIS_TOKEN {
+		j--
+	}
+
+	switch kindAt(tokens, j) {
+	case ARROW_TOKEN:
+		return true
+	case COLON_TOKEN:
+		if top(open) == paramsBracket || top(open) == structBracket {
+			return true
+		}
+		// let name: T and const name: T
+		return kindAt(tokens, j-1) == IDENTIFIER_TOKEN && (kindAt(tokens, j-2) == LET_TOKEN || kindAt(tokens, j-2) == CONST_TOKEN)
+	case OPEN_PAREN_TOKEN, COMMA_TOKEN:
+		// the parameters of a function type are bare types, fn(i32, str)
+		next := kindAt(tokens, i+1)
+		return top(open) == paramsBracket && (next == COMMA_TOKEN || next == CLOSE_PAREN_TOKEN)
+	}
+
+	return false
+}
+
+// declaredName skips the type parameters ending at i, the index of the
+// name they follow is returned. Without them i is the name.
+func declaredName(tokens []Token, i int) int {
+
+	if kindAt(tokens, i) != GREATER_TOKEN {
+		return i
+	}
+
+	if opening := typeArgsOpening(tokens, i); opening >= 0 {
+		return opening - 1
+	}
+
+	return i
+}
+
+// typeArgsOpening returns the index of the < of the type parameters or type
+// arguments the token at i is in, -1 when it is in none. Only the tokens a
+// list of types is written with may come between.
+func typeArgsOpening(tokens []Token, i int) int {
+
+	depth := 0
+
+	for j := i - 1; j >= 0; j-- {
+		switch tokens[j].Kind {
+		case GREATER_TOKEN:
+			depth++
+		case LESS_TOKEN:
+			if depth == 0 {
+				return j
+			}
+			depth--
+		case IDENTIFIER_TOKEN, COMMA_TOKEN, COLON_TOKEN, PLUS_TOKEN, ARROW_TOKEN, FUNCTION_TOKEN,
+			OPEN_BRACKET_TOKEN, CLOSE_BRACKET_TOKEN, OPEN_PAREN_TOKEN, CLOSE_PAREN_TOKEN:
+		default:
+			return -1
+		}
+	}
+
+	return -1
+}
+
+// isGeneric tells if the identifier at i is the name of a generic
+// declaration or a generic type, the < after it opens type parameters
+func isGeneric(tokens []Token, i int, open []bracket) bool {
+
+	if kindAt(tokens, i) != IDENTIFIER_TOKEN {
+		return false
+	}
+
+	switch kindAt(tokens, i-1) {
+	case FUNCTION_TOKEN, STRUCT_TOKEN, TRAIT_TOKEN, ENUM_TOKEN, FOR_TOKEN:
+		return true
+	}
+
+	// a type argument of an other generic type, Box<Pair<K, V>>
+	if opening := typeArgsOpening(tokens, i); opening >= 0 && isGeneric(tokens, opening-1, open) {
+		return true
+	}
+
+	return isTypePosition(tokens, i, open)
+}
+
+// implHeader classifies the names of impl A, B for T and impl T, the traits
+// come before for and the implementing type after it
+func implHeader(tokens []Token, i int) (SEMANTIC_KIND, bool) {
+
+	start := -1
+
+	for j := i - 1; j >= 0; j-- {
+		kind := tokens[j].Kind
+		if kind == IMPLEMENT_TOKEN {
+			start = j
+			break
+		}
+		if kind != IDENTIFIER_TOKEN && kind != COMMA_TOKEN && kind != FOR_TOKEN {
+			return "", false
+		}
+	}
+
+	if start < 0 {
+		return "", false
+	}
+
+	for j := start + 1; j < len(tokens) && tokens[j].Kind != OPEN_CURLY_TOKEN; j++ {
+		if tokens[j].Kind == FOR_TOKEN {
+			if j > i {
+				return SEMANTIC_TRAIT, true
+			}
+			return SEMANTIC_STRUCT, true
+		}
+	}
+
+	return SEMANTIC_STRUCT, true
+}
@@ -0,0 +1,105 @@
+package lexer_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"walrus/frontend/lexer"
+	"walrus/utils"
+)
+
+// classified returns the text and the kind of each semantic token of a source
+func classified(t *testing.T, source string, resolve lexer.Resolver) []string {
+
+	t.Helper()
+
+	tokens, _, err := lexer.Tokenize(source, "test.wal", false)
+
+	if err != nil {
+		t.Fatalf("%q: %v", source, err)
+	}
+
+	var got []string
+
+	for _, token := range lexer.Classify(tokens, resolve) {
+		got = append(got, fmt.Sprintf("%s %s", source[token.StartPos.Index:token.EndPos.Index], token.Kind))
+	}
+
+	return got
+}
+
+func TestClassify(t *testing.T) {
+
+	for _, test := range []struct {
+		source string
+		want   []string
+	}{
+		// a keyword before a parenthesis is not a call
+		{"if(a) {}", []string{"if keyword"}},
+		{"while(true) {}", []string{"while keyword", "true literal"}},
+		// an unfinished declaration still names a function
+		{"fn add(", []string{"fn keyword", "add function"}},
+		{"fn add(a: i32, b: ...str) {}", []string{"fn keyword", "add function", "a parameter", "i32 type", "b parameter", "str type"}},
+		// a name starting like a builtin type is a name
+		{"let stri8ng := str;", []string{"let keyword", "str type"}},
+		{"print(stri8ng, i32x);", []string{"print function"}},
+		{"let s: str = \"a\";", []string{"let keyword", "str type", "\"a\" literal"}},
+		{"let p: Point = Point{x: 1};", []string{"let keyword", "Point type", "Point struct", "1 literal"}},
+		{"struct Point {\n    x: i32;\n}", []string{"struct keyword", "Point struct", "i32 type"}},
+		{"enum Shape {\n    Circle(r: f64),\n    Empty,\n}", []string{"enum keyword", "Shape enum", "Circle enumMember", "r parameter", "f64 type", "Empty enumMember"}},
+		{"const max := 1; // the most", []string{"const keyword", "max constant", "1 literal", "// the most comment"}},
+		{"let m := map{1: 2};", []string{"let keyword", "map keyword", "1 literal", "2 literal"}},
+		{"fn map<T>(xs: []T) {}", []string{"fn keyword", "map function", "T type", "xs parameter", "T type"}},
+	} {
+		if got := classified(t, test.source, nil); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q:\ngot  %s\nwant %s", test.source, strings.Join(got, ", "), strings.Join(test.want, ", "))
+		}
+	}
+}
+
+func TestClassifyResolved(t *testing.T) {
+
+	// the resolver knows x is a parameter where the syntax does not tell
+	resolve := func(i int, token lexer.Token) (lexer.SEMANTIC_KIND, bool) {
+		if token.Value == "x" {
+			return lexer.SEMANTIC_PARAMETER, true
+		}
+		return "", false
+	}
+
+	want := []string{"ret keyword", "x parameter", "1 literal"}
+
+	if got := classified(t, "ret x + y + 1;", resolve); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHighlightLines(t *testing.T) {
+
+	source := "let a := 1; /* one\nfn two() {\n*/ let b := \"x\";"
+
+	resolve := func(i int, token lexer.Token) (lexer.SEMANTIC_KIND, bool) {
+		if token.Value == "a" {
+			return lexer.SEMANTIC_CONSTANT, true
+		}
+		return "", false
+	}
+
+	// the line inside the comment is a comment, not a declaration
+	want := []string{
+		utils.Colorize(utils.PURPLE, "let") + " " + utils.Colorize(utils.ORANGE, "a") + " := " + utils.Colorize(utils.ORANGE, "1") + "; " + utils.Colorize(utils.GREY, "/* one"),
+		utils.Colorize(utils.GREY, "fn two() {"),
+		utils.Colorize(utils.GREY, "*/") + " " + utils.Colorize(utils.PURPLE, "let") + " b := " + utils.Colorize(utils.ORANGE, `"x"`) + ";",
+	}
+
+	if got := lexer.HighlightLines(source, resolve); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// a character no token starts with leaves the rest plain
+	if got := lexer.HighlightLines("let a := 1 @ 2;\nfn f", nil); got[1] != "fn f" || !strings.HasSuffix(got[0], " @ 2;") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"regexp"
+	"sort"
 )
 
 // TOKEN_KIND represents the type of token
@@ -34,6 +35,7 @@ const (
 	ASSIGNMENT_TOKEN TOKEN_KIND = "="
 	WALRUS_TOKEN     TOKEN_KIND = ":="
 	ARROW_TOKEN      TOKEN_KIND = "->"
+	FAT_ARROW_TOKEN  TOKEN_KIND = "=>"
 
 	// Comparison operators
 	EQUALS_TOKEN         TOKEN_KIND = "=="
@@ -50,6 +52,7 @@ const (
 	// Literals
 	DOT_TOKEN        TOKEN_KIND = "."
 	DOT_DOT_TOKEN    TOKEN_KIND = ".."
+	ELLIPSIS_TOKEN   TOKEN_KIND = "..."
 	SEMI_COLON_TOKEN TOKEN_KIND = ";"
 	COLON_TOKEN      TOKEN_KIND = ":"
 	QUESTION_TOKEN   TOKEN_KIND = "?"
@@ -89,6 +92,7 @@ const (
 
 	BREAK_TOKEN    TOKEN_KIND = "break"
 	CONTINUE_TOKEN TOKEN_KIND = "continue"
+	DEFER_TOKEN    TOKEN_KIND = "defer"
 
 	IF_TOKEN      TOKEN_KIND = "if"
 	ELSEIF_TOKEN  TOKEN_KIND = "elf"
@@ -108,6 +112,7 @@ const (
 
 	// Other
 	STRUCT_TOKEN    TOKEN_KIND = "struct"
+	ENUM_TOKEN      TOKEN_KIND = "enum"
 	EMBED_TOKEN     TOKEN_KIND = "embed"
 	TRAIT_TOKEN     TOKEN_KIND = "trait"
 	IMPLEMENT_TOKEN TOKEN_KIND = "implement"
@@ -131,6 +136,7 @@ var reservedLookup map[string]TOKEN_KIND = map[string]TOKEN_KIND{
 	"default":  DEFAULT_TOKEN,
 	"break":    BREAK_TOKEN,
 	"continue": CONTINUE_TOKEN,
+	"defer":    DEFER_TOKEN,
 	"if":       IF_TOKEN,
 	"elf":      ELSEIF_TOKEN,
 	"els":      ELSE_TOKEN,
@@ -145,6 +151,7 @@ var reservedLookup map[string]TOKEN_KIND = map[string]TOKEN_KIND{
 	"true":     TRUE_TOKEN,
 	"false":    FALSE_TOKEN,
 	"struct":   STRUCT_TOKEN,
+	"enum":     ENUM_TOKEN,
 	"embed":    EMBED_TOKEN,
 	"trait":    TRAIT_TOKEN,
 	"impl":     IMPLEMENT_TOKEN,
@@ -156,19 +163,38 @@ var reservedLookup map[string]TOKEN_KIND = map[string]TOKEN_KIND{
 	"ret":      RETURN_TOKEN,
 }
 
+// Keywords returns the reserved words in alphabetical order
+func Keywords() []string {
+	keywords := make([]string, 0, len(reservedLookup))
+	for keyword := range reservedLookup {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
 func IsKeyword(tokenKind TOKEN_KIND) bool {
 	_, ok := reservedLookup[string(tokenKind)]
 	return ok
 }
 
+// IsNumber tells if a word is a whole integer or decimal number
 func IsNumber(tokenKind TOKEN_KIND) bool {
-	regexp := regexp.MustCompile(`[0-9]+(?:\.[0-9]*)?`)
-	return regexp.MatchString(string(tokenKind))
+	return numberRegex.MatchString(string(tokenKind))
+}
+
+var numberRegex = regexp.MustCompile(`^[0-9]+(?:\.[0-9]*)?$`)
+
+// the type names the parser knows without a declaration
+var builtInTypes = map[string]bool{
+	"i8": true, "i16": true, "i32": true, "i64": true, "i128": true,
+	"u8": true, "u16": true, "u32": true, "u64": true, "u128": true,
+	"f32": true, "f64": true, "bool": true, "chr": true, "str": true, "any": true,
 }
 
+// IsBuiltInType tells if a word is the name of a builtin type, like i32 but not stri8ng
 func IsBuiltInType(tokenKind TOKEN_KIND) bool {
-	regexp := regexp.MustCompile(`i8|i16|i32|i64|i128|f32|f64|bool|str|chr`)
-	return regexp.MatchString(string(tokenKind))
+	return builtInTypes[string(tokenKind)]
 }
 
 // Define token types
@@ -177,9 +203,18 @@ type Token struct {
 	Value    string
 	StartPos Position
 	EndPos   Position
+	// comments between the previous token and this one, the EOF token holds the trailing ones
+	Comments []Comment `json:",omitempty"`
 	//LineNumber 	int
 }
 
+// Comment is kept as trivia of the token after it, Text includes the // or /* */
+type Comment struct {
+	Text     string
+	StartPos Position
+	EndPos   Position
+}
+
 func (token Token) isOneOfMany(expectedTokens ...TOKEN_KIND) bool {
 	for _, expected := range expectedTokens {
 		if expected == token.Kind {
@@ -201,6 +236,9 @@ func (token Token) Debug() {
 
 func NewToken(kind TOKEN_KIND, value string, start Position, end Position) Token {
 	return Token{
-		kind, value, start, end,
+		Kind:     kind,
+		Value:    value,
+		StartPos: start,
+		EndPos:   end,
 	}
 }
@@ -21,8 +21,58 @@ type HintType struct {
 }
 
 type ErrorMessage struct {
-	Message string
-	hints   []HintType
+	// the rendered report with the source line and hints
+	Message  string
+	Text     string
+	FilePath string
+	StartPos lexer.Position
+	EndPos   lexer.Position
+	// the calls in progress when a runtime error was raised, the innermost first
+	Trace []TraceFrame
+	// the error of a native the report was made for
+	Err    error
+	hints  []HintType
+	parser *Parser
+}
+
+// TraceFrame is a call on the stack of a runtime error, at the position it had reached
+type TraceFrame struct {
+	Function string
+	FilePath string
+	Pos      lexer.Position
+}
+
+func (f TraceFrame) String() string {
+	return fmt.Sprintf("at %s (%s:%d:%d)", f.Function, f.FilePath, f.Pos.Line, f.Pos.Column)
+}
+
+// frames of a longer trace are elided in the middle, deep recursion would bury the error
+const traceEnds = 10
+
+// FormatTrace renders a trace one frame a line, innermost first
+func FormatTrace(trace []TraceFrame) string {
+
+	var lines []string
+
+	for i, frame := range trace {
+		if len(trace) > 2*traceEnds && i >= traceEnds && i < len(trace)-traceEnds {
+			if i == traceEnds {
+				lines = append(lines, fmt.Sprintf("    ... %d more frames", len(trace)-2*traceEnds))
+			}
+			continue
+		}
+		lines = append(lines, "    "+frame.String())
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (e *ErrorMessage) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.FilePath, e.StartPos.Line, e.StartPos.Column, strings.TrimSpace(e.Text))
 }
 
 func (e *ErrorMessage) AddHint(htext string, htype htype) *ErrorMessage {
@@ -33,21 +83,50 @@ func (e *ErrorMessage) AddHint(htext string, htype htype) *ErrorMessage {
 	return e
 }
 
-func (e *ErrorMessage) Display() {
-	fmt.Print(e.Message)
+// WithTrace attaches the stack of a runtime error to the report
+func (e *ErrorMessage) WithTrace(trace []TraceFrame) *ErrorMessage {
+	e.Trace = trace
+	return e
+}
+
+// WithCause keeps the error a report was made for, errors.As finds it through the report
+func (e *ErrorMessage) WithCause(err error) *ErrorMessage {
+	e.Err = err
+	return e
+}
+
+func (e *ErrorMessage) Unwrap() error {
+	return e.Err
+}
+
+// Report returns the rendered error followed by its hints and its trace
+func (e *ErrorMessage) Report() string {
+	report := e.Message
 	// hints
 	for i, hint := range e.hints {
 		if i == 0 {
-			fmt.Print(utils.Colorize(utils.ORANGE, "Hint: "))
+			report += utils.Colorize(utils.ORANGE, "Hint: ")
 		}
-		//fmt.Print(utils.Colorize( utils.ORANGE, (fmt.Sprintf("Hint: %s\n", hint.HText))))
 		if hint.HType == TEXT_HINT {
-			fmt.Print(utils.Colorize(utils.ORANGE, hint.HText))
+			report += utils.Colorize(utils.ORANGE, hint.HText)
 		} else {
-			fmt.Print(lexer.Highlight(hint.HText))
+			report += lexer.Highlight(hint.HText)
 		}
 	}
-	fmt.Println("")
+	if len(e.Trace) > 0 {
+		if !strings.HasSuffix(report, "\n") {
+			report += "\n"
+		}
+		report += utils.Colorize(utils.GREY, FormatTrace(e.Trace))
+	}
+	return report
+}
+
+func (e *ErrorMessage) Display() {
+	if e.parser != nil && e.parser.PanicOnError {
+		panic(e)
+	}
+	fmt.Println(e.Report())
 	//panic("Error")
 	os.Exit(1)
 }
@@ -63,16 +142,12 @@ func MakeError(p *Parser, lineNo int, filePath string, startPos lexer.Position,
 	var errStr string
 
 	var prvLines []string
-	line := (*p.Lines)[lineNo-1]
+	line := p.highlighted(lineNo - 1)
 	maxWidth := len(fmt.Sprintf("%d", len(*p.Lines)))
 
 	if lineNo-1 > 0 {
-		// add the padding to each line
-		prvLines = (*p.Lines)[lineNo-2 : lineNo-1]
-
-		for i, l := range prvLines {
-			prvLines[i] = utils.Colorize(utils.GREY, makePadding(maxWidth, lineNo-1+i) + lexer.Highlight(l))
-		}
+		// add the padding to each line, the lines of the parser are left as they are
+		prvLines = append(prvLines, utils.Colorize(utils.GREY, makePadding(maxWidth, lineNo-1)+p.highlighted(lineNo-2)))
 	}
 
 	errStr += fmt.Sprintf("\nIn file: %s:%d:%d\n", filePath, startPos.Line, startPos.Column)
@@ -80,7 +155,7 @@ func MakeError(p *Parser, lineNo int, filePath string, startPos lexer.Position,
 	padding := makePadding(maxWidth, startPos.Line)
 
 	errStr += strings.Join(prvLines, "\n") + "\n"
-	errStr += utils.Colorize(utils.GREY, padding) + lexer.Highlight(line) + "\n"
+	errStr += utils.Colorize(utils.GREY, padding) + line + "\n"
 	errStr += strings.Repeat(" ", (startPos.Column-1)+len(padding))
 	repeatCount := (endPos.Column - 1) - (startPos.Column - 1) - 1
 	if repeatCount < 0 {
@@ -90,6 +165,11 @@ func MakeError(p *Parser, lineNo int, filePath string, startPos lexer.Position,
 	errStr += fmt.Sprint(utils.Colorize(utils.RED, fmt.Sprintf("Error: %s\n", errMsg)))
 
 	return &ErrorMessage{
-		Message: errStr,
+		Message:  errStr,
+		Text:     errMsg,
+		FilePath: filePath,
+		StartPos: startPos,
+		EndPos:   endPos,
+		parser:   p,
 	}
 }
@@ -40,9 +40,11 @@ func parseBinaryExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
 // representing the parsed function call.
 func parseCallExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
 
-	//try to convert the left expression to a function
-
-	if left.INodeType() != ast.IDENTIFIER {
+	//try to convert the left expression to a function. module members like fs.walk are called through a property,
+	//function values can be called where they are produced, like makeAdder(1)(2), fns[0](x) or fn(x: i32) { ... }(1)
+	switch left.INodeType() {
+	case ast.IDENTIFIER, ast.PROPERTY, ast.FUNCTION_CALL_EXPRESSION, ast.ARRAY_ACCESS, ast.FUNCTION_EXPRESSION:
+	default:
 		start, end := left.GetPos()
 		MakeError(p, p.currentToken().StartPos.Line, p.FilePath, start, end, "cannot parse expression. calling a non-function").Display()
 	}
@@ -52,11 +54,22 @@ func parseCallExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
 	p.expect(lexer.OPEN_PAREN_TOKEN)
 
 	var arguments []ast.Node
+	var named []ast.NamedArg
 
 	for p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
-		//parse the arguments
-		argument := parseExpr(p, DEFAULT_BP)
-		arguments = append(arguments, argument)
+
+		//an identifier followed by a colon names the parameter it is given to
+		if p.currentTokenKind() == lexer.IDENTIFIER_TOKEN && p.nextToken().Kind == lexer.COLON_TOKEN {
+			named = append(named, parseNamedArg(p))
+		} else {
+			//parse the arguments
+			argument := parseExpr(p, DEFAULT_BP)
+			if len(named) > 0 {
+				start, end := argument.GetPos()
+				MakeError(p, start.Line, p.FilePath, start, end, "positional argument after a named argument").AddHint("named arguments come after the positional ones", TEXT_HINT).Display()
+			}
+			arguments = append(arguments, argument)
+		}
 
 		if p.currentTokenKind() == lexer.COMMA_TOKEN {
 			p.advance()
@@ -71,8 +84,54 @@ func parseCallExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
 			StartPos: start,
 			EndPos:   end,
 		},
-		Caller: left.(ast.IdentifierExpr),
-		Args:   arguments,
+		Caller:    left,
+		Args:      arguments,
+		NamedArgs: named,
+	}
+}
+
+// parseNamedArg parses name: value, an argument given by the name of its parameter
+func parseNamedArg(p *Parser) ast.NamedArg {
+
+	identifier := p.expect(lexer.IDENTIFIER_TOKEN)
+
+	p.expect(lexer.COLON_TOKEN)
+
+	value := parseExpr(p, DEFAULT_BP)
+
+	_, end := value.GetPos()
+
+	return ast.NamedArg{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.NAMED_ARGUMENT,
+			StartPos: identifier.StartPos,
+			EndPos:   end,
+		},
+		Name: ast.IdentifierExpr{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.IDENTIFIER,
+				StartPos: identifier.StartPos,
+				EndPos:   identifier.EndPos,
+			},
+			Identifier: identifier.Value,
+		},
+		Value: value,
+	}
+}
+
+// parseTryExpr parses value?, which unwraps an Ok Result and returns an Err
+// from the function around it
+func parseTryExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
+
+	end := p.expect(lexer.QUESTION_TOKEN).EndPos
+
+	return ast.TryExpr{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.TRY_EXPRESSION,
+			StartPos: ast.StartOf(left),
+			EndPos:   end,
+		},
+		Value: left,
 	}
 }
 
@@ -118,7 +177,7 @@ func parseExpr(p *Parser, bp BINDING_POWER) ast.Node {
 
 	tokenPos := p.pos
 
-	if tokenKind == lexer.IDENTIFIER_TOKEN && p.tokens[tokenPos+1].Kind == lexer.OPEN_CURLY_TOKEN && p.tokens[tokenPos+2].Kind == lexer.IDENTIFIER_TOKEN && p.tokens[tokenPos+3].Kind == lexer.COLON_TOKEN {
+	if tokenKind == lexer.IDENTIFIER_TOKEN && p.tokens[tokenPos+1].Kind == lexer.OPEN_CURLY_TOKEN && p.tokens[tokenPos+2].Kind == lexer.IDENTIFIER_TOKEN && p.tokens[tokenPos+3].Kind == lexer.COLON_TOKEN && !lexer.IsMapKeyword(p.tokens, tokenPos) {
 		return parseStructInstantiationExpr(p, parsePrimaryExpr(p))
 	}
 
@@ -220,6 +279,14 @@ func parsePrimaryExpr(p *Parser) ast.Node {
 			Value: p.advance().Value,
 		}
 	case lexer.IDENTIFIER_TOKEN:
+		// match is not reserved, only a match before its subject starts one
+		if lexer.IsMatchKeyword(p.tokens, p.pos) {
+			return parseMatchExpr(p)
+		}
+		// map is not reserved either, map[K]V{ and map{ start a map literal
+		if lexer.IsMapKeyword(p.tokens, p.pos) {
+			return parseMapExpr(p)
+		}
 		return ast.IdentifierExpr{
 			BaseStmt: ast.BaseStmt{
 				Kind:     ast.IDENTIFIER,
@@ -316,7 +383,7 @@ func parseVarAssignmentExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node
 
 	switch assignee := left.(type) {
 
-	case ast.IdentifierExpr, ast.PropertyExpr:
+	case ast.IdentifierExpr, ast.PropertyExpr, ast.ArrayIndexAccess:
 		identifier = assignee
 	default:
 		errMsg := "Cannot assign to a non-identifier\n"
@@ -411,39 +478,282 @@ func parseArrayExpr(p *Parser) ast.Node {
 	}
 }
 
+// parseMapExpr parses map{"a": 1, "b": 2}, the first entry gives the types
+// of the map. A literal without entries writes them, map[str]i32{}.
+func parseMapExpr(p *Parser) ast.Node {
+
+	start := p.currentToken().StartPos
+
+	var mapType ast.Type
+
+	if p.nextToken().Kind == lexer.OPEN_BRACKET_TOKEN {
+		mapType = parseType(p, DEFAULT_BP)
+	} else {
+		p.expect(lexer.IDENTIFIER_TOKEN)
+	}
+
+	p.expect(lexer.OPEN_CURLY_TOKEN)
+
+	entries := []ast.MapEntry{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
+		key := parseExpr(p, LOGICAL)
+		p.expect(lexer.COLON_TOKEN)
+		value := parseExpr(p, LOGICAL)
+
+		entries = append(entries, ast.MapEntry{Key: key, Value: value})
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	end := p.expect(lexer.CLOSE_CURLY_TOKEN).EndPos
+
+	if mapType == nil && len(entries) == 0 {
+		err := MakeError(p, start.Line, p.FilePath, start, end, "a map without entries needs its type")
+		err.AddHint("write it before the braces, ", TEXT_HINT)
+		err.AddHint("map[str]i32{}", CODE_HINT)
+		err.Display()
+	}
+
+	return ast.MapLiteral{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.MAP_LITERAL,
+			StartPos: start,
+			EndPos:   end,
+		},
+		Type:    mapType,
+		Entries: entries,
+	}
+}
+
+// parseArrayAccessExpr parses array[index]. An array is indexed by an integer
+// and a map by a key, the evaluator knows which the left side holds. The left
+// side can be any expression, groups[ext][0] indexes the array in a map.
 func parseArrayAccessExpr(p *Parser, left ast.Node, bp BINDING_POWER) ast.Node {
-	//start := p.currentToken().StartPos
 
-	start := p.expect(lexer.OPEN_BRACKET_TOKEN).StartPos
+	start, _ := left.GetPos()
+
+	p.expect(lexer.OPEN_BRACKET_TOKEN)
 
 	//get the index
-	index := parseExpr(p, bp)
+	index := parseExpr(p, DEFAULT_BP)
 
 	end := p.expect(lexer.CLOSE_BRACKET_TOKEN).EndPos
 
-	switch t := index.(type) {
-	case ast.NumericLiteral:
-		if t.Kind == ast.INTEGER_LITERAL {
-			return ast.ArrayIndexAccess{
-				BaseStmt: ast.BaseStmt{
-					Kind:     ast.ARRAY_ACCESS,
-					StartPos: start,
-					EndPos:   end,
-				},
-				ArrayName: left.(ast.IdentifierExpr).Identifier,
-				Index: ast.NumericLiteral{
-					BaseStmt: ast.BaseStmt{
-						Kind:     ast.INTEGER_LITERAL,
-						StartPos: t.StartPos,
-						EndPos:   t.EndPos,
-					},
-					Value:   t.Value,
-					BitSize: utils.GetIntBitSize(t.Value),
-				},
+	if t, ok := index.(ast.NumericLiteral); ok {
+		if t.Kind != ast.INTEGER_LITERAL {
+			MakeError(p, t.StartPos.Line, p.FilePath, t.StartPos, t.EndPos, "invalid index value").AddHint("index must be an integer", TEXT_HINT).Display()
+		}
+		index = ast.NumericLiteral{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.INTEGER_LITERAL,
+				StartPos: t.StartPos,
+				EndPos:   t.EndPos,
+			},
+			Value:   t.Value,
+			BitSize: utils.GetIntBitSize(t.Value),
+		}
+	}
+
+	return ast.ArrayIndexAccess{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.ARRAY_ACCESS,
+			StartPos: start,
+			EndPos:   end,
+		},
+		Array: left,
+		Index: index,
+	}
+}
+
+// parseMatchExpr parses match subject { pattern [if guard] => body, ... }.
+// The body of an arm is an expression or a block, the comma after a block is optional.
+// parseFunctionExpr parses an anonymous function, fn(x: i32) -> i32 { ret x * 2; }.
+// Like a declared function it returns void without an arrow.
+func parseFunctionExpr(p *Parser) ast.Node {
+
+	start := p.expect(lexer.FUNCTION_TOKEN).StartPos
+
+	if p.currentTokenKind() != lexer.OPEN_PAREN_TOKEN {
+		token := p.currentToken()
+		err := MakeError(p, token.StartPos.Line, p.FilePath, token.StartPos, token.EndPos, "a function used as a value has no name")
+		err.AddHint("declare it with ", TEXT_HINT)
+		err.AddHint("let name := fn(x: i32) -> i32 { ... };", CODE_HINT)
+		err.Display()
+	}
+
+	params := parseParams(p)
+
+	var returnType ast.Type = ast.VoidType{
+		Kind: ast.T_VOID,
+	}
+
+	if p.currentTokenKind() == lexer.ARROW_TOKEN {
+		p.advance()
+		returnType = parseType(p, DEFAULT_BP)
+	}
+
+	body := parseBlock(p)
+
+	return ast.FunctionExpr{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.FUNCTION_EXPRESSION,
+			StartPos: start,
+			EndPos:   body.EndPos,
+		},
+		Parameters: params,
+		ReturnType: returnType,
+		Block:      body,
+	}
+}
+
+func parseMatchExpr(p *Parser) ast.Node {
+
+	start := p.advance().StartPos // skip match
+
+	subject := parseExpr(p, DEFAULT_BP)
+
+	p.expect(lexer.OPEN_CURLY_TOKEN)
+
+	arms := []ast.MatchArm{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
+
+		armStart := p.currentToken().StartPos
+
+		pattern := parsePattern(p)
+
+		var guard ast.Node
+
+		if p.currentTokenKind() == lexer.IF_TOKEN {
+			p.advance()
+			guard = parseExpr(p, DEFAULT_BP)
+		}
+
+		p.expectError(lexer.FAT_ARROW_TOKEN, "expected => after the pattern of a match arm")
+
+		var body ast.Node
+
+		if p.currentTokenKind() == lexer.OPEN_CURLY_TOKEN {
+			body = parseBlock(p)
+		} else {
+			body = parseExpr(p, DEFAULT_BP)
+		}
+
+		_, armEnd := body.GetPos()
+
+		arms = append(arms, ast.MatchArm{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.MATCH_ARM,
+				StartPos: armStart,
+				EndPos:   armEnd,
+			},
+			Pattern: pattern,
+			Guard:   guard,
+			Body:    body,
+		})
+
+		if p.currentTokenKind() == lexer.CLOSE_CURLY_TOKEN {
+			break
+		}
+
+		if _, isBlock := body.(ast.BlockStmt); isBlock && p.currentTokenKind() != lexer.COMMA_TOKEN {
+			continue
+		}
+
+		p.expectError(lexer.COMMA_TOKEN, "expected , between the arms of a match")
+	}
+
+	end := p.expect(lexer.CLOSE_CURLY_TOKEN).EndPos
+
+	if len(arms) == 0 {
+		MakeError(p, start.Line, p.FilePath, start, end, "a match needs at least one arm").AddHint("Use ", TEXT_HINT).AddHint("_ => value", CODE_HINT).AddHint(" to match anything", TEXT_HINT).Display()
+	}
+
+	return ast.MatchExpr{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.MATCH_EXPRESSION,
+			StartPos: start,
+			EndPos:   end,
+		},
+		Subject: subject,
+		Arms:    arms,
+	}
+}
+
+// parsePattern parses the pattern of a match arm: a literal, a name,
+// Variant(pattern, ...) or Enum.Variant with or without a payload
+func parsePattern(p *Parser) ast.Node {
+
+	token := p.currentToken()
+
+	switch token.Kind {
+	case lexer.INTEGER_TOKEN, lexer.FLOATING_TOKEN, lexer.STRING_TOKEN, lexer.CHARACTER_TOKEN, lexer.TRUE_TOKEN, lexer.FALSE_TOKEN, lexer.NULL_TOKEN:
+		return parsePrimaryExpr(p)
+	case lexer.MINUS_TOKEN:
+		operator := p.advance()
+		if kind := p.currentTokenKind(); kind != lexer.INTEGER_TOKEN && kind != lexer.FLOATING_TOKEN {
+			MakeError(p, token.StartPos.Line, p.FilePath, token.StartPos, p.currentToken().EndPos, "only a number can be negated in a pattern").Display()
+		}
+		number := parsePrimaryExpr(p)
+		_, end := number.GetPos()
+		return ast.UnaryExpr{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.UNARY_EXPRESSION,
+				StartPos: token.StartPos,
+				EndPos:   end,
+			},
+			Operator: operator,
+			Argument: number,
+		}
+	case lexer.IDENTIFIER_TOKEN:
+		// handled below
+	default:
+		MakeError(p, token.StartPos.Line, p.FilePath, token.StartPos, token.EndPos, fmt.Sprintf("expected a pattern but got '%s'", token.Value)).AddHint("A pattern is a literal, a name, ", TEXT_HINT).AddHint("_", CODE_HINT).AddHint(" or a variant like ", TEXT_HINT).AddHint("Circle(r)", CODE_HINT).Display()
+	}
+
+	name := p.advance()
+
+	pattern := ast.VariantPattern{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.VARIANT_PATTERN,
+			StartPos: name.StartPos,
+			EndPos:   name.EndPos,
+		},
+		Variant: name.Value,
+	}
+
+	if p.currentTokenKind() == lexer.DOT_TOKEN {
+		p.advance()
+		variant := p.expect(lexer.IDENTIFIER_TOKEN)
+		pattern.EnumName = name.Value
+		pattern.Variant = variant.Value
+		pattern.EndPos = variant.EndPos
+	} else if p.currentTokenKind() != lexer.OPEN_PAREN_TOKEN {
+		// _, a binding or a plain variant, the checker tells them apart
+		return ast.IdentifierExpr{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.IDENTIFIER,
+				StartPos: name.StartPos,
+				EndPos:   name.EndPos,
+			},
+			Identifier: name.Value,
+		}
+	}
+
+	if p.currentTokenKind() == lexer.OPEN_PAREN_TOKEN {
+		p.advance()
+		pattern.Fields = []ast.Node{}
+		for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+			pattern.Fields = append(pattern.Fields, parsePattern(p))
+			if p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+				p.expect(lexer.COMMA_TOKEN)
 			}
 		}
+		pattern.EndPos = p.expect(lexer.CLOSE_PAREN_TOKEN).EndPos
 	}
 
-	MakeError(p, start.Line, p.FilePath, start, end, "invalid index value").AddHint("index must be an integer", TEXT_HINT).Display()
-	panic("error")
+	return pattern
 }
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"math"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+)
+
+// declaration is a name the program declares and the source it can be used in
+type declaration struct {
+	kind       lexer.SEMANTIC_KIND
+	start, end int
+}
+
+// declarations collects the names of a program which the syntax around their
+// uses does not tell apart, like a function passed as a value or a parameter
+// used in the body. The methods of impls are only reached through a dot.
+func declarations(program ast.ProgramStmt) map[string][]declaration {
+
+	declared := map[string][]declaration{}
+
+	declare := func(name string, kind lexer.SEMANTIC_KIND, start int, end int) {
+		declared[name] = append(declared[name], declaration{kind, start, end})
+	}
+
+	methods := map[lexer.Position]bool{}
+
+	// the nodes around the one inspected, the innermost last
+	var parents []ast.Node
+
+	ast.Inspect(program, func(node ast.Node) bool {
+
+		if node == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+
+		switch n := node.(type) {
+		case ast.FunctionDeclStmt:
+			if !methods[n.StartPos] {
+				declare(n.Name.Identifier, lexer.SEMANTIC_FUNCTION, 0, math.MaxInt)
+			}
+			for _, param := range n.Parameters {
+				declare(param.Identifier.Identifier, lexer.SEMANTIC_PARAMETER, n.StartPos.Index, n.EndPos.Index)
+			}
+		case ast.FunctionExpr:
+			for _, param := range n.Parameters {
+				declare(param.Identifier.Identifier, lexer.SEMANTIC_PARAMETER, n.StartPos.Index, n.EndPos.Index)
+			}
+		case ast.ImplementStatement:
+			for _, method := range n.Methods {
+				methods[method.FunctionDeclStmt.StartPos] = true
+			}
+		case ast.StructDeclStatement:
+			declare(n.StructName, lexer.SEMANTIC_STRUCT, 0, math.MaxInt)
+		case ast.TraitDeclStatement:
+			declare(n.TraitName, lexer.SEMANTIC_TRAIT, 0, math.MaxInt)
+		case ast.EnumDeclStatement:
+			declare(n.EnumName, lexer.SEMANTIC_ENUM, 0, math.MaxInt)
+		case ast.VariableDclStml:
+			if n.IsConstant {
+				declare(n.Identifier.Identifier, lexer.SEMANTIC_CONSTANT, n.StartPos.Index, enclosingEnd(parents))
+			}
+		}
+
+		parents = append(parents, node)
+
+		return true
+	})
+
+	return declared
+}
+
+// enclosingEnd is where the innermost block around a node ends, a constant of
+// the program is visible to the end of the source
+func enclosingEnd(parents []ast.Node) int {
+	for i := len(parents) - 1; i >= 0; i-- {
+		if block, ok := parents[i].(ast.BlockStmt); ok {
+			return block.EndPos.Index
+		}
+	}
+	return math.MaxInt
+}
+
+// resolver classifies the identifiers of the source by the declarations of the
+// parsed program, nil before the program parsed. The innermost declaration of
+// a name around an identifier wins.
+func (p *Parser) resolver() lexer.Resolver {
+
+	if p.program == nil {
+		return nil
+	}
+
+	declared := declarations(*p.program)
+
+	return func(i int, token lexer.Token) (lexer.SEMANTIC_KIND, bool) {
+
+		// a member, the struct it belongs to is not known here
+		if i > 0 && i < len(p.tokens) && p.tokens[i-1].Kind == lexer.DOT_TOKEN {
+			return "", false
+		}
+
+		var found *declaration
+
+		for j, decl := range declared[token.Value] {
+			index := token.StartPos.Index
+			if index < decl.start || index > decl.end {
+				continue
+			}
+			if found == nil || decl.end-decl.start < found.end-found.start {
+				found = &declared[token.Value][j]
+			}
+		}
+
+		if found == nil {
+			return "", false
+		}
+
+		return found.kind, true
+	}
+}
+
+// highlighted returns a line of the source coloured for a report, the whole
+// source is highlighted once
+func (p *Parser) highlighted(line int) string {
+
+	if p.highlights == nil {
+		p.highlights = lexer.HighlightLines(strings.Join(*p.Lines, "\n"), p.resolver())
+	}
+
+	if line < 0 || line >= len(p.highlights) {
+		return ""
+	}
+
+	return p.highlights[line]
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"walrus/frontend/lexer"
+	"walrus/utils"
+)
+
+func TestReportColoursDeclaredNames(t *testing.T) {
+
+	source := "const limit := 2;\nfn add(a: i32) -> i32 {\n    ret a + limit;\n}\n/* let f := add;\n*/ let f := add;\n"
+
+	p, err := NewParserFromSource(source, "test.wal", false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Parse()
+
+	// the syntax alone does not tell add is a function where it is used as a value
+	report := MakeError(p, 6, "test.wal", lexer.Position{Line: 6, Column: 4}, lexer.Position{Line: 6, Column: 7}, "error").Message
+
+	if !strings.Contains(report, " f := "+utils.Colorize(utils.BLUE, "add")) {
+		t.Errorf("the use of add is not coloured as a function:\n%q", report)
+	}
+
+	// the line before is inside a comment
+	if !strings.Contains(report, utils.Colorize(utils.GREY, "/* let f := add;")) {
+		t.Errorf("the line in the comment is not coloured as a comment:\n%q", report)
+	}
+
+	if line := p.highlighted(2); !strings.Contains(line, utils.Colorize(utils.ORANGE, "limit")) {
+		t.Errorf("the use of the constant is not coloured: %q", line)
+	}
+}
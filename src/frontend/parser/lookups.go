@@ -84,6 +84,7 @@ func createTokenLookups() {
 	nud(lexer.MINUS_MINUS_TOKEN, parseUnaryExpr)
 	nud(lexer.NOT_TOKEN, parseUnaryExpr)
 	nud(lexer.OPEN_BRACKET_TOKEN, parseArrayExpr)
+	nud(lexer.FUNCTION_TOKEN, parseFunctionExpr)
 
 	// Assignment
 	led(lexer.ASSIGNMENT_TOKEN, ASSIGNMENT, parseVarAssignmentExpr)
@@ -112,6 +113,9 @@ func createTokenLookups() {
 	led(lexer.EQUALS_TOKEN, RELATIONAL, parseBinaryExpr)
 	led(lexer.NOT_EQUALS_TOKEN, RELATIONAL, parseBinaryExpr)
 
+	// Membership, key in map
+	led(lexer.IN_TOKEN, RELATIONAL, parseBinaryExpr)
+
 	// Additive & Multiplicative
 	led(lexer.PLUS_TOKEN, ADDITIVE, parseBinaryExpr)
 	led(lexer.MINUS_TOKEN, ADDITIVE, parseBinaryExpr)
@@ -124,6 +128,9 @@ func createTokenLookups() {
 	//call
 	led(lexer.OPEN_PAREN_TOKEN, CALL, parseCallExpr)
 
+	//value? binds like a call, -read(path)? is -(read(path)?)
+	led(lexer.QUESTION_TOKEN, CALL, parseTryExpr)
+
 	// Statements
 	stmt(lexer.CONST_TOKEN, parseVarDeclStmt)
 	stmt(lexer.LET_TOKEN, parseVarDeclStmt)
@@ -131,6 +138,7 @@ func createTokenLookups() {
 	stmt(lexer.MODULE_TOKEN, parseModuleStmt)
 	stmt(lexer.IMPORT_TOKEN, parseImportStmt)
 	stmt(lexer.STRUCT_TOKEN, parseStructDeclStmt)
+	stmt(lexer.ENUM_TOKEN, parseEnumDeclStmt)
 	stmt(lexer.TRAIT_TOKEN, parseTraitDeclStmt)
 	stmt(lexer.IMPLEMENT_TOKEN, parseImplementStmt)
 	stmt(lexer.OPEN_CURLY_TOKEN, parseBlockStmt)
@@ -146,6 +154,7 @@ func createTokenLookups() {
 	//function
 	stmt(lexer.FUNCTION_TOKEN, parseFunctionDeclStmt)
 	stmt(lexer.RETURN_TOKEN, parseReturnStmt)
+	stmt(lexer.DEFER_TOKEN, parseDeferStmt)
 
 	stmt(lexer.CONTINUE_TOKEN, parseContinueStmt)
 	stmt(lexer.BREAK_TOKEN, parseBreakStmt)
@@ -12,6 +12,15 @@ type Parser struct {
 	pos      int
 	Lines    *[]string
 	FilePath string
+	// report errors by panicking with the *ErrorMessage instead of exiting,
+	// hosts embedding the language recover it as an error
+	PanicOnError bool
+	// the type parameters in scope, inside a generic declaration
+	typeParams map[string]bool
+	// the parsed program, its declarations colour the source of reports
+	program *ast.ProgramStmt
+	// the lines of the source coloured for reports, made for the first one
+	highlights []string
 }
 
 func NewParser(fileSrc string, debugMode bool) *Parser {
@@ -23,12 +32,27 @@ func NewParser(fileSrc string, debugMode bool) *Parser {
 		panic(err)
 	}
 
-	source := string(bytes)
-
 	//filePath := filepath.Base(fileSrc)
 	filePath := fileSrc
 
-	tokens, lines := lexer.Tokenize(source, filePath, debugMode)
+	parser, err := NewParserFromSource(string(bytes), filePath, debugMode)
+
+	if err != nil {
+		fmt.Println(err.(*lexer.LexError).Report)
+		os.Exit(-1)
+	}
+
+	return parser
+}
+
+// NewParserFromSource tokenizes an in-memory source, filePath is only used in error reports
+func NewParserFromSource(source string, filePath string, debugMode bool) (*Parser, error) {
+
+	tokens, lines, err := lexer.Tokenize(source, filePath, debugMode)
+
+	if err != nil {
+		return nil, err
+	}
 
 	createTokenLookups()
 	createTokenTypesLookups()
@@ -40,7 +64,7 @@ func NewParser(fileSrc string, debugMode bool) *Parser {
 		FilePath: filePath,
 	}
 
-	return parser
+	return parser, nil
 }
 
 func (p *Parser) Parse() ast.ProgramStmt {
@@ -65,7 +89,7 @@ func (p *Parser) Parse() ast.ProgramStmt {
 
 	end := p.tokens[len(p.tokens)-1].EndPos
 
-	return ast.ProgramStmt{
+	program := ast.ProgramStmt{
 		BaseStmt: ast.BaseStmt{
 			Kind: ast.PROGRAM,
 			StartPos: p.tokens[0].StartPos,
@@ -76,6 +100,11 @@ func (p *Parser) Parse() ast.ProgramStmt {
 		Contents:   contents,
 		FileName:   p.FilePath,
 	}
+
+	// reports made from now on colour the names the program declares
+	p.program, p.highlights = &program, nil
+
+	return program
 }
 
 func (p *Parser) currentTokenKind() lexer.TOKEN_KIND {
@@ -95,6 +124,14 @@ func (p *Parser) previousToken() lexer.Token {
 	return lexer.Token{}
 }
 
+// nextToken looks one token ahead without consuming the current one
+func (p *Parser) nextToken() lexer.Token {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return lexer.Token{}
+}
+
 func (p *Parser) advance() lexer.Token {
 	token := p.currentToken()
 	p.pos++
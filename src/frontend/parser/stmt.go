@@ -17,6 +17,20 @@ func parseNode(p *Parser) ast.Node {
 		return stmt_fn(p)
 	}
 
+	// test is not reserved, only a test before fn starts a test
+	if isTestStmt(p) {
+		return parseTestStmt(p)
+	}
+
+	// a match statement ends with its braces like an if, the semicolon is optional
+	if lexer.IsMatchKeyword(p.tokens, p.pos) {
+		expr := parseMatchExpr(p)
+		if p.currentTokenKind() == lexer.SEMI_COLON_TOKEN {
+			p.advance()
+		}
+		return expr
+	}
+
 	// if not a statement, then it must be an expression
 	expr := parseExpr(p, DEFAULT_BP)
 
@@ -182,6 +196,13 @@ func parseBlock(p *Parser) ast.BlockStmt {
 
 func parseFunctionDeclStmt(p *Parser) ast.Node {
 
+	// fn( starts an anonymous function, like one called right away
+	if p.nextToken().Kind == lexer.OPEN_PAREN_TOKEN {
+		expr := parseExpr(p, DEFAULT_BP)
+		p.expect(lexer.SEMI_COLON_TOKEN)
+		return expr
+	}
+
 	start := p.currentToken().StartPos
 
 	p.expect(lexer.FUNCTION_TOKEN)
@@ -197,6 +218,9 @@ func parseFunctionDeclStmt(p *Parser) ast.Node {
 		Identifier: function.Value,
 	}
 
+	typeParams := parseTypeParams(p)
+	defer p.scopeTypeParams(typeParams)()
+
 	//parse parameters
 	params := parseParams(p)
 
@@ -230,6 +254,7 @@ func parseFunctionDeclStmt(p *Parser) ast.Node {
 				EndPos:   end,
 			},
 			Name:       functionName,
+			TypeParams: typeParams,
 			Parameters: params,
 			ReturnType: explicitReturnType,
 		},
@@ -237,6 +262,48 @@ func parseFunctionDeclStmt(p *Parser) ast.Node {
 	}
 }
 
+func isTestStmt(p *Parser) bool {
+	token := p.currentToken()
+	return token.Kind == lexer.IDENTIFIER_TOKEN && token.Value == "test" && p.nextToken().Kind == lexer.FUNCTION_TOKEN
+}
+
+// parseTestStmt parses test fn name() { ... }, a test takes no parameters and returns nothing
+func parseTestStmt(p *Parser) ast.Node {
+
+	start := p.advance().StartPos // skip test
+
+	p.expect(lexer.FUNCTION_TOKEN)
+
+	name := p.expect(lexer.IDENTIFIER_TOKEN)
+
+	p.expect(lexer.OPEN_PAREN_TOKEN)
+	p.expectError(lexer.CLOSE_PAREN_TOKEN, "a test takes no parameters")
+
+	if p.currentTokenKind() == lexer.ARROW_TOKEN {
+		token := p.currentToken()
+		MakeError(p, token.StartPos.Line, p.FilePath, token.StartPos, token.EndPos, "a test returns nothing").Display()
+	}
+
+	block := parseBlock(p)
+
+	return ast.TestStmt{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.TEST_STATEMENT,
+			StartPos: start,
+			EndPos:   block.EndPos,
+		},
+		Name: ast.IdentifierExpr{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.IDENTIFIER,
+				StartPos: name.StartPos,
+				EndPos:   name.EndPos,
+			},
+			Identifier: name.Value,
+		},
+		Block: block,
+	}
+}
+
 func parseReturnStmt(p *Parser) ast.Node {
 
 	start := p.currentToken().StartPos
@@ -265,6 +332,32 @@ func parseReturnStmt(p *Parser) ast.Node {
 	}
 }
 
+// parseDeferStmt parses defer call;, only a call can be deferred
+func parseDeferStmt(p *Parser) ast.Node {
+
+	start := p.expect(lexer.DEFER_TOKEN).StartPos
+
+	expr := parseExpr(p, DEFAULT_BP)
+
+	call, ok := expr.(ast.FunctionCallExpr)
+
+	if !ok {
+		exprStart, exprEnd := expr.GetPos()
+		MakeError(p, exprStart.Line, p.FilePath, ast.StartOf(expr), exprEnd, "defer needs a function call").AddHint("defer close(file);", CODE_HINT).Display()
+	}
+
+	end := p.expect(lexer.SEMI_COLON_TOKEN).EndPos
+
+	return ast.DeferStmt{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.DEFER_STATEMENT,
+			StartPos: start,
+			EndPos:   end,
+		},
+		Call: call,
+	}
+}
+
 func parseBreakStmt(p *Parser) ast.Node {
 	return parseBreakoutStmt(p)
 }
@@ -275,9 +368,20 @@ func parseContinueStmt(p *Parser) ast.Node {
 
 func parseBreakoutStmt(p *Parser) ast.Node {
 
-	start := p.advance().StartPos
+	token := p.advance()
+	start := token.StartPos
 	end := p.expect(lexer.SEMI_COLON_TOKEN).EndPos
 
+	if token.Kind == lexer.CONTINUE_TOKEN {
+		return ast.ContinueStmt{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.CONTINUE_STATEMENT,
+				StartPos: start,
+				EndPos:   end,
+			},
+		}
+	}
+
 	return ast.BreakStmt{
 		BaseStmt: ast.BaseStmt{
 			Kind:     ast.BREAK_STATEMENT,
@@ -297,6 +401,9 @@ func parseStructDeclStmt(p *Parser) ast.Node {
 	structName := p.expect(lexer.IDENTIFIER_TOKEN).Value
 	var embeds []string
 
+	typeParams := parseTypeParams(p)
+	defer p.scopeTypeParams(typeParams)()
+
 	p.expect(lexer.OPEN_CURLY_TOKEN)
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
@@ -337,6 +444,7 @@ func parseStructDeclStmt(p *Parser) ast.Node {
 		Properties: properties,
 		Embeds:     embeds,
 		StructName: structName,
+		TypeParams: typeParams,
 	}
 }
 
@@ -413,6 +521,9 @@ func parseTraitDeclStmt(p *Parser) ast.Node {
 
 	traitName := p.expect(lexer.IDENTIFIER_TOKEN).Value
 
+	typeParams := parseTypeParams(p)
+	defer p.scopeTypeParams(typeParams)()
+
 	p.expect(lexer.OPEN_CURLY_TOKEN)
 
 	methods := map[string]ast.Method{}
@@ -445,6 +556,12 @@ func parseTraitDeclStmt(p *Parser) ast.Node {
 				StartPos: method.StartPos,
 				EndPos:   method.EndPos,
 			},
+			FunctionType: ast.FunctionType{
+				Kind:       ast.T_FN,
+				TypeParams: method.TypeParams,
+				Parameters: method.Parameters,
+				ReturnType: method.ReturnType,
+			},
 			IsPublic: isPublic,
 			IsStatic: isStatic,
 		}
@@ -460,8 +577,9 @@ func parseTraitDeclStmt(p *Parser) ast.Node {
 			StartPos: start,
 			EndPos:   end,
 		},
-		TraitName: traitName,
-		Methods:   methods,
+		TraitName:  traitName,
+		TypeParams: typeParams,
+		Methods:    methods,
 	}
 }
 
@@ -471,6 +589,9 @@ func parseFunctionPrototype(p *Parser) ast.FunctionPrototype {
 
 	function := p.expect(lexer.IDENTIFIER_TOKEN)
 
+	typeParams := parseTypeParams(p)
+	defer p.scopeTypeParams(typeParams)()
+
 	Parameters := parseParams(p)
 
 	var ReturnType ast.Type
@@ -498,6 +619,7 @@ func parseFunctionPrototype(p *Parser) ast.FunctionPrototype {
 			},
 			Identifier: function.Value,
 		},
+		TypeParams: typeParams,
 		Parameters: Parameters,
 		ReturnType: ReturnType,
 	}
@@ -512,6 +634,8 @@ func parseImplementStmt(p *Parser) ast.Node {
 
 	var TypeToImplement string
 
+	var typeParams []ast.TypeParam
+
 	// syntax: impl A, B, C for T { ... } or impl A for T { ... } or impl T { ... }
 	if p.currentTokenKind() == lexer.IDENTIFIER_TOKEN {
 		traits = append(traits, p.expect(lexer.IDENTIFIER_TOKEN).Value)
@@ -526,10 +650,14 @@ func parseImplementStmt(p *Parser) ast.Node {
 	if p.currentTokenKind() != lexer.OPEN_CURLY_TOKEN {
 		p.expect(lexer.FOR_TOKEN)
 		TypeToImplement = p.expect(lexer.IDENTIFIER_TOKEN).Value
+		// impl Display for Box<T> { ... } names the parameters of a generic type
+		typeParams = parseTypeParams(p)
 	} else {
 		TypeToImplement = traits[0]
 	}
 
+	defer p.scopeTypeParams(typeParams)()
+
 	p.expect(lexer.OPEN_CURLY_TOKEN)
 
 	methods := map[string]ast.MethodImplementStmt{}
@@ -577,6 +705,7 @@ func parseImplementStmt(p *Parser) ast.Node {
 			EndPos:   end,
 		},
 		Impliments: TypeToImplement,
+		TypeParams: typeParams,
 		Traits:     traits,
 		Methods:    methods,
 	}
@@ -592,16 +721,41 @@ func parseParams(p *Parser) []ast.FunctionParameter {
 
 		param := p.expect(lexer.IDENTIFIER_TOKEN)
 
+		if len(params) > 0 && params[len(params)-1].IsVariadic {
+			last := params[len(params)-1].Identifier
+			MakeError(p, last.StartPos.Line, p.FilePath, last.StartPos, last.EndPos, fmt.Sprintf("variadic parameter %s must be the last parameter", last.Identifier)).Display()
+		}
+
 		p.expect(lexer.COLON_TOKEN)
 
+		// args: ...T takes the rest of the arguments, they arrive as a []T
+		isVariadic := false
+
+		if p.currentTokenKind() == lexer.ELLIPSIS_TOKEN {
+			p.advance()
+			isVariadic = true
+		}
+
 		paramType := parseType(p, DEFAULT_BP)
 
+		var defaultVal ast.Node
+
+		if p.currentTokenKind() == lexer.ASSIGNMENT_TOKEN {
+			equals := p.advance()
+			if isVariadic {
+				MakeError(p, equals.StartPos.Line, p.FilePath, equals.StartPos, equals.EndPos, fmt.Sprintf("variadic parameter %s cannot have a default value", param.Value)).Display()
+			}
+			defaultVal = parseExpr(p, DEFAULT_BP)
+		} else if len(params) > 0 && params[len(params)-1].DefaultVal != nil && !isVariadic {
+			MakeError(p, param.StartPos.Line, p.FilePath, param.StartPos, param.EndPos, fmt.Sprintf("parameter %s needs a default value, it follows a parameter with one", param.Value)).AddHint("parameters with default values come last", TEXT_HINT).Display()
+		}
+
 		//add to the map
 		params = append(params, ast.FunctionParameter{
 			BaseStmt: ast.BaseStmt{
 				Kind:     ast.FUNCTION_PARAMETER,
 				StartPos: param.StartPos,
-				EndPos:   param.EndPos,
+				EndPos:   p.previousToken().EndPos,
 			},
 			Identifier: ast.IdentifierExpr{
 				BaseStmt: ast.BaseStmt{
@@ -611,9 +765,9 @@ func parseParams(p *Parser) []ast.FunctionParameter {
 				},
 				Identifier: param.Value,
 			},
-			IsVariadic: false,
+			IsVariadic: isVariadic,
 			Type:       paramType,
-			DefaultVal: nil,
+			DefaultVal: defaultVal,
 		})
 
 		if p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
@@ -858,3 +1012,113 @@ func parseWhileLoopStmt(p *Parser) ast.Node {
 		Block:     block,
 	}
 }
+
+// parseEnumDeclStmt parses enum Name { Variant(field: T, ...), Variant, ... }, a trailing comma is allowed
+func parseEnumDeclStmt(p *Parser) ast.Node {
+
+	start := p.currentToken().StartPos
+
+	p.expect(lexer.ENUM_TOKEN)
+
+	enumName := p.expect(lexer.IDENTIFIER_TOKEN)
+
+	typeParams := parseTypeParams(p)
+	defer p.scopeTypeParams(typeParams)()
+
+	p.expect(lexer.OPEN_CURLY_TOKEN)
+
+	variants := []ast.EnumVariant{}
+
+	declared := map[string]bool{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
+
+		name := p.expect(lexer.IDENTIFIER_TOKEN)
+
+		if declared[name.Value] {
+			MakeError(p, name.StartPos.Line, p.FilePath, name.StartPos, name.EndPos, fmt.Sprintf("variant %s is already declared in enum %s", name.Value, enumName.Value)).Display()
+		}
+
+		declared[name.Value] = true
+
+		variant := ast.EnumVariant{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.ENUM_VARIANT,
+				StartPos: name.StartPos,
+				EndPos:   name.EndPos,
+			},
+			Name: name.Value,
+		}
+
+		if p.currentTokenKind() == lexer.OPEN_PAREN_TOKEN {
+			variant.Fields = parseEnumFields(p)
+			variant.EndPos = p.previousToken().EndPos
+		}
+
+		variants = append(variants, variant)
+
+		if p.currentTokenKind() != lexer.CLOSE_CURLY_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	end := p.expect(lexer.CLOSE_CURLY_TOKEN).EndPos
+
+	if len(variants) == 0 {
+		MakeError(p, start.Line, p.FilePath, start, end, fmt.Sprintf("enum %s has no variants", enumName.Value)).AddHint("An enum needs at least one variant, like ", TEXT_HINT).AddHint("enum "+enumName.Value+" { Empty }", CODE_HINT).Display()
+	}
+
+	return ast.EnumDeclStatement{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.ENUM_STATEMENT,
+			StartPos: start,
+			EndPos:   end,
+		},
+		EnumName:   enumName.Value,
+		TypeParams: typeParams,
+		Variants:   variants,
+	}
+}
+
+// parseEnumFields parses the payload (name: T, ...) of a variant
+func parseEnumFields(p *Parser) []ast.EnumField {
+
+	fields := []ast.EnumField{}
+
+	declared := map[string]bool{}
+
+	p.expect(lexer.OPEN_PAREN_TOKEN)
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+
+		name := p.expect(lexer.IDENTIFIER_TOKEN)
+
+		if declared[name.Value] {
+			MakeError(p, name.StartPos.Line, p.FilePath, name.StartPos, name.EndPos, fmt.Sprintf("field %s is already declared", name.Value)).Display()
+		}
+
+		declared[name.Value] = true
+
+		p.expect(lexer.COLON_TOKEN)
+
+		fieldType := parseType(p, DEFAULT_BP)
+
+		fields = append(fields, ast.EnumField{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.ENUM_FIELD,
+				StartPos: name.StartPos,
+				EndPos:   p.previousToken().EndPos,
+			},
+			Name: name.Value,
+			Type: fieldType,
+		})
+
+		if p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	p.expect(lexer.CLOSE_PAREN_TOKEN)
+
+	return fields
+}
@@ -29,9 +29,16 @@ func typeNUD(kind lexer.TOKEN_KIND, handleTypeNud typeNudNandlerType) {
 func createTokenTypesLookups() {
 	typeNUD(lexer.IDENTIFIER_TOKEN, parseDataType)
 	typeNUD(lexer.OPEN_BRACKET_TOKEN, parseArrayType)
+	typeNUD(lexer.FUNCTION_TOKEN, parseFunctionType)
 }
 
 func parseDataType(p *Parser) ast.Type {
+
+	// map is not reserved, only a map before its key type starts a map type
+	if lexer.IsMapKeyword(p.tokens, p.pos) {
+		return parseMapType(p)
+	}
+
 	identifier := p.expect(lexer.IDENTIFIER_TOKEN)
 
 	value := identifier.Value
@@ -66,13 +73,135 @@ func parseDataType(p *Parser) ast.Type {
 		return ast.StringType{
 			Kind: ast.T_STRING,
 		}
+	case "any":
+		return ast.AnyType{
+			Kind: ast.T_ANY,
+		}
 	default:
+		if p.typeParams[value] {
+			return ast.TypeParamType{
+				Kind: ast.DATA_TYPE(value),
+			}
+		}
+		if p.currentTokenKind() == lexer.LESS_TOKEN {
+			return ast.GenericType{
+				Kind:     ast.DATA_TYPE(value),
+				TypeArgs: parseTypeArgs(p),
+			}
+		}
 		return ast.StructType{
 			Kind: ast.DATA_TYPE(value),
 		}
 	}
 }
 
+// parseTypeArgs parses the type arguments of a generic struct, <i32, []str>
+func parseTypeArgs(p *Parser) []ast.Type {
+
+	p.expect(lexer.LESS_TOKEN)
+
+	var args []ast.Type
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.GREATER_TOKEN {
+
+		args = append(args, parseType(p, DEFAULT_BP))
+
+		if p.currentTokenKind() != lexer.GREATER_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	end := p.expect(lexer.GREATER_TOKEN)
+
+	if len(args) == 0 {
+		MakeError(p, end.StartPos.Line, p.FilePath, end.StartPos, end.EndPos, "expected a type argument").Display()
+	}
+
+	return args
+}
+
+// parseTypeParams parses the type parameters after the name of a generic
+// declaration, <T, U: Display + Debug>. A declaration without them has none.
+func parseTypeParams(p *Parser) []ast.TypeParam {
+
+	if p.currentTokenKind() != lexer.LESS_TOKEN {
+		return nil
+	}
+
+	p.advance()
+
+	params := []ast.TypeParam{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.GREATER_TOKEN {
+
+		name := p.expect(lexer.IDENTIFIER_TOKEN)
+
+		for _, param := range params {
+			if param.Name == name.Value {
+				MakeError(p, name.StartPos.Line, p.FilePath, name.StartPos, name.EndPos, fmt.Sprintf("type parameter %s is already declared", name.Value)).Display()
+			}
+		}
+
+		var bounds []string
+
+		if p.currentTokenKind() == lexer.COLON_TOKEN {
+			p.advance()
+			bounds = append(bounds, p.expect(lexer.IDENTIFIER_TOKEN).Value)
+			for p.currentTokenKind() == lexer.PLUS_TOKEN {
+				p.advance()
+				bounds = append(bounds, p.expect(lexer.IDENTIFIER_TOKEN).Value)
+			}
+		}
+
+		params = append(params, ast.TypeParam{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.TYPE_PARAMETER,
+				StartPos: name.StartPos,
+				EndPos:   p.previousToken().EndPos,
+			},
+			Name:   name.Value,
+			Bounds: bounds,
+		})
+
+		if p.currentTokenKind() != lexer.GREATER_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	end := p.expect(lexer.GREATER_TOKEN)
+
+	if len(params) == 0 {
+		MakeError(p, end.StartPos.Line, p.FilePath, end.StartPos, end.EndPos, "expected a type parameter").AddHint("remove the ", TEXT_HINT).AddHint("<>", CODE_HINT).AddHint(" or name a type like ", TEXT_HINT).AddHint("<T>", CODE_HINT).Display()
+	}
+
+	return params
+}
+
+// scopeTypeParams makes the type parameters types until the returned function
+// restores the outer scope, the parameters of an impl stay visible in its methods
+func (p *Parser) scopeTypeParams(params []ast.TypeParam) func() {
+
+	outer := p.typeParams
+
+	if len(params) == 0 {
+		return func() {}
+	}
+
+	scope := map[string]bool{}
+
+	for name := range outer {
+		scope[name] = true
+	}
+
+	for _, param := range params {
+		scope[param.Name] = true
+	}
+
+	p.typeParams = scope
+
+	return func() { p.typeParams = outer }
+}
+
 func parseArrayType(p *Parser) ast.Type {
 
 	p.advance()
@@ -86,6 +215,79 @@ func parseArrayType(p *Parser) ast.Type {
 	}
 }
 
+// parseMapType parses map[str]i32, keys are strings, integers, characters or booleans
+func parseMapType(p *Parser) ast.Type {
+
+	p.expect(lexer.IDENTIFIER_TOKEN)
+	p.expect(lexer.OPEN_BRACKET_TOKEN)
+
+	start := p.currentToken().StartPos
+	keyType := parseType(p, DEFAULT_BP)
+	end := p.previousToken().EndPos
+
+	p.expect(lexer.CLOSE_BRACKET_TOKEN)
+
+	switch keyType.(type) {
+	case ast.StringType, ast.IntegerType, ast.CharType, ast.BoolType:
+	default:
+		MakeError(p, start.Line, p.FilePath, start, end, fmt.Sprintf("type '%s' cannot be a map key", keyType.IType())).AddHint("keys are strings, integers, characters or booleans", TEXT_HINT).Display()
+	}
+
+	valueType := parseType(p, DEFAULT_BP)
+
+	return ast.MapType{
+		Kind:      ast.T_MAP,
+		KeyType:   keyType,
+		ValueType: valueType,
+	}
+}
+
+// parseFunctionType parses fn(i32, str) -> bool, a function type without an arrow returns void
+func parseFunctionType(p *Parser) ast.Type {
+
+	p.advance()
+	p.expect(lexer.OPEN_PAREN_TOKEN)
+
+	params := []ast.FunctionParameter{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+
+		start := p.currentToken().StartPos
+
+		paramType := parseType(p, DEFAULT_BP)
+
+		params = append(params, ast.FunctionParameter{
+			BaseStmt: ast.BaseStmt{
+				Kind:     ast.FUNCTION_PARAMETER,
+				StartPos: start,
+				EndPos:   p.previousToken().EndPos,
+			},
+			Type: paramType,
+		})
+
+		if p.currentTokenKind() != lexer.CLOSE_PAREN_TOKEN {
+			p.expect(lexer.COMMA_TOKEN)
+		}
+	}
+
+	p.expect(lexer.CLOSE_PAREN_TOKEN)
+
+	var returnType ast.Type = ast.VoidType{
+		Kind: ast.T_VOID,
+	}
+
+	if p.currentTokenKind() == lexer.ARROW_TOKEN {
+		p.advance()
+		returnType = parseType(p, DEFAULT_BP)
+	}
+
+	return ast.FunctionType{
+		Kind:       ast.T_FN,
+		ReturnType: returnType,
+		Parameters: params,
+	}
+}
+
 func parseType(p *Parser, bp BINDING_POWER) ast.Type {
 	// Fist parse the NUD
 	tokenKind := p.currentTokenKind()
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"walrus/builtins"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/tc"
+	"walrus/typechecker"
+)
+
+// go test -run TestGolden -update rewrites the golden files from the current output
+var update = flag.Bool("update", false, "rewrite the golden files of the samples")
+
+// the samples, every .wal file below it is one test
+const samplesDir = "../code"
+
+// the limits keep a broken sample from hanging the tests
+var sampleLimits = typechecker.Limits{
+	MaxSteps:     10_000_000,
+	MaxCallDepth: typechecker.DefaultLimits.MaxCallDepth,
+}
+
+// TestGolden parses every sample and compares it with the files next to it:
+//
+//	name.json  the syntax tree, as walrus writes it when compiling
+//	name.out   what the program prints, the program only runs when it has one
+//	name.err   the error of the sample, from parsing, checking or running
+//
+// A sample failing without a .err file fails the test. To start comparing
+// the output of a program create an empty .out file and run with -update.
+func TestGolden(t *testing.T) {
+
+	var samples []string
+
+	err := filepath.WalkDir(samplesDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".wal" {
+			samples = append(samples, path)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) == 0 {
+		t.Fatalf("no samples in %s", samplesDir)
+	}
+
+	for _, sample := range samples {
+		name, _ := filepath.Rel(samplesDir, sample)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			testSample(t, sample, filepath.ToSlash(name))
+		})
+	}
+}
+
+func testSample(t *testing.T, path string, name string) {
+
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := strings.TrimSuffix(path, ".wal")
+
+	result := runSample(name, string(source), exists(base+".out") || exists(base+".err"))
+
+	if result.tree != nil {
+		compareGolden(t, base+".json", result.tree, true)
+	}
+
+	diagnostic := ""
+
+	if result.err != nil {
+		diagnostic = result.err.Error() + "\n" + parser.FormatTrace(typechecker.StackTrace(result.err))
+	}
+
+	switch {
+	case exists(base + ".err"):
+		compareGolden(t, base+".err", []byte(diagnostic), diagnostic != "")
+	case diagnostic != "":
+		if *update {
+			compareGolden(t, base+".err", []byte(diagnostic), true)
+		} else {
+			t.Errorf("unexpected error, add %s.err to expect it:\n%s", filepath.Base(base), diagnostic)
+		}
+	}
+
+	if exists(base + ".out") {
+		compareGolden(t, base+".out", result.stdout, true)
+	}
+}
+
+type sampleResult struct {
+	// the syntax tree as json, nil when the sample does not parse
+	tree   []byte
+	stdout []byte
+	err    error
+}
+
+// runSample parses and checks a source like compile does, then runs it when asked
+func runSample(name string, source string, run bool) (result sampleResult) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if err, ok := recovered.(error); ok {
+				result.err = err
+				return
+			}
+			result.err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	p, err := parser.NewParserFromSource(source, name, false)
+
+	if err != nil {
+		return sampleResult{err: lexError(err)}
+	}
+
+	p.PanicOnError = true
+
+	program := p.Parse()
+
+	result.tree, err = ast.EncodeJSON(program)
+
+	if err != nil {
+		return sampleResult{err: err}
+	}
+
+	globalTypes := tc.NewTypeEnv(nil)
+	builtins.DeclareTypes(globalTypes)
+
+	if _, err := tc.CheckType(program, tc.NewTypeEnv(globalTypes)); err != nil {
+		if typeErr, ok := err.(tc.TypeError); ok {
+			err = parser.MakeError(p, typeErr.Start.Line, name, typeErr.Start, typeErr.End, typeErr.Message)
+		}
+		result.err = err
+		return result
+	}
+
+	if run {
+		result.stdout, result.err = evaluateSample(program, p)
+	}
+
+	return result
+}
+
+func evaluateSample(program ast.ProgramStmt, p *parser.Parser) (stdout []byte, err error) {
+
+	var out bytes.Buffer
+
+	defer func() {
+		stdout = out.Bytes()
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	// the samples may not touch the file system
+	globals := typechecker.NewEnvironment(nil, p)
+	builtins.Declare(globals, builtins.NewFsCapability())
+	globals.SetVariable("print", builtins.Print(&out), true)
+	// nor print what changes from run to run
+	globals.SetVariable("time", typechecker.MakeNativeFUNCTION(sampleTime, typechecker.NativeSignature{
+		ReturnType: ast.IntegerType{Kind: ast.T_INTEGER64, BitSize: 64, IsSigned: true},
+	}), true)
+
+	env := typechecker.NewEnvironment(globals, p)
+	env.SetLimits(sampleLimits)
+
+	typechecker.Evaluate(program, env)
+
+	return nil, nil
+}
+
+// sampleTime is the clock of the samples, stopped at the start of 2024
+func sampleTime(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+	return typechecker.MakeINT(1704067200, 64, true), nil
+}
+
+// lexError drops the rendered report of a lexer error, its colours would end up in the golden file
+func lexError(err error) error {
+	if lexErr, ok := err.(*lexer.LexError); ok {
+		return fmt.Errorf("%s:%d:%d: unexpected character '%c'", lexErr.FilePath, lexErr.Pos.Line, lexErr.Pos.Column, lexErr.Char)
+	}
+	return err
+}
+
+// compareGolden checks got against a golden file or rewrites it with -update.
+// A golden file which should not exist is removed by -update.
+func compareGolden(t *testing.T, path string, got []byte, want bool) {
+
+	t.Helper()
+
+	if *update {
+		if !want {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			return
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if !want {
+		t.Errorf("%s is stale, nothing failed", filepath.Base(path))
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		t.Errorf("missing %s, run go test -run TestGolden -update", filepath.Base(path))
+		return
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(golden, got) {
+		t.Errorf("%s differs:\n%s", filepath.Base(path), diff(string(golden), string(got)))
+	}
+}
+
+// diff shows the lines from the first one two texts differ on, with a little context before it
+func diff(want string, got string) string {
+
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	first := 0
+
+	for first < len(wantLines) && first < len(gotLines) && wantLines[first] == gotLines[first] {
+		first++
+	}
+
+	const context = 3
+
+	var out strings.Builder
+
+	for i := first - context; i <= first+context; i++ {
+		switch {
+		case i < 0:
+		case i < first:
+			fmt.Fprintf(&out, " %4d | %s\n", i+1, wantLines[i])
+		default:
+			if i < len(wantLines) {
+				fmt.Fprintf(&out, "-%4d | %s\n", i+1, wantLines[i])
+			}
+			if i < len(gotLines) {
+				fmt.Fprintf(&out, "+%4d | %s\n", i+1, gotLines[i])
+			}
+		}
+	}
+
+	return out.String()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
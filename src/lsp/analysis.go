@@ -0,0 +1,916 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"walrus/builtins"
+	"walrus/formatter"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/tc"
+)
+
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	constantSymbol
+	parameterSymbol
+	functionSymbol
+	structSymbol
+	traitSymbol
+	fieldSymbol
+	methodSymbol
+	importSymbol
+	implSymbol
+	enumSymbol
+	variantSymbol
+)
+
+// symbol is a declaration of the program, or a builtin when it has no position
+type symbol struct {
+	name string
+	kind symbolKind
+	// static type, nil when the checker could not work it out
+	t      ast.Type
+	detail string
+	// the name and the whole declaration
+	start, end         lexer.Position
+	declStart, declEnd lexer.Position
+	// where the symbol can be used by its name. Members have no scope,
+	// they are only reached through a dot.
+	scopeStart, scopeEnd lexer.Position
+	member               bool
+	builtin              bool
+	children             []*symbol
+	// structs embedded by a struct
+	embeds []string
+}
+
+// analysis is what the server knows about a document which parsed
+type analysis struct {
+	symbols []*symbol
+	// top level declarations in source order, with their members as children
+	roots []*symbol
+	// structs and traits by name
+	types map[string]*symbol
+	// methods of impl blocks by the name of the type they implement
+	methods  map[string][]*symbol
+	builtins map[string]*symbol
+	tokens   []lexer.Token
+}
+
+// analyze parses and checks a document. The analysis is nil when the document
+// does not parse, the diagnostics hold the error in that case.
+func analyze(name string, text string) (a *analysis, diagnostics []Diagnostic) {
+
+	lines := strings.Split(text, "\n")
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			a = nil
+			diagnostics = []Diagnostic{errorDiagnostic(lines, recovered)}
+		}
+	}()
+
+	p, err := parser.NewParserFromSource(text, name, false)
+
+	if err != nil {
+		return nil, []Diagnostic{errorDiagnostic(lines, err)}
+	}
+
+	p.PanicOnError = true
+
+	program := p.Parse()
+
+	globals := tc.NewTypeEnv(nil)
+	builtins.DeclareTypes(globals)
+
+	diagnostics = []Diagnostic{}
+
+	if _, err := tc.CheckType(program, tc.NewTypeEnv(globals)); err != nil {
+		diagnostics = append(diagnostics, errorDiagnostic(lines, err))
+	}
+
+	tokens, _, _ := lexer.Tokenize(text, name, false)
+
+	a = &analysis{
+		types:    nativeTypes(),
+		methods:  map[string][]*symbol{},
+		builtins: builtinSymbols(),
+		tokens:   tokens,
+	}
+
+	a.program(program, tc.NewTypeEnv(globals))
+
+	return a, diagnostics
+}
+
+// errorDiagnostic places an error of the lexer, the parser or the checker in the document
+func errorDiagnostic(lines []string, err interface{}) Diagnostic {
+
+	var start, end lexer.Position
+	var text string
+
+	switch e := err.(type) {
+	case *parser.ErrorMessage:
+		start, end, text = e.StartPos, e.EndPos, strings.TrimSpace(e.Text)
+	case *lexer.LexError:
+		start, end = e.Pos, e.Pos
+		end.Column++
+		text = fmt.Sprintf("unexpected character '%c'", e.Char)
+	case tc.TypeError:
+		start, end, text = e.Start, e.End, e.Message
+	case error:
+		text = e.Error()
+	default:
+		text = fmt.Sprint(e)
+	}
+
+	// errors without a position are shown on the first line
+	if start.Line == 0 {
+		start, end = lexer.Position{Line: 1, Column: 1}, lexer.Position{Line: 1, Column: 1}
+	}
+
+	rng := Range{toPosition(lines, start), toPosition(lines, end)}
+
+	if rng.End.Line < rng.Start.Line || (rng.End.Line == rng.Start.Line && rng.End.Character <= rng.Start.Character) {
+		rng.End = Position{rng.Start.Line, rng.Start.Character + 1}
+	}
+
+	return Diagnostic{
+		Range:    rng,
+		Severity: SeverityError,
+		Source:   "walrus",
+		Message:  text,
+	}
+}
+
+// builtinSymbols are the natives every program can call without an import
+func builtinSymbols() map[string]*symbol {
+
+	symbols := map[string]*symbol{}
+
+	for name, native := range builtins.Globals() {
+		fnType := native.Signature.FunctionType()
+		symbols[name] = &symbol{
+			name:    name,
+			kind:    functionSymbol,
+			t:       fnType,
+			detail:  "fn " + signature(name, fnType.Parameters, fnType.ReturnType),
+			builtin: true,
+		}
+	}
+
+	symbols[string(ast.T_RESULT)] = builtinEnum(ast.ResultEnum())
+
+	for _, name := range []string{"true", "false", "null"} {
+		symbols[name] = &symbol{name: name, kind: constantSymbol, builtin: true}
+	}
+
+	symbols["true"].t = ast.BoolType{Kind: ast.T_BOOLEAN}
+	symbols["false"].t = ast.BoolType{Kind: ast.T_BOOLEAN}
+	symbols["null"].t = ast.NullType{Kind: ast.T_NULL}
+
+	for _, s := range symbols {
+		if s.detail == "" {
+			s.detail = "const " + s.name + ": " + typeString(s.t)
+		}
+	}
+
+	return symbols
+}
+
+// builtinEnum is the symbol of an enum every program has, like Result
+func builtinEnum(enum ast.EnumType) *symbol {
+
+	name := string(enum.Kind)
+
+	s := &symbol{name: name, kind: enumSymbol, t: enum, builtin: true}
+
+	lines := []string{"enum " + name + formatter.TypeParams(enum.TypeParams) + " {"}
+
+	for _, variant := range enum.Variants {
+
+		fields := make([]string, len(variant.Fields))
+
+		for i, field := range variant.Fields {
+			fields[i] = field.Name + ": " + typeString(field.Type)
+		}
+
+		text := variant.Name + "(" + strings.Join(fields, ", ") + ")"
+
+		lines = append(lines, formatter.INDENT+text+",")
+
+		s.children = append(s.children, &symbol{
+			name:    variant.Name,
+			kind:    variantSymbol,
+			t:       ast.StructType{Kind: enum.Kind},
+			detail:  text + " // " + name,
+			member:  true,
+			builtin: true,
+		})
+	}
+
+	s.detail = strings.Join(append(lines, "}"), "\n")
+
+	return s
+}
+
+// nativeTypes are the structs the native modules return, like Instant of core::time
+func nativeTypes() map[string]*symbol {
+
+	types := map[string]*symbol{}
+
+	for _, module := range builtins.Modules(nil) {
+		for name, native := range module.Structs {
+
+			s := &symbol{name: name, kind: structSymbol, t: ast.StructType{Kind: ast.DATA_TYPE(name)}, builtin: true}
+
+			lines := []string{"struct " + name + " {"}
+
+			for _, property := range sortedFields(native.Fields) {
+				field := propertyString(property)
+				lines = append(lines, formatter.INDENT+field+";")
+				s.children = append(s.children, &symbol{
+					name:    property.Name,
+					kind:    fieldSymbol,
+					t:       property.Type,
+					detail:  field + " // " + module.Name + "::" + name,
+					member:  true,
+					builtin: true,
+				})
+			}
+
+			s.detail = strings.Join(append(lines, "}"), "\n") + " // " + module.Name
+
+			types[name] = s
+		}
+	}
+
+	return types
+}
+
+func (a *analysis) declare(s *symbol) *symbol {
+	a.symbols = append(a.symbols, s)
+	return s
+}
+
+// nameAfter finds the identifier a declaration names among the tokens, for
+// declarations the parser keeps no position of the name for
+func (a *analysis) nameAfter(name string, from lexer.Position) (lexer.Position, lexer.Position) {
+	for _, token := range a.tokens {
+		if token.StartPos.Index >= from.Index && token.Kind == lexer.IDENTIFIER_TOKEN && token.Value == name {
+			return token.StartPos, token.EndPos
+		}
+	}
+	return from, from
+}
+
+func (a *analysis) program(program ast.ProgramStmt, env *tc.TypeEnv) {
+
+	start := lexer.Position{Line: 1, Column: 1}
+	end := a.tokens[len(a.tokens)-1].EndPos
+
+	// the checker declares the imported names, unknown modules are left undeclared
+	tc.CheckType(ast.ProgramStmt{Imports: program.Imports}, env)
+
+	for _, stmt := range program.Imports {
+		a.roots = append(a.roots, a.importStmt(stmt, env, start, end)...)
+	}
+
+	a.roots = append(a.roots, a.statements(program.Contents, env, start, end)...)
+}
+
+func (a *analysis) importStmt(stmt ast.ImportStmt, env *tc.TypeEnv, scopeStart, scopeEnd lexer.Position) []*symbol {
+
+	var symbols []*symbol
+
+	names := stmt.Identifiers
+
+	if len(names) == 0 {
+		names = []string{stmt.ModuleName[strings.LastIndex(stmt.ModuleName, ":")+1:]}
+	}
+
+	for _, name := range names {
+
+		start, end := stmt.StartPos, stmt.EndPos
+
+		if len(stmt.Identifiers) > 0 {
+			start, end = a.nameAfter(name, stmt.StartPos)
+		}
+
+		t := env.GetVar(name)
+
+		detail := "import \"" + stmt.ModuleName + "\""
+
+		if len(stmt.Identifiers) > 0 {
+			detail = name + ": " + typeString(t) + " from \"" + stmt.ModuleName + "\""
+		}
+
+		symbols = append(symbols, a.declare(&symbol{
+			name:       name,
+			kind:       importSymbol,
+			t:          t,
+			detail:     detail,
+			start:      start,
+			end:        end,
+			declStart:  stmt.StartPos,
+			declEnd:    stmt.EndPos,
+			scopeStart: scopeStart,
+			scopeEnd:   scopeEnd,
+		}))
+	}
+
+	return symbols
+}
+
+// statements indexes the declarations of a block whose scope ends at scopeEnd
+// and returns those declared directly in it
+func (a *analysis) statements(nodes []ast.Node, env *tc.TypeEnv, scopeStart, scopeEnd lexer.Position) []*symbol {
+
+	var declared []*symbol
+
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case ast.VariableDclStml:
+			declared = append(declared, a.varDecl(n, env, scopeEnd))
+		case ast.FunctionDeclStmt:
+			declared = append(declared, a.function(n, env, scopeStart, scopeEnd))
+		case ast.StructDeclStatement:
+			declared = append(declared, a.structDecl(n, scopeStart, scopeEnd))
+		case ast.TraitDeclStatement:
+			declared = append(declared, a.traitDecl(n, scopeStart, scopeEnd))
+		case ast.EnumDeclStatement:
+			declared = append(declared, a.enumDecl(n, env, scopeStart, scopeEnd))
+		case ast.ImplementStatement:
+			declared = append(declared, a.implement(n, env))
+		default:
+			a.nested(node, env)
+		}
+	}
+
+	return declared
+}
+
+// nested indexes the blocks of a statement which declares nothing itself
+func (a *analysis) nested(node ast.Node, env *tc.TypeEnv) {
+	switch n := node.(type) {
+	case ast.BlockStmt:
+		a.block(n, tc.NewTypeEnv(env))
+	case ast.IfStmt:
+		a.block(n.Block, tc.NewTypeEnv(env))
+		switch alternate := n.Alternate.(type) {
+		case ast.IfStmt:
+			a.nested(alternate, env)
+		case ast.BlockStmt:
+			a.block(alternate, tc.NewTypeEnv(env))
+		}
+	case ast.WhileLoopStmt:
+		a.block(n.Block, tc.NewTypeEnv(env))
+	case ast.TestStmt:
+		a.block(n.Block, tc.NewTypeEnv(env))
+	case ast.SwitchStmt:
+		// cases listing several tests share their block
+		for i, c := range n.Cases {
+			if i == 0 || c.StartPos != n.Cases[i-1].StartPos {
+				a.block(c.Consequent, tc.NewTypeEnv(env))
+			}
+		}
+	case ast.ForStmt:
+		scope := tc.NewTypeEnv(env)
+		t, _ := tc.CheckType(n.Init, scope)
+		a.loopVariable(n.Variable, t, n.StartPos, n.StartPos, n.EndPos, scope)
+		a.block(n.Block, scope)
+	case ast.ForeachStmt:
+		scope := tc.NewTypeEnv(env)
+		// a map gives its keys and their values, an array its elements and their indexes
+		var variable, index ast.Type = nil, ast.IntegerType{Kind: ast.T_INTEGER32, BitSize: 32, IsSigned: true}
+		iterable, _ := tc.CheckType(n.Iterable, env)
+		if t, ok := iterable.(ast.MapType); ok {
+			variable, index = t.KeyType, t.ValueType
+		}
+		_, end := a.loopVariable(n.Variable, variable, n.StartPos, n.StartPos, n.EndPos, scope)
+		if n.IndexVariable != "" {
+			a.loopVariable(n.IndexVariable, index, end, n.StartPos, n.EndPos, scope)
+		}
+		a.block(n.Block, scope)
+	case ast.MatchExpr:
+		for _, arm := range n.Arms {
+			scope := tc.NewTypeEnv(env)
+			a.bindings(arm.Pattern, arm.StartPos, arm.EndPos, scope)
+			if body, ok := arm.Body.(ast.BlockStmt); ok {
+				a.block(body, scope)
+			} else {
+				a.lambdas(arm.Body, scope)
+			}
+		}
+	default:
+		a.lambdas(node, env)
+	}
+}
+
+// bindings declares the names a match pattern binds for its arm. A bare name
+// is a binding unless it is a variant, the checker knows the types.
+func (a *analysis) bindings(pattern ast.Node, scopeStart, scopeEnd lexer.Position, env *tc.TypeEnv) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(ast.IdentifierExpr); ok && ident.Identifier != "_" && !a.isVariant(ident.Identifier) {
+			a.loopVariable(ident.Identifier, nil, ident.StartPos, scopeStart, scopeEnd, env)
+		}
+		return true
+	})
+}
+
+func (a *analysis) isVariant(name string) bool {
+	for _, s := range a.symbols {
+		if s.kind == variantSymbol && s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *analysis) block(block ast.BlockStmt, env *tc.TypeEnv) {
+	a.statements(block.Items, env, block.StartPos, block.EndPos)
+}
+
+// loopVariable declares the variable of a loop, the parser keeps only its name
+func (a *analysis) loopVariable(name string, t ast.Type, from lexer.Position, scopeStart, scopeEnd lexer.Position, env *tc.TypeEnv) (lexer.Position, lexer.Position) {
+
+	start, end := a.nameAfter(name, from)
+
+	env.SetVar(name, t, false)
+
+	a.declare(&symbol{
+		name:       name,
+		kind:       variableSymbol,
+		t:          t,
+		detail:     "let " + name + ": " + typeString(t),
+		start:      start,
+		end:        end,
+		declStart:  start,
+		declEnd:    end,
+		scopeStart: scopeStart,
+		scopeEnd:   scopeEnd,
+	})
+
+	return start, end
+}
+
+func (a *analysis) varDecl(decl ast.VariableDclStml, env *tc.TypeEnv, scopeEnd lexer.Position) *symbol {
+
+	t := decl.ExplicitType
+
+	if t == nil && decl.Value != nil {
+		t, _ = tc.CheckType(decl.Value, env)
+	}
+
+	env.SetVar(decl.Identifier.Identifier, t, decl.IsConstant)
+
+	kind, keyword := variableSymbol, "let "
+
+	if decl.IsConstant {
+		kind, keyword = constantSymbol, "const "
+	}
+
+	s := a.declare(&symbol{
+		name:       decl.Identifier.Identifier,
+		kind:       kind,
+		t:          t,
+		detail:     keyword + decl.Identifier.Identifier + ": " + typeString(t),
+		start:      decl.Identifier.StartPos,
+		end:        decl.Identifier.EndPos,
+		declStart:  decl.StartPos,
+		declEnd:    decl.EndPos,
+		scopeStart: decl.StartPos,
+		scopeEnd:   scopeEnd,
+	})
+
+	// an anonymous function is called after the declaration, it sees the variable
+	a.lambdas(decl.Value, env)
+
+	return s
+}
+
+// function declares a function in the scope it is written in, where it can be
+// called before its declaration, and indexes its body
+func (a *analysis) function(fn ast.FunctionDeclStmt, env *tc.TypeEnv, scopeStart, scopeEnd lexer.Position) *symbol {
+
+	fnType := ast.FunctionType{
+		Kind:       ast.T_FN,
+		TypeParams: fn.TypeParams,
+		ReturnType: fn.ReturnType,
+		Parameters: fn.Parameters,
+	}
+
+	env.SetVar(fn.Name.Identifier, fnType, true)
+
+	s := a.declare(&symbol{
+		name:       fn.Name.Identifier,
+		kind:       functionSymbol,
+		t:          fnType,
+		detail:     "fn " + signature(fn.Name.Identifier+formatter.TypeParams(fn.TypeParams), fn.Parameters, fn.ReturnType),
+		start:      fn.Name.StartPos,
+		end:        fn.Name.EndPos,
+		declStart:  fn.StartPos,
+		declEnd:    fn.EndPos,
+		scopeStart: scopeStart,
+		scopeEnd:   scopeEnd,
+	})
+
+	a.body(fn.Parameters, fn.Block, env)
+
+	return s
+}
+
+// body declares the parameters of a function, method or anonymous function and indexes its block
+func (a *analysis) body(params []ast.FunctionParameter, block ast.BlockStmt, env *tc.TypeEnv) {
+
+	scope := tc.NewTypeEnv(env)
+
+	for _, param := range params {
+
+		name := param.Identifier.Identifier
+
+		scope.SetVar(name, param.Type, false)
+
+		a.declare(&symbol{
+			name:       name,
+			kind:       parameterSymbol,
+			t:          param.Type,
+			detail:     parameter(param),
+			start:      param.Identifier.StartPos,
+			end:        param.Identifier.EndPos,
+			declStart:  param.StartPos,
+			declEnd:    param.EndPos,
+			scopeStart: block.StartPos,
+			scopeEnd:   block.EndPos,
+		})
+	}
+
+	a.block(block, scope)
+}
+
+// lambdas indexes the anonymous functions written in an expression
+func (a *analysis) lambdas(node ast.Node, env *tc.TypeEnv) {
+
+	if node == nil {
+		return
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if fn, ok := n.(ast.FunctionExpr); ok {
+			a.body(fn.Parameters, fn.Block, env)
+			return false
+		}
+		return true
+	})
+}
+
+func (a *analysis) structDecl(decl ast.StructDeclStatement, scopeStart, scopeEnd lexer.Position) *symbol {
+
+	start, end := a.nameAfter(decl.StructName, decl.StartPos)
+
+	s := a.declare(&symbol{
+		name:       decl.StructName,
+		kind:       structSymbol,
+		t:          ast.StructType{Kind: ast.DATA_TYPE(decl.StructName)},
+		start:      start,
+		end:        end,
+		declStart:  decl.StartPos,
+		declEnd:    decl.EndPos,
+		scopeStart: scopeStart,
+		scopeEnd:   scopeEnd,
+		embeds:     decl.Embeds,
+	})
+
+	lines := []string{"struct " + decl.StructName + formatter.TypeParams(decl.TypeParams) + " {"}
+
+	for _, embed := range decl.Embeds {
+		lines = append(lines, formatter.INDENT+"embed "+embed+";")
+	}
+
+	for _, property := range sortedProperties(decl.Properties) {
+
+		start, end := a.nameAfter(property.Name, property.StartPos)
+
+		field := propertyString(property)
+
+		lines = append(lines, formatter.INDENT+field+";")
+
+		s.children = append(s.children, a.declare(&symbol{
+			name:      property.Name,
+			kind:      fieldSymbol,
+			t:         property.Type,
+			detail:    field + " // " + decl.StructName,
+			start:     start,
+			end:       end,
+			declStart: property.StartPos,
+			declEnd:   property.EndPos,
+			member:    true,
+		}))
+	}
+
+	s.detail = strings.Join(append(lines, "}"), "\n")
+
+	a.types[decl.StructName] = s
+
+	return s
+}
+
+func (a *analysis) traitDecl(decl ast.TraitDeclStatement, scopeStart, scopeEnd lexer.Position) *symbol {
+
+	start, end := a.nameAfter(decl.TraitName, decl.StartPos)
+
+	s := a.declare(&symbol{
+		name:       decl.TraitName,
+		kind:       traitSymbol,
+		t:          ast.StructType{Kind: ast.DATA_TYPE(decl.TraitName)},
+		start:      start,
+		end:        end,
+		declStart:  decl.StartPos,
+		declEnd:    decl.EndPos,
+		scopeStart: scopeStart,
+		scopeEnd:   scopeEnd,
+	})
+
+	names := make([]string, 0, len(decl.Methods))
+
+	for name := range decl.Methods {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return decl.Methods[names[i]].StartPos.Index < decl.Methods[names[j]].StartPos.Index
+	})
+
+	lines := []string{"trait " + decl.TraitName + formatter.TypeParams(decl.TypeParams) + " {"}
+
+	for _, name := range names {
+
+		method := decl.Methods[name]
+
+		start, end := a.nameAfter(name, method.StartPos)
+
+		prototype := modifiers(method.IsPublic, method.IsStatic) + "fn " + signature(name+formatter.TypeParams(method.TypeParams), method.Parameters, method.ReturnType)
+
+		lines = append(lines, formatter.INDENT+prototype+";")
+
+		s.children = append(s.children, a.declare(&symbol{
+			name:      name,
+			kind:      methodSymbol,
+			t:         method.FunctionType,
+			detail:    prototype + " // " + decl.TraitName,
+			start:     start,
+			end:       end,
+			declStart: method.StartPos,
+			declEnd:   method.EndPos,
+			member:    true,
+		}))
+	}
+
+	s.detail = strings.Join(append(lines, "}"), "\n")
+
+	a.types[decl.TraitName] = s
+
+	return s
+}
+
+func (a *analysis) enumDecl(decl ast.EnumDeclStatement, env *tc.TypeEnv, scopeStart, scopeEnd lexer.Position) *symbol {
+
+	// the checker declares the enum for the values built from its variants
+	tc.CheckType(decl, env)
+
+	start, end := a.nameAfter(decl.EnumName, decl.StartPos)
+
+	s := a.declare(&symbol{
+		name:       decl.EnumName,
+		kind:       enumSymbol,
+		t:          ast.EnumType{Kind: ast.DATA_TYPE(decl.EnumName), TypeParams: decl.TypeParams, Variants: decl.Variants},
+		start:      start,
+		end:        end,
+		declStart:  decl.StartPos,
+		declEnd:    decl.EndPos,
+		scopeStart: scopeStart,
+		scopeEnd:   scopeEnd,
+	})
+
+	lines := []string{"enum " + decl.EnumName + formatter.TypeParams(decl.TypeParams) + " {"}
+
+	for _, variant := range decl.Variants {
+
+		start, end := a.nameAfter(variant.Name, variant.StartPos)
+
+		fields := make([]string, len(variant.Fields))
+
+		for i, field := range variant.Fields {
+			fields[i] = field.Name + ": " + typeString(field.Type)
+		}
+
+		text := variant.Name
+
+		if len(fields) > 0 {
+			text += "(" + strings.Join(fields, ", ") + ")"
+		}
+
+		lines = append(lines, formatter.INDENT+text+",")
+
+		// the variants are reached through the enum, Shape.Circle
+		s.children = append(s.children, a.declare(&symbol{
+			name:      variant.Name,
+			kind:      variantSymbol,
+			t:         ast.StructType{Kind: ast.DATA_TYPE(decl.EnumName)},
+			detail:    text + " // " + decl.EnumName,
+			start:     start,
+			end:       end,
+			declStart: variant.StartPos,
+			declEnd:   variant.EndPos,
+			member:    true,
+		}))
+	}
+
+	s.detail = strings.Join(append(lines, "}"), "\n")
+
+	return s
+}
+
+func (a *analysis) implement(stmt ast.ImplementStatement, env *tc.TypeEnv) *symbol {
+
+	name := "impl " + stmt.Impliments
+
+	if len(stmt.Traits) != 1 || stmt.Traits[0] != stmt.Impliments {
+		name = "impl " + strings.Join(stmt.Traits, ", ") + " for " + stmt.Impliments + formatter.TypeParams(stmt.TypeParams)
+	}
+
+	start, end := a.nameAfter(stmt.Impliments, stmt.StartPos)
+
+	// the block itself can not be named, it only shows in the outline
+	s := &symbol{
+		name:      name,
+		kind:      implSymbol,
+		detail:    name,
+		start:     start,
+		end:       end,
+		declStart: stmt.StartPos,
+		declEnd:   stmt.EndPos,
+	}
+
+	methods := make([]ast.MethodImplementStmt, 0, len(stmt.Methods))
+
+	for _, method := range stmt.Methods {
+		methods = append(methods, method)
+	}
+
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].StartPos.Index < methods[j].StartPos.Index
+	})
+
+	for _, method := range methods {
+
+		fnType := ast.FunctionType{
+			Kind:       ast.T_FN,
+			TypeParams: method.TypeParams,
+			ReturnType: method.ReturnType,
+			Parameters: method.Parameters,
+		}
+
+		m := a.declare(&symbol{
+			name:      method.Name.Identifier,
+			kind:      methodSymbol,
+			t:         fnType,
+			detail:    modifiers(method.IsPublic, method.IsStatic) + "fn " + signature(method.Name.Identifier+formatter.TypeParams(method.TypeParams), method.Parameters, method.ReturnType) + " // " + stmt.Impliments,
+			start:     method.Name.StartPos,
+			end:       method.Name.EndPos,
+			declStart: method.StartPos,
+			declEnd:   method.EndPos,
+			member:    true,
+		})
+
+		s.children = append(s.children, m)
+		a.methods[stmt.Impliments] = append(a.methods[stmt.Impliments], m)
+
+		a.body(method.Parameters, method.Block, env)
+	}
+
+	return s
+}
+
+// sortedFields orders the fields of a native struct by name, they have no position
+func sortedFields(fields map[string]ast.Property) []ast.Property {
+
+	sorted := make([]ast.Property, 0, len(fields))
+
+	for _, field := range fields {
+		sorted = append(sorted, field)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
+func sortedProperties(properties map[string]ast.Property) []ast.Property {
+
+	sorted := make([]ast.Property, 0, len(properties))
+
+	for _, property := range properties {
+		sorted = append(sorted, property)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartPos.Index < sorted[j].StartPos.Index
+	})
+
+	return sorted
+}
+
+// typeString names a type for hovers and completions
+func typeString(t ast.Type) string {
+	switch t := t.(type) {
+	case nil:
+		return "unknown"
+	case ast.FunctionType:
+		return "fn" + signature("", t.Parameters, t.ReturnType)
+	case ast.ModuleType:
+		return "module " + t.Name
+	default:
+		return formatter.TypeName(t)
+	}
+}
+
+// signature prints name(a: T, b: T) -> R the way it is declared
+func signature(name string, params []ast.FunctionParameter, returnType ast.Type) string {
+
+	parts := make([]string, len(params))
+
+	for i, param := range params {
+		parts[i] = parameter(param)
+	}
+
+	text := name + "(" + strings.Join(parts, ", ") + ")"
+
+	if _, isVoid := returnType.(ast.VoidType); returnType != nil && !isVoid {
+		text += " -> " + typeString(returnType)
+	}
+
+	return text
+}
+
+func parameter(param ast.FunctionParameter) string {
+
+	text := typeString(param.Type)
+
+	if param.IsVariadic {
+		text = "..." + text
+	}
+
+	// the parameters of a function type have no names
+	if param.Identifier.Identifier != "" {
+		text = param.Identifier.Identifier + ": " + text
+	}
+
+	if param.DefaultVal != nil {
+		text += " = " + formatter.Expr(param.DefaultVal)
+	}
+
+	return text
+}
+
+func propertyString(property ast.Property) string {
+
+	text := "priv "
+
+	if property.IsPublic {
+		text = "pub "
+	}
+
+	if property.IsStatic {
+		text += "static "
+	}
+
+	if property.ReadOnly {
+		text += "readonly "
+	}
+
+	return text + property.Name + ": " + typeString(property.Type)
+}
+
+func modifiers(isPublic bool, isStatic bool) string {
+
+	text := ""
+
+	if isPublic {
+		text = "pub "
+	}
+
+	if isStatic {
+		text += "static "
+	}
+
+	return text
+}
@@ -0,0 +1,82 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+
+	"walrus/framing"
+)
+
+// JSON-RPC error codes used by the server
+const (
+	parseError     = -32700
+	invalidParams  = -32602
+	methodNotFound = -32601
+	invalidRequest = -32600
+	requestFailed  = -32803
+)
+
+// message is a request or a notification, only requests carry an id
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// response always writes its result, null is a valid result
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+// conn reads and writes JSON-RPC messages framed by a Content-Length header
+type conn struct {
+	*framing.Conn
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{framing.NewConn(in, out)}
+}
+
+func (c *conn) write(msg interface{}) error {
+
+	body, err := json.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Write(body)
+}
+
+func (c *conn) reply(id *json.RawMessage, result interface{}) error {
+	return c.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (c *conn) replyError(id *json.RawMessage, code int, text string) error {
+	return c.write(errorResponse{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (c *conn) notify(method string, params interface{}) error {
+
+	raw, err := json.Marshal(params)
+
+	if err != nil {
+		return err
+	}
+
+	return c.write(message{JSONRPC: "2.0", Method: method, Params: raw})
+}
@@ -0,0 +1,326 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+)
+
+// toPosition turns a position of the lexer, counted in characters from one,
+// into a protocol position counted in UTF-16 code units from zero
+func toPosition(lines []string, pos lexer.Position) Position {
+
+	line := pos.Line - 1
+
+	if line < 0 {
+		return Position{}
+	}
+
+	if line >= len(lines) {
+		return Position{Line: line}
+	}
+
+	character := 0
+	column := 1
+
+	for _, r := range lines[line] {
+		if column >= pos.Column {
+			break
+		}
+		character += len(utf16.Encode([]rune{r}))
+		column++
+	}
+
+	// the lexer may end a token past the last character, like the end of the file
+	character += pos.Column - column
+
+	return Position{Line: line, Character: character}
+}
+
+// toColumn turns the UTF-16 offset of a protocol position into a byte offset of the line
+func toColumn(line string, character int) int {
+
+	units := 0
+
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+
+	return len(line)
+}
+
+// fromPosition turns a protocol position into a lexer position of the document
+func fromPosition(lines []string, pos Position) lexer.Position {
+
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return lexer.Position{Line: pos.Line + 1, Column: 1}
+	}
+
+	offset := toColumn(lines[pos.Line], pos.Character)
+
+	return lexer.Position{Line: pos.Line + 1, Column: utf8.RuneCountInString(lines[pos.Line][:offset]) + 1}
+}
+
+func utf16Units(text string) []uint16 {
+	return utf16.Encode([]rune(text))
+}
+
+func toRange(lines []string, start lexer.Position, end lexer.Position) Range {
+	return Range{toPosition(lines, start), toPosition(lines, end)}
+}
+
+func before(a lexer.Position, b lexer.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func within(pos lexer.Position, start lexer.Position, end lexer.Position) bool {
+	return !before(pos, start) && !before(end, pos)
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// reference is the identifier under the cursor with the member chain leading to it,
+// a.b.c with the cursor on c gives the chain a, b, c
+type reference struct {
+	chain []string
+	// the part of the last name before the cursor
+	prefix string
+	// range of the last name, which is empty right after a dot
+	rng Range
+	// the chain starts with something other than a name, like a call: a().b
+	unresolved bool
+}
+
+// referenceAt reads the reference at a position from the text, which does
+// not need to parse so completion works while typing
+func referenceAt(lines []string, pos Position) (reference, bool) {
+
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return reference{}, false
+	}
+
+	line := lines[pos.Line]
+	cursor := toColumn(line, pos.Character)
+
+	start, end := cursor, cursor
+
+	for start > 0 && isIdentifierByte(line[start-1]) {
+		start--
+	}
+
+	for end < len(line) && isIdentifierByte(line[end]) {
+		end++
+	}
+
+	word := line[start:end]
+
+	if word != "" && word[0] >= '0' && word[0] <= '9' {
+		return reference{}, false
+	}
+
+	ref := reference{
+		chain:  []string{word},
+		prefix: line[start:cursor],
+		rng: Range{
+			Position{pos.Line, len(utf16Units(line[:start]))},
+			Position{pos.Line, len(utf16Units(line[:end]))},
+		},
+	}
+
+	// walk back over .name pieces, .. is the range operator
+	for i := start; i > 0 && line[i-1] == '.' && (i < 2 || line[i-2] != '.'); {
+
+		j := i - 1
+
+		for j > 0 && line[j-1] == ' ' {
+			j--
+		}
+
+		k := j
+
+		for k > 0 && isIdentifierByte(line[k-1]) {
+			k--
+		}
+
+		if k == j {
+			ref.unresolved = true
+			break
+		}
+
+		ref.chain = append([]string{line[k:j]}, ref.chain...)
+
+		i = k
+	}
+
+	return ref, word != "" || len(ref.chain) > 1
+}
+
+// lookup finds the declaration a name refers to at a position. The innermost
+// one wins, a later declaration shadows an earlier one of the same scope.
+func (a *analysis) lookup(name string, pos lexer.Position) *symbol {
+
+	var found, fallback *symbol
+
+	for _, s := range a.symbols {
+
+		if s.member || s.name != name {
+			continue
+		}
+
+		if fallback == nil {
+			fallback = s
+		}
+
+		if !within(pos, s.scopeStart, s.scopeEnd) {
+			continue
+		}
+
+		if found == nil || before(found.scopeStart, s.scopeStart) ||
+			(found.scopeStart == s.scopeStart && !before(s.declStart, found.declStart)) {
+			found = s
+		}
+	}
+
+	if found != nil {
+		return found
+	}
+
+	if builtin, ok := a.builtins[name]; ok {
+		return builtin
+	}
+
+	// a global used in a function declared before it
+	return fallback
+}
+
+// resolve follows a member chain from the name it starts with
+func (a *analysis) resolve(ref reference, pos lexer.Position) *symbol {
+
+	if ref.unresolved {
+		return nil
+	}
+
+	s := a.lookup(ref.chain[0], pos)
+
+	for _, name := range ref.chain[1:] {
+
+		if s == nil {
+			return nil
+		}
+
+		var next *symbol
+
+		for _, member := range a.members(s) {
+			if member.name == name {
+				next = member
+				break
+			}
+		}
+
+		s = next
+	}
+
+	return s
+}
+
+// members lists what can follow a symbol after a dot: the fields and
+// methods of its struct or trait, or the members of a module
+func (a *analysis) members(s *symbol) []*symbol {
+
+	switch s.kind {
+	case structSymbol, traitSymbol:
+		return a.typeMembers(s.name, map[string]bool{})
+	case enumSymbol:
+		return s.children
+	}
+
+	switch t := s.t.(type) {
+	case ast.StructType:
+		return a.typeMembers(string(t.Kind), map[string]bool{})
+	case ast.ModuleType:
+		names := make([]string, 0, len(t.Members))
+		for name := range t.Members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		members := make([]*symbol, len(names))
+		for i, name := range names {
+			member := t.Members[name]
+			kind, detail := fieldSymbol, name+": "+typeString(member)
+			if fn, ok := member.(ast.FunctionType); ok {
+				kind, detail = functionSymbol, "fn "+signature(name, fn.Parameters, fn.ReturnType)
+			}
+			members[i] = &symbol{name: name, kind: kind, t: member, detail: detail + " // " + t.Name, member: true, builtin: true}
+		}
+		return members
+	}
+
+	return nil
+}
+
+// typeMembers are the fields of a struct with those of the structs it embeds,
+// followed by the methods implemented for it
+func (a *analysis) typeMembers(name string, seen map[string]bool) []*symbol {
+
+	if seen[name] {
+		return nil
+	}
+
+	seen[name] = true
+
+	decl, ok := a.types[name]
+
+	if !ok {
+		return nil
+	}
+
+	members := append([]*symbol{}, decl.children...)
+
+	for _, embed := range decl.embeds {
+		members = append(members, a.typeMembers(embed, seen)...)
+	}
+
+	return append(members, a.methods[name]...)
+}
+
+// visible lists the names usable at a position, the innermost declaration of each
+func (a *analysis) visible(pos lexer.Position) []*symbol {
+
+	names := map[string]bool{}
+
+	for _, s := range a.symbols {
+		if !s.member && within(pos, s.scopeStart, s.scopeEnd) {
+			names[s.name] = true
+		}
+	}
+
+	for name := range a.builtins {
+		names[name] = true
+	}
+
+	symbols := make([]*symbol, 0, len(names))
+
+	for name := range names {
+		symbols = append(symbols, a.lookup(name, pos))
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].name < symbols[j].name
+	})
+
+	return symbols
+}
+
+// hasPrefix matches completions without regard to case, like most editors do
+func hasPrefix(name string, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))
+}
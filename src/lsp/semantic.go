@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"encoding/json"
+
+	"walrus/frontend/lexer"
+)
+
+// the token types and modifiers of the legend, tokens refer to them by index
+var semanticTokenTypes = []string{"keyword", "type", "struct", "interface", "function", "parameter", "variable", "number", "string", "comment", "enum", "enumMember"}
+var semanticTokenModifiers = []string{"readonly"}
+
+func semanticLegend() map[string]interface{} {
+	return map[string]interface{}{
+		"tokenTypes":     semanticTokenTypes,
+		"tokenModifiers": semanticTokenModifiers,
+	}
+}
+
+func tokenType(name string) int {
+	for i, t := range semanticTokenTypes {
+		if t == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// semanticResolver classifies the identifiers of a document by the
+// declarations they resolve to in its last analysis
+func (a *analysis) semanticResolver(tokens []lexer.Token) lexer.Resolver {
+	return func(i int, token lexer.Token) (lexer.SEMANTIC_KIND, bool) {
+
+		chain := []string{token.Value}
+
+		j := i
+
+		for j >= 2 && tokens[j-1].Kind == lexer.DOT_TOKEN && tokens[j-2].Kind == lexer.IDENTIFIER_TOKEN {
+			chain = append([]string{tokens[j-2].Value}, chain...)
+			j -= 2
+		}
+
+		// a member of something other than a name, like a call
+		if j >= 1 && tokens[j-1].Kind == lexer.DOT_TOKEN {
+			return "", false
+		}
+
+		s := a.resolve(reference{chain: chain}, token.StartPos)
+
+		if s == nil {
+			return "", false
+		}
+
+		switch s.kind {
+		case functionSymbol, methodSymbol:
+			return lexer.SEMANTIC_FUNCTION, true
+		case structSymbol:
+			return lexer.SEMANTIC_STRUCT, true
+		case traitSymbol:
+			return lexer.SEMANTIC_TRAIT, true
+		case enumSymbol:
+			return lexer.SEMANTIC_ENUM, true
+		case variantSymbol:
+			return lexer.SEMANTIC_VARIANT, true
+		case parameterSymbol:
+			return lexer.SEMANTIC_PARAMETER, true
+		case constantSymbol:
+			return lexer.SEMANTIC_CONSTANT, true
+		}
+
+		return "", false
+	}
+}
+
+func (s *Server) semanticTokens(params json.RawMessage) (interface{}, error) {
+
+	var p SemanticTokensParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := []int{}
+
+	tokens, _, err := lexer.Tokenize(doc.text, documentName(doc.uri), false)
+
+	// a document which does not lex keeps the colours the editor has
+	if err != nil {
+		return SemanticTokens{Data: data}, nil
+	}
+
+	var resolve lexer.Resolver
+
+	if doc.analysis != nil {
+		resolve = doc.analysis.semanticResolver(tokens)
+	}
+
+	line, character := 0, 0
+
+	for _, token := range lexer.Classify(tokens, resolve) {
+
+		typeIndex, modifiers := semanticType(doc.lines, token), 0
+
+		if token.Kind == lexer.SEMANTIC_CONSTANT {
+			modifiers = 1
+		}
+
+		// tokens like block comments span lines, editors take one piece per line
+		for _, piece := range splitRange(doc.lines, toRange(doc.lines, token.StartPos, token.EndPos)) {
+
+			if piece.Start.Line != line {
+				character = 0
+			}
+
+			length := piece.End.Character - piece.Start.Character
+
+			if length <= 0 {
+				continue
+			}
+
+			data = append(data, piece.Start.Line-line, piece.Start.Character-character, length, typeIndex, modifiers)
+
+			line, character = piece.Start.Line, piece.Start.Character
+		}
+	}
+
+	return SemanticTokens{Data: data}, nil
+}
+
+// semanticType maps a semantic kind to the legend, literals are told apart by their first character
+func semanticType(lines []string, token lexer.SemanticToken) int {
+
+	switch token.Kind {
+	case lexer.SEMANTIC_TRAIT:
+		return tokenType("interface")
+	case lexer.SEMANTIC_CONSTANT:
+		return tokenType("variable")
+	case lexer.SEMANTIC_LITERAL:
+		start := toPosition(lines, token.StartPos)
+		text := lines[start.Line][toColumn(lines[start.Line], start.Character):]
+		switch {
+		case text == "":
+			return tokenType("keyword")
+		case text[0] == '"' || text[0] == '\'':
+			return tokenType("string")
+		case text[0] >= '0' && text[0] <= '9':
+			return tokenType("number")
+		}
+		// true, false and null
+		return tokenType("keyword")
+	}
+
+	return tokenType(string(token.Kind))
+}
+
+// splitRange cuts a range into one range per line
+func splitRange(lines []string, rng Range) []Range {
+
+	if rng.Start.Line == rng.End.Line {
+		return []Range{rng}
+	}
+
+	var pieces []Range
+
+	for line := rng.Start.Line; line <= rng.End.Line && line < len(lines); line++ {
+
+		start, end := 0, len(utf16Units(lines[line]))
+
+		if line == rng.Start.Line {
+			start = rng.Start.Character
+		}
+
+		if line == rng.End.Line {
+			end = rng.End.Character
+		}
+
+		pieces = append(pieces, Range{Position{line, start}, Position{line, end}})
+	}
+
+	return pieces
+}
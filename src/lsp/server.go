@@ -0,0 +1,536 @@
+// Package lsp implements a language server for walrus over stdio.
+// Documents are analyzed from the text the editor sends, so unsaved changes
+// are diagnosed, and the last analysis which parsed keeps serving hovers and
+// completions while the text is broken mid-edit.
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"walrus/formatter"
+	"walrus/frontend/lexer"
+)
+
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+	// the analysis of the last version which parsed, nil until one did
+	analysis *analysis
+}
+
+type Server struct {
+	conn      *conn
+	documents map[string]*document
+	log       io.Writer
+	shutdown  bool
+}
+
+// NewServer creates a server reading requests from in and writing to out,
+// errors of the connection go to log
+func NewServer(in io.Reader, out io.Writer, log io.Writer) *Server {
+	return &Server{
+		conn:      newConn(in, out),
+		documents: map[string]*document{},
+		log:       log,
+	}
+}
+
+// Run serves until the exit notification or the end of the input. It returns
+// an error when the client exits without asking the server to shut down first.
+func (s *Server) Run() error {
+
+	for {
+
+		body, err := s.conn.Read()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var msg message
+
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.conn.replyError(nil, parseError, err.Error())
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+
+		s.handle(msg)
+	}
+}
+
+func (s *Server) handle(msg message) {
+
+	// a handler failing on some input should not take the server down
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Fprintf(s.log, "walrus lsp: %s: %v\n", msg.Method, recovered)
+			if msg.ID != nil {
+				s.conn.replyError(msg.ID, requestFailed, fmt.Sprint(recovered))
+			}
+		}
+	}()
+
+	handler, ok := handlers[msg.Method]
+
+	if !ok {
+		// unknown notifications are ignored, unknown requests need an answer
+		if msg.ID != nil {
+			s.conn.replyError(msg.ID, methodNotFound, "method not supported: "+msg.Method)
+		}
+		return
+	}
+
+	if s.shutdown && msg.ID != nil {
+		s.conn.replyError(msg.ID, invalidRequest, "the server is shutting down")
+		return
+	}
+
+	result, err := handler(s, msg.Params)
+
+	if msg.ID == nil {
+		if err != nil {
+			fmt.Fprintf(s.log, "walrus lsp: %s: %v\n", msg.Method, err)
+		}
+		return
+	}
+
+	if err != nil {
+		s.conn.replyError(msg.ID, invalidParams, err.Error())
+		return
+	}
+
+	s.conn.reply(msg.ID, result)
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":                       (*Server).initialize,
+		"initialized":                      ignore,
+		"shutdown":                         (*Server).shutdownRequest,
+		"$/cancelRequest":                  ignore,
+		"textDocument/didOpen":             (*Server).didOpen,
+		"textDocument/didChange":           (*Server).didChange,
+		"textDocument/didSave":             ignore,
+		"textDocument/didClose":            (*Server).didClose,
+		"textDocument/hover":               (*Server).hover,
+		"textDocument/definition":          (*Server).definition,
+		"textDocument/completion":          (*Server).completion,
+		"textDocument/documentSymbol":      (*Server).documentSymbol,
+		"textDocument/formatting":          (*Server).formatting,
+		"textDocument/semanticTokens/full": (*Server).semanticTokens,
+	}
+}
+
+func ignore(s *Server, params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			// the whole text is sent on every change
+			"textDocumentSync":           1,
+			"hoverProvider":              true,
+			"definitionProvider":         true,
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"."},
+			},
+			"semanticTokensProvider": map[string]interface{}{
+				"legend": semanticLegend(),
+				"full":   true,
+			},
+		},
+		"serverInfo": map[string]string{
+			"name": "walrus",
+		},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+
+	var p DidOpenTextDocumentParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc := &document{uri: p.TextDocument.URI}
+	s.documents[doc.uri] = doc
+
+	s.update(doc, p.TextDocument.Version, p.TextDocument.Text)
+
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+
+	var p DidChangeTextDocumentParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	// with full synchronization the last change holds the whole text
+	s.update(doc, p.TextDocument.Version, p.ContentChanges[len(p.ContentChanges)-1].Text)
+
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+
+	var p DidCloseTextDocumentParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.documents, p.TextDocument.URI)
+
+	// the diagnostics of a closed document are cleared
+	return nil, s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// update analyzes a new version of a document and publishes its diagnostics
+func (s *Server) update(doc *document, version int, text string) {
+
+	doc.version = version
+	doc.text = text
+	doc.lines = strings.Split(text, "\n")
+
+	a, diagnostics := analyze(documentName(doc.uri), text)
+
+	if a != nil {
+		doc.analysis = a
+	}
+
+	s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     &doc.version,
+		Diagnostics: diagnostics,
+	})
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("document not open: %s", uri)
+	}
+	return doc, nil
+}
+
+// positionParams decodes the document and position of a request
+func (s *Server) positionParams(params json.RawMessage) (*document, Position, error) {
+
+	var p TextDocumentPositionParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, Position{}, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+
+	return doc, p.Position, err
+}
+
+// symbolAt resolves the reference under the cursor with the last analysis of the document
+func (doc *document) symbolAt(pos Position) (*symbol, reference) {
+
+	ref, ok := referenceAt(doc.lines, pos)
+
+	if !ok || doc.analysis == nil || ref.chain[len(ref.chain)-1] == "" {
+		return nil, ref
+	}
+
+	return doc.analysis.resolve(ref, fromPosition(doc.lines, pos)), ref
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+
+	doc, pos, err := s.positionParams(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sym, ref := doc.symbolAt(pos)
+
+	if sym == nil {
+		return nil, nil
+	}
+
+	return Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: "```walrus\n" + sym.detail + "\n```",
+		},
+		Range: &ref.rng,
+	}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+
+	doc, pos, err := s.positionParams(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sym, _ := doc.symbolAt(pos)
+
+	if sym == nil || sym.builtin {
+		return nil, nil
+	}
+
+	return Location{
+		URI:   doc.uri,
+		Range: toRange(doc.lines, sym.start, sym.end),
+	}, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+
+	doc, pos, err := s.positionParams(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := []CompletionItem{}
+
+	ref, ok := referenceAt(doc.lines, pos)
+
+	if !ok {
+		ref = reference{chain: []string{""}}
+	}
+
+	a := doc.analysis
+	at := fromPosition(doc.lines, pos)
+
+	// after a dot only the members of what is before it can follow
+	if len(ref.chain) > 1 {
+
+		if a == nil {
+			return items, nil
+		}
+
+		object := a.resolve(reference{chain: ref.chain[:len(ref.chain)-1], unresolved: ref.unresolved}, at)
+
+		if object == nil {
+			return items, nil
+		}
+
+		for _, member := range a.members(object) {
+			if hasPrefix(member.name, ref.prefix) {
+				items = append(items, completionItem(member))
+			}
+		}
+
+		return items, nil
+	}
+
+	if ref.unresolved {
+		return items, nil
+	}
+
+	if a != nil {
+		for _, sym := range a.visible(at) {
+			if hasPrefix(sym.name, ref.prefix) {
+				items = append(items, completionItem(sym))
+			}
+		}
+	} else {
+		for _, sym := range builtinSymbols() {
+			if hasPrefix(sym.name, ref.prefix) {
+				items = append(items, completionItem(sym))
+			}
+		}
+	}
+
+	for _, keyword := range lexer.Keywords() {
+		if hasPrefix(keyword, ref.prefix) {
+			items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+		}
+	}
+
+	return items, nil
+}
+
+func completionItem(sym *symbol) CompletionItem {
+
+	kinds := map[symbolKind]CompletionItemKind{
+		variableSymbol:  CompletionVariable,
+		constantSymbol:  CompletionConstant,
+		parameterSymbol: CompletionVariable,
+		functionSymbol:  CompletionFunction,
+		structSymbol:    CompletionStruct,
+		traitSymbol:     CompletionClass,
+		fieldSymbol:     CompletionField,
+		methodSymbol:    CompletionMethod,
+		importSymbol:    CompletionModule,
+		enumSymbol:      CompletionEnum,
+		variantSymbol:   CompletionVariant,
+	}
+
+	detail := sym.detail
+
+	// the whole declaration of a struct, trait or enum is too long for a completion
+	if sym.kind == structSymbol || sym.kind == traitSymbol || sym.kind == enumSymbol {
+		detail = strings.SplitN(detail, " {", 2)[0]
+	}
+
+	return CompletionItem{Label: sym.name, Kind: kinds[sym.kind], Detail: detail}
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+
+	var p DocumentSymbolParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := []DocumentSymbol{}
+
+	if doc.analysis == nil {
+		return symbols, nil
+	}
+
+	for _, sym := range doc.analysis.roots {
+		symbols = append(symbols, documentSymbol(doc.lines, sym))
+	}
+
+	return symbols, nil
+}
+
+func documentSymbol(lines []string, sym *symbol) DocumentSymbol {
+
+	kinds := map[symbolKind]SymbolKind{
+		variableSymbol: SymbolVariable,
+		constantSymbol: SymbolConstant,
+		functionSymbol: SymbolFunction,
+		structSymbol:   SymbolStruct,
+		traitSymbol:    SymbolInterface,
+		fieldSymbol:    SymbolField,
+		methodSymbol:   SymbolMethod,
+		importSymbol:   SymbolModule,
+		implSymbol:     SymbolNamespace,
+		enumSymbol:     SymbolEnum,
+		variantSymbol:  SymbolVariant,
+	}
+
+	detail := ""
+
+	if sym.kind != structSymbol && sym.kind != traitSymbol && sym.kind != implSymbol && sym.kind != enumSymbol {
+		detail = typeString(sym.t)
+	}
+
+	result := DocumentSymbol{
+		Name:           sym.name,
+		Detail:         detail,
+		Kind:           kinds[sym.kind],
+		Range:          toRange(lines, sym.declStart, sym.declEnd),
+		SelectionRange: toRange(lines, sym.start, sym.end),
+	}
+
+	for _, child := range sym.children {
+		result.Children = append(result.Children, documentSymbol(lines, child))
+	}
+
+	return result
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+
+	var p DocumentFormattingParams
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := formatter.Format(documentName(doc.uri), doc.text)
+
+	// a document which does not parse is left as it is, its diagnostics tell why
+	if err != nil {
+		return nil, nil
+	}
+
+	if formatted == doc.text {
+		return []TextEdit{}, nil
+	}
+
+	last := len(doc.lines) - 1
+
+	return []TextEdit{{
+		Range: Range{
+			End: Position{Line: last, Character: len(utf16Units(doc.lines[last]))},
+		},
+		NewText: formatted,
+	}}, nil
+}
+
+// documentName is the path of a file URI, other URIs like those of unsaved
+// buffers are used as they are
+func documentName(uri string) string {
+
+	u, err := url.Parse(uri)
+
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(u.Path)
+}
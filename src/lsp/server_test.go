@@ -0,0 +1,310 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"walrus/framing/framingtest"
+	"walrus/lsp"
+)
+
+// client speaks JSON-RPC to a server, it keeps the notifications read while
+// waiting for responses
+type client struct {
+	*framingtest.Client
+	t *testing.T
+	// the notifications read while waiting for responses, oldest first
+	notifications []map[string]json.RawMessage
+	nextID        int
+}
+
+func start(t *testing.T) *client {
+
+	t.Helper()
+
+	return &client{
+		Client: framingtest.Start(t, func(in io.Reader, out io.Writer) error {
+			return lsp.NewServer(in, out, io.Discard).Run()
+		}),
+		t: t,
+	}
+}
+
+func (c *client) send(msg map[string]interface{}) {
+	c.t.Helper()
+	msg["jsonrpc"] = "2.0"
+	c.Send(msg)
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// next returns the next message of the server
+func (c *client) next() map[string]json.RawMessage {
+	c.t.Helper()
+	var msg map[string]json.RawMessage
+	c.Next(&msg)
+	return msg
+}
+
+// request sends a request and decodes the result of its response into result
+func (c *client) request(method string, params interface{}, result interface{}) {
+
+	c.t.Helper()
+
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+
+	c.send(map[string]interface{}{"id": c.nextID, "method": method, "params": params})
+
+	for {
+		msg := c.next()
+
+		if _, ok := msg["method"]; ok {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+
+		if string(msg["id"]) != id {
+			c.t.Fatalf("%s: response to request %s, want %s", method, msg["id"], id)
+		}
+
+		if msg["error"] != nil {
+			c.t.Fatalf("%s: %s", method, msg["error"])
+		}
+
+		if err := json.Unmarshal(msg["result"], result); err != nil {
+			c.t.Fatalf("%s: %v in %s", method, err, msg["result"])
+		}
+
+		return
+	}
+}
+
+// diagnostics waits for the next diagnostics the server publishes
+func (c *client) diagnostics() lsp.PublishDiagnosticsParams {
+
+	c.t.Helper()
+
+	for {
+		var msg map[string]json.RawMessage
+
+		if len(c.notifications) > 0 {
+			msg, c.notifications = c.notifications[0], c.notifications[1:]
+		} else {
+			msg = c.next()
+		}
+
+		if string(msg["method"]) != `"textDocument/publishDiagnostics"` {
+			continue
+		}
+
+		var params lsp.PublishDiagnosticsParams
+		json.Unmarshal(msg["params"], &params)
+
+		return params
+	}
+}
+
+func position(uri string, line int, character int) lsp.TextDocumentPositionParams {
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: line, Character: character},
+	}
+}
+
+func labels(items []lsp.CompletionItem) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Label
+	}
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// semanticTypes returns the token types of the legend the server offers
+func semanticTypes(t *testing.T, capabilities map[string]interface{}) []string {
+
+	t.Helper()
+
+	var provider struct {
+		Legend struct {
+			TokenTypes []string `json:"tokenTypes"`
+		} `json:"legend"`
+	}
+
+	data, _ := json.Marshal(capabilities["semanticTokensProvider"])
+
+	if err := json.Unmarshal(data, &provider); err != nil || len(provider.Legend.TokenTypes) == 0 {
+		t.Fatalf("capabilities %v offer no semantic tokens", capabilities)
+	}
+
+	return provider.Legend.TokenTypes
+}
+
+// decodeTokens returns the type of each semantic token by its line and character
+func decodeTokens(data []int) map[[2]int]int {
+
+	tokens := map[[2]int]int{}
+	line, character := 0, 0
+
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			character = 0
+		}
+		line += data[i]
+		character += data[i+1]
+		tokens[[2]int{line, character}] = data[i+3]
+	}
+
+	return tokens
+}
+
+const source = `struct Point {
+    pub x: i32;
+    pub y: i32;
+}
+
+fn add(a: i32, b: i32) -> i32 {
+    ret a + b;
+}
+
+let sum := add(1, 2) * 2;
+let p := Point{x: 1, y: 2};
+`
+
+func TestSession(t *testing.T) {
+
+	c := start(t)
+
+	const uri = "file:///tmp/main.wal"
+
+	var initialized struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+
+	c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &initialized)
+
+	if initialized.Capabilities["hoverProvider"] != true {
+		t.Errorf("capabilities %v do not offer hovers", initialized.Capabilities)
+	}
+
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "walrus", Version: 1, Text: source},
+	})
+
+	if diagnostics := c.diagnostics(); diagnostics.URI != uri || len(diagnostics.Diagnostics) != 0 {
+		t.Errorf("got diagnostics %+v for a valid document", diagnostics)
+	}
+
+	// the type of sum is inferred from the arithmetic
+	var hover lsp.Hover
+
+	c.request("textDocument/hover", position(uri, 9, 5), &hover)
+
+	if !strings.Contains(hover.Contents.Value, "let sum: i32") {
+		t.Errorf("hover on sum shows %q", hover.Contents.Value)
+	}
+
+	var definition lsp.Location
+
+	c.request("textDocument/definition", position(uri, 9, 12), &definition)
+
+	if definition.URI != uri || definition.Range.Start.Line != 5 {
+		t.Errorf("the definition of add is %+v, want line 5", definition)
+	}
+
+	var semantic lsp.SemanticTokens
+
+	c.request("textDocument/semanticTokens/full", lsp.SemanticTokensParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}}, &semantic)
+
+	// the legend of initialize names the types, a and b are parameters in the body too
+	types := semanticTypes(t, initialized.Capabilities)
+	tokens := decodeTokens(semantic.Data)
+
+	for at, want := range map[[2]int]string{
+		{0, 7}:  "struct",
+		{1, 11}: "type",
+		{5, 3}:  "function",
+		{6, 8}:  "parameter",
+		{6, 12}: "parameter",
+		{9, 11}: "function",
+		{9, 23}: "number",
+	} {
+		if got, ok := tokens[at]; !ok || types[got] != want {
+			t.Errorf("semantic token at %v is %v, want %s", at, types[got], want)
+		}
+	}
+
+	// mid-edit the document does not parse, the last analysis keeps serving
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: source + "p.\n"}},
+	})
+
+	if diagnostics := c.diagnostics(); len(diagnostics.Diagnostics) == 0 {
+		t.Error("no diagnostics for a document which does not parse")
+	}
+
+	var members []lsp.CompletionItem
+
+	c.request("textDocument/completion", position(uri, 11, 2), &members)
+
+	if names := labels(members); !contains(names, "x") || !contains(names, "y") || contains(names, "add") {
+		t.Errorf("completion after p. offers %v", names)
+	}
+
+	var visible []lsp.CompletionItem
+
+	c.request("textDocument/completion", position(uri, 11, 0), &visible)
+
+	if names := labels(visible); !contains(names, "add") || !contains(names, "sum") || !contains(names, "fn") {
+		t.Errorf("completion at the start of a line offers %v", names)
+	}
+
+	var shutdown interface{}
+
+	c.request("shutdown", nil, &shutdown)
+	c.notify("exit", nil)
+
+	if err := c.Wait(); err != nil {
+		t.Errorf("the server stopped with %v", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+
+	c := start(t)
+
+	c.notify("exit", nil)
+
+	if err := c.Wait(); err == nil {
+		t.Error("exiting without a shutdown request is not reported")
+	}
+}
+
+func TestUnknownRequest(t *testing.T) {
+
+	c := start(t)
+
+	c.send(map[string]interface{}{"id": 1, "method": "workspace/unknown"})
+
+	if msg := c.next(); !strings.Contains(string(msg["error"]), "method not supported") {
+		t.Errorf("got %v for an unknown request", msg)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"walrus/lsp"
+)
+
+// runLsp serves the language server protocol over stdin and stdout, walrus lsp
+func runLsp(args []string) {
+
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.Parse(args)
+
+	// stdout carries the protocol, everything else goes to stderr
+	if err := lsp.NewServer(os.Stdin, os.Stdout, os.Stderr).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "walrus lsp: %s\n", err)
+		os.Exit(1)
+	}
+}
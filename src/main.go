@@ -1,27 +1,104 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 	"time"
+	"walrus/frontend/ast"
 	"walrus/frontend/parser"
 	"walrus/typechecker"
 	"walrus/utils"
 	"walrus/builtins"
-	//"walrus/tc"
+	"walrus/tc"
 )
 
 
+// dirList collects a flag given several times or as a comma separated list,
+// like --allow-read=a --allow-read=b,c
+type dirList []string
+
+func (d *dirList) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *dirList) Set(value string) error {
+	*d = append(*d, strings.Split(value, ",")...)
+	return nil
+}
 
 func main() {
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "repl":
+			runRepl(os.Args[2:])
+			return
+		case "fmt":
+			runFmt(os.Args[2:])
+			return
+		case "lsp":
+			runLsp(os.Args[2:])
+			return
+		case "dap":
+			runDap(os.Args[2:])
+			return
+		case "test":
+			runTest(os.Args[2:])
+			return
+		}
+	}
+
+	compile(os.Args[1:])
+}
+
+// sandboxFlags adds the flags granting scripts access to the file system
+func sandboxFlags(flags *flag.FlagSet) (allowRead, allowWrite *dirList) {
+	allowRead, allowWrite = &dirList{}, &dirList{}
+	flags.Var(allowRead, "allow-read", "directory the scripts may read, can be repeated")
+	flags.Var(allowWrite, "allow-write", "directory the scripts may read and write, can be repeated")
+	return allowRead, allowWrite
+}
+
+// allowDirs grants the directories of the flags, scripts can not touch the file system unless a flag allows it
+func allowDirs(fsCapability *builtins.FsCapability, allowRead, allowWrite *dirList) {
+
+	for _, dir := range *allowRead {
+		if err := fsCapability.AllowRead(dir); err != nil {
+			fmt.Println(utils.Colorize(utils.RED, fmt.Sprintf("--allow-read: %s", err)))
+			os.Exit(1)
+		}
+	}
+
+	for _, dir := range *allowWrite {
+		if err := fsCapability.AllowWrite(dir); err != nil {
+			fmt.Println(utils.Colorize(utils.RED, fmt.Sprintf("--allow-write: %s", err)))
+			os.Exit(1)
+		}
+	}
+}
+
+// compile parses, checks and runs every .wal file of a directory and stores its syntax tree next to it
+func compile(args []string) {
+
+	flags := flag.NewFlagSet("walrus", flag.ExitOnError)
+	allowRead, allowWrite := sandboxFlags(flags)
+	flags.Parse(args)
+
+	fsCapability := builtins.NewFsCapability()
+	allowDirs(fsCapability, allowRead, allowWrite)
+
 	// time start
 
 	timeStart := time.Now()
 
 	targetDir := "./../code/test/tc"
 
+	if flags.NArg() > 0 {
+		targetDir = flags.Arg(0)
+	}
+
 	dir, err := os.ReadDir(targetDir)
 
 	if err != nil {
@@ -42,7 +119,7 @@ func main() {
 
 		parserMachine := parser.NewParser(filename, false)
 
-		ast := parserMachine.Parse()
+		program := parserMachine.Parse()
 
 		fmt.Printf("Parsed: %v\n", filename)
 
@@ -54,7 +131,7 @@ func main() {
 		}
 
 		//parse as string
-		astString, err := json.MarshalIndent(ast, "", "  ")
+		astString, err := ast.EncodeJSON(program)
 
 		if err != nil {
 			panic(err)
@@ -69,21 +146,25 @@ func main() {
 		file.Close()
 
 		
-		env := typechecker.NewEnvironment(nil, parserMachine)
+		// builtins live in the global scope, so a program's own declarations and imports can shadow them
+		globalTypes := tc.NewTypeEnv(nil)
+		builtins.DeclareTypes(globalTypes)
+
+		if _, err := tc.CheckType(program, tc.NewTypeEnv(globalTypes)); err != nil {
+			if typeErr, ok := err.(tc.TypeError); ok {
+				parser.MakeError(parserMachine, typeErr.Start.Line, filename, typeErr.Start, typeErr.End, typeErr.Message).Display()
+			}
+			panic(err)
+		}
 
-		env.DeclareVariable("true", typechecker.MakeBOOL(true), true)
-		env.DeclareVariable("false", typechecker.MakeBOOL(false), true)
-		env.DeclareVariable("null", typechecker.MakeNULL(), true)
+		globals := typechecker.NewEnvironment(nil, parserMachine)
+		builtins.Declare(globals, fsCapability)
 
-		env.DeclareNativeFn("print", typechecker.MakeNativeFUNCTION(builtins.NativePrint))
-		env.DeclareNativeFn("time", typechecker.MakeNativeFUNCTION(builtins.NativeTime))
+		env := typechecker.NewEnvironment(globals, parserMachine)
 
 		fmt.Printf("Evaluating: %v\n", filename)
 
-		typechecker.Evaluate(ast, env)
-		
-
-		//tc.CheckType(ast)
+		typechecker.Evaluate(program, env)
 
 	}
 
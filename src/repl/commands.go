@@ -0,0 +1,191 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"walrus/formatter"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/utils"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(r *Repl, arg string)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":   {":help", "show this list", (*Repl).help},
+		"type":   {":type <expr>", "show the static type of an expression without running it", (*Repl).typeOf},
+		"ast":    {":ast <code>", "show the syntax tree of code as json", (*Repl).ast},
+		"tokens": {":tokens <code>", "show the tokens of code", (*Repl).tokens},
+		"load":   {":load <file>", "run a file in the session", (*Repl).load},
+		"reset":  {":reset", "forget every declaration of the session", (*Repl).reset},
+		"quit":   {":quit", "end the session", nil},
+	}
+}
+
+// command runs an input starting with ':', returning false for :quit
+func (r *Repl) command(input string) bool {
+
+	name, arg, _ := strings.Cut(strings.TrimPrefix(input, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	cmd, exists := commands[name]
+
+	if !exists {
+		fmt.Fprintln(r.out, utils.Colorize(utils.RED, fmt.Sprintf("Error: unknown command ':%s', try :help", name)))
+		return true
+	}
+
+	if cmd.run == nil {
+		return false
+	}
+
+	cmd.run(r, arg)
+
+	return true
+}
+
+func (r *Repl) help(string) {
+
+	names := []string{"type", "ast", "tokens", "load", "reset", "help", "quit"}
+
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(r.out, "  %-16s %s\n", cmd.usage, cmd.description)
+	}
+}
+
+func (r *Repl) typeOf(arg string) {
+
+	if arg == "" {
+		r.usage("type")
+		return
+	}
+
+	t, err := r.vm.TypeOf(r.name(), terminate(arg))
+
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	if t == nil {
+		fmt.Fprintln(r.out, utils.Colorize(utils.GREY, "unknown"))
+		return
+	}
+
+	fmt.Fprintln(r.out, utils.Colorize(utils.CYAN, formatter.TypeName(t)))
+}
+
+func (r *Repl) ast(arg string) {
+
+	if arg == "" {
+		r.usage("ast")
+		return
+	}
+
+	program, err := parse(r.name(), terminate(arg))
+
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	// a single statement is shown without the program around it
+	var node ast.Node = program
+
+	if len(program.Contents) == 1 && len(program.Imports) == 0 {
+		node = program.Contents[0]
+	}
+
+	astString, err := ast.EncodeNodeJSON(node)
+
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	fmt.Fprintln(r.out, string(astString))
+}
+
+func (r *Repl) tokens(arg string) {
+
+	if arg == "" {
+		r.usage("tokens")
+		return
+	}
+
+	tokens, _, err := lexer.Tokenize(arg, r.name(), false)
+
+	if err != nil {
+		r.printError(err)
+		return
+	}
+
+	for _, token := range tokens {
+
+		if token.Kind == lexer.EOF_TOKEN {
+			break
+		}
+
+		position := fmt.Sprintf("%d:%d", token.StartPos.Line, token.StartPos.Column)
+
+		fmt.Fprintf(r.out, "%s %-12s %s\n", utils.Colorize(utils.GREY, fmt.Sprintf("%-6s", position)), token.Kind, token.Value)
+	}
+}
+
+func (r *Repl) load(arg string) {
+
+	if arg == "" {
+		r.usage("load")
+		return
+	}
+
+	if err := r.vm.LoadFile(arg); err != nil {
+		r.printError(err)
+		return
+	}
+
+	fmt.Fprintln(r.out, utils.Colorize(utils.GREEN, fmt.Sprintf("loaded %s", arg)))
+}
+
+func (r *Repl) reset(string) {
+	r.vm.Reset()
+	fmt.Fprintln(r.out, utils.Colorize(utils.GREEN, "environment reset"))
+}
+
+func (r *Repl) usage(name string) {
+	fmt.Fprintln(r.out, utils.Colorize(utils.RED, fmt.Sprintf("Error: usage %s", commands[name].usage)))
+}
+
+// parse parses a source without running it, errors are returned instead of ending the process
+func parse(name string, source string) (program ast.ProgramStmt, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", recovered)
+			}
+		}
+	}()
+
+	p, err := parser.NewParserFromSource(source, name, false)
+
+	if err != nil {
+		return ast.ProgramStmt{}, err
+	}
+
+	p.PanicOnError = true
+
+	return p.Parse(), nil
+}
@@ -0,0 +1,182 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/typechecker"
+	"walrus/utils"
+	"walrus/vm"
+)
+
+const (
+	PROMPT          = "walrus> "
+	CONTINUE_PROMPT = "   ...> "
+)
+
+// Repl reads walrus code line by line and runs it in one environment, so later
+// inputs see the variables, functions and imports of earlier ones.
+// Errors are printed and never end the session.
+type Repl struct {
+	vm  *vm.VM
+	out io.Writer
+	// inputs read so far, used to name each input in error reports
+	count int
+}
+
+func New(machine *vm.VM, out io.Writer) *Repl {
+	return &Repl{
+		vm:  machine,
+		out: out,
+	}
+}
+
+// Run reads inputs until in ends or :quit is entered.
+// An input continues over several lines while its braces are unbalanced.
+func (r *Repl) Run(in io.Reader) error {
+
+	scanner := bufio.NewScanner(in)
+
+	var input []string
+
+	fmt.Fprint(r.out, PROMPT)
+
+	for scanner.Scan() {
+
+		input = append(input, scanner.Text())
+
+		source := strings.Join(input, "\n")
+
+		if isIncomplete(source) {
+			fmt.Fprint(r.out, CONTINUE_PROMPT)
+			continue
+		}
+
+		input = nil
+
+		if !r.Execute(source) {
+			return nil
+		}
+
+		fmt.Fprint(r.out, PROMPT)
+	}
+
+	// whatever is left when the input ends still runs
+	if len(input) > 0 {
+		r.Execute(strings.Join(input, "\n"))
+	}
+
+	fmt.Fprintln(r.out)
+
+	return scanner.Err()
+}
+
+// Execute runs one complete input, either a command or code.
+// It returns false once the session should end.
+func (r *Repl) Execute(source string) bool {
+
+	trimmed := strings.TrimSpace(source)
+
+	if trimmed == "" {
+		return true
+	}
+
+	r.count++
+
+	if strings.HasPrefix(trimmed, ":") {
+		return r.command(trimmed)
+	}
+
+	value, err := r.vm.Eval(r.name(), terminate(source))
+
+	if err != nil {
+		r.printError(err)
+		return true
+	}
+
+	// statements and calls returning nothing print nothing
+	if value == nil {
+		return true
+	}
+
+	if _, ok := value.(typechecker.VoidValue); ok {
+		return true
+	}
+
+	fmt.Fprintf(r.out, "%s : %s\n", typechecker.FormatValue(value), utils.Colorize(utils.CYAN, string(typechecker.GetRuntimeType(value))))
+
+	return true
+}
+
+// name identifies an input in error reports, like <input 3> for the third one
+func (r *Repl) name() string {
+	return fmt.Sprintf("<input %d>", r.count)
+}
+
+func (r *Repl) printError(err error) {
+	switch e := err.(type) {
+	case *parser.ErrorMessage:
+		fmt.Fprintln(r.out, strings.TrimRight(e.Report(), "\n"))
+	case *lexer.LexError:
+		fmt.Fprintln(r.out, strings.TrimRight(e.Report, "\n"))
+	case *typechecker.RuntimeError:
+		fmt.Fprintln(r.out, utils.Colorize(utils.RED, fmt.Sprintf("Error: %s", err)))
+		fmt.Fprint(r.out, utils.Colorize(utils.GREY, parser.FormatTrace(e.Trace)))
+	default:
+		fmt.Fprintln(r.out, utils.Colorize(utils.RED, fmt.Sprintf("Error: %s", err)))
+	}
+}
+
+// isIncomplete reports whether a source has more opening than closing braces,
+// brackets or parentheses. Sources that do not tokenize are complete, so their
+// error is reported instead of waiting for more lines.
+func isIncomplete(source string) bool {
+
+	tokens, _, err := lexer.Tokenize(source, "repl", false)
+
+	if err != nil {
+		return false
+	}
+
+	depth := 0
+
+	for _, token := range tokens {
+		switch token.Kind {
+		case lexer.OPEN_CURLY_TOKEN, lexer.OPEN_BRACKET_TOKEN, lexer.OPEN_PAREN_TOKEN:
+			depth++
+		case lexer.CLOSE_CURLY_TOKEN, lexer.CLOSE_BRACKET_TOKEN, lexer.CLOSE_PAREN_TOKEN:
+			depth--
+		}
+	}
+
+	return depth > 0
+}
+
+// terminate adds the semicolon an expression typed at the prompt usually lacks.
+// It goes on a line of its own so a trailing comment can not swallow it.
+func terminate(source string) string {
+
+	tokens, _, err := lexer.Tokenize(source, "repl", false)
+
+	// the error is reported when the source runs
+	if err != nil || len(tokens) < 2 {
+		return source
+	}
+
+	// the last token is always EOF
+	switch tokens[len(tokens)-2].Kind {
+	case lexer.SEMI_COLON_TOKEN:
+		return source
+	case lexer.CLOSE_CURLY_TOKEN:
+		// a block ends a statement, a struct literal still needs the semicolon
+		if _, err := parse("repl", source); err == nil {
+			return source
+		}
+	}
+
+	return source + "\n;"
+}
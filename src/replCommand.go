@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"walrus/repl"
+	"walrus/utils"
+	"walrus/vm"
+)
+
+// runRepl starts an interactive session, walrus repl [--allow-read=dir] [--allow-write=dir]
+func runRepl(args []string) {
+
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	allowRead, allowWrite := sandboxFlags(flags)
+	flags.Parse(args)
+
+	machine := vm.New()
+	allowDirs(machine.Fs(), allowRead, allowWrite)
+
+	fmt.Println(utils.Colorize(utils.GREEN, "walrus repl, :help lists the commands"))
+
+	if err := repl.New(machine, os.Stdout).Run(os.Stdin); err != nil {
+		fmt.Println(utils.Colorize(utils.RED, err.Error()))
+		os.Exit(1)
+	}
+}
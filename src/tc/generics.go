@@ -0,0 +1,370 @@
+package tc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"walrus/formatter"
+	"walrus/frontend/ast"
+)
+
+// checkTraitDecl declares a trait and the methods its impls have to give,
+// the bounds of type parameters name it
+func checkTraitDecl(decl *ast.TraitDeclStatement, env *TypeEnv) (ast.Type, error) {
+
+	env.traits[decl.TraitName] = true
+
+	methods := make([]string, 0, len(decl.Methods))
+
+	for name := range decl.Methods {
+		methods = append(methods, name)
+	}
+
+	sort.Strings(methods)
+
+	env.methods[decl.TraitName] = methods
+
+	return ast.VoidType{Kind: ast.T_VOID}, checkTypeParams(decl.TypeParams, env)
+}
+
+// checkImplement records the traits a type implements, the impl has to give
+// every method they declare. An impl without traits, impl T { ... }, names
+// the type as its only trait.
+func checkImplement(stmt *ast.ImplementStatement, env *TypeEnv) (ast.Type, error) {
+
+	if len(stmt.Traits) == 1 && stmt.Traits[0] == stmt.Impliments {
+		return ast.VoidType{Kind: ast.T_VOID}, nil
+	}
+
+	if env.impls[stmt.Impliments] == nil {
+		env.impls[stmt.Impliments] = make(map[string]bool)
+	}
+
+	for _, trait := range stmt.Traits {
+
+		for _, method := range env.methodsOf(trait) {
+			if _, ok := stmt.Methods[method]; !ok {
+				return nil, makeTypeError(*stmt, "impl of trait '%s' for '%s' is missing method '%s'", trait, stmt.Impliments, method)
+			}
+		}
+
+		env.impls[stmt.Impliments][trait] = true
+	}
+
+	return ast.VoidType{Kind: ast.T_VOID}, nil
+}
+
+// DeclareTrait declares a builtin trait like error
+func (t *TypeEnv) DeclareTrait(name string) {
+	t.traits[name] = true
+}
+
+// DeclareImpl records that a builtin type implements a trait, str implements error
+func (t *TypeEnv) DeclareImpl(name ast.DATA_TYPE, trait string) {
+	if t.impls[string(name)] == nil {
+		t.impls[string(name)] = make(map[string]bool)
+	}
+	t.impls[string(name)][trait] = true
+}
+
+// checkTypeParams reports a bound naming a trait that is not declared
+func checkTypeParams(params []ast.TypeParam, env *TypeEnv) error {
+	for _, param := range params {
+		for _, bound := range param.Bounds {
+			if !env.hasTrait(bound) {
+				return TypeError{
+					Message: fmt.Sprintf("type parameter %s is bound by '%s' which is not a trait", param.Name, bound),
+					Start:   param.StartPos,
+					End:     param.EndPos,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// methodsOf returns the methods a trait declares, none for a trait that is not declared
+func (t *TypeEnv) methodsOf(trait string) []string {
+	for scope := t; scope != nil; scope = scope.parent {
+		if methods, ok := scope.methods[trait]; ok {
+			return methods
+		}
+	}
+	return nil
+}
+
+func (t *TypeEnv) hasTrait(name string) bool {
+	if t.traits[name] {
+		return true
+	}
+	if t.parent == nil {
+		return false
+	}
+	return t.parent.hasTrait(name)
+}
+
+// implements tells if a type implements a trait. A type parameter of the
+// function being checked implements the traits of its bounds.
+func (t *TypeEnv) implements(typ ast.Type, trait string) bool {
+
+	if param, ok := typ.(ast.TypeParamType); ok {
+		bounds, ok := t.boundsOf(string(param.Kind))
+		if !ok {
+			return true
+		}
+		for _, bound := range bounds {
+			if bound == trait {
+				return true
+			}
+		}
+		return false
+	}
+
+	name := formatter.TypeName(typ)
+
+	if generic, ok := typ.(ast.GenericType); ok {
+		name = string(generic.Kind)
+	}
+
+	for scope := t; scope != nil; scope = scope.parent {
+		if scope.impls[name][trait] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *TypeEnv) boundsOf(param string) ([]string, bool) {
+	if bounds, ok := t.bounds[param]; ok {
+		return bounds, true
+	}
+	if t.parent == nil {
+		return nil, false
+	}
+	return t.parent.boundsOf(param)
+}
+
+// inference binds the type parameters of a generic function to the types
+// of the arguments of a call, from records the argument each one came from
+type inference struct {
+	call     *ast.FunctionCallExpr
+	params   map[string]bool
+	bindings map[string]ast.Type
+	from     map[string]int
+}
+
+// instantiate infers the type arguments of a call to a generic function,
+// checks them against the bounds and returns the signature they give
+func instantiate(call *ast.FunctionCallExpr, fnType ast.FunctionType, argTypes []ast.Type, namedTypes []ast.Type, env *TypeEnv) (ast.FunctionType, error) {
+
+	inf := &inference{
+		call:     call,
+		params:   make(map[string]bool),
+		bindings: make(map[string]ast.Type),
+		from:     make(map[string]int),
+	}
+
+	for _, param := range fnType.TypeParams {
+		inf.params[param.Name] = true
+	}
+
+	for i, argType := range argTypes {
+		if i >= len(fnType.Parameters) {
+			break
+		}
+		if err := inf.unify(fnType.Parameters[i].Type, argType, i); err != nil {
+			return fnType, err
+		}
+	}
+
+	// the named arguments are numbered after the positional ones
+	for j, argType := range namedTypes {
+		i := ast.ParamIndex(fnType.Parameters, call.NamedArgs[j].Name.Identifier)
+		if i < 0 {
+			continue
+		}
+		if err := inf.unify(fnType.Parameters[i].Type, argType, len(argTypes)+j); err != nil {
+			return fnType, err
+		}
+	}
+
+	for _, param := range fnType.TypeParams {
+
+		bound, ok := inf.bindings[param.Name]
+
+		if !ok {
+			continue
+		}
+
+		for _, trait := range param.Bounds {
+			if !env.implements(bound, trait) {
+				return fnType, makeTypeError(inf.arg(inf.from[param.Name]), "'%s' does not implement trait '%s' required by type parameter %s of '%s'", formatter.TypeName(bound), trait, param.Name, call.CallerName())
+			}
+		}
+	}
+
+	params := make([]ast.FunctionParameter, len(fnType.Parameters))
+
+	for i, param := range fnType.Parameters {
+		param.Type = substituteType(param.Type, inf.bindings)
+		params[i] = param
+	}
+
+	return ast.FunctionType{
+		Kind:       fnType.Kind,
+		ReturnType: substituteType(fnType.ReturnType, inf.bindings),
+		Parameters: params,
+	}, nil
+}
+
+// unify binds the type parameters in the type of a parameter to the parts
+// of the argument type at the same place. A parameter bound twice takes the
+// wider of two integer or float types, other types have to be the same.
+func (inf *inference) unify(param ast.Type, arg ast.Type, index int) error {
+
+	if param == nil || arg == nil {
+		return nil
+	}
+
+	switch t := param.(type) {
+	case ast.TypeParamType:
+		return inf.bind(string(t.Kind), arg, index)
+	case ast.ArrayType:
+		if argArray, ok := arg.(ast.ArrayType); ok && inf.params[string(t.ElementType)] {
+			return inf.bind(string(t.ElementType), typeOf(argArray.ElementType), index)
+		}
+	case ast.GenericType:
+		if argGeneric, ok := arg.(ast.GenericType); ok && argGeneric.Kind == t.Kind && len(argGeneric.TypeArgs) == len(t.TypeArgs) {
+			for i, typeArg := range t.TypeArgs {
+				if err := inf.unify(typeArg, argGeneric.TypeArgs[i], index); err != nil {
+					return err
+				}
+			}
+		}
+	case ast.FunctionType:
+		if argFn, ok := arg.(ast.FunctionType); ok && len(argFn.Parameters) == len(t.Parameters) {
+			for i, fnParam := range t.Parameters {
+				if err := inf.unify(fnParam.Type, argFn.Parameters[i].Type, index); err != nil {
+					return err
+				}
+			}
+			return inf.unify(t.ReturnType, argFn.ReturnType, index)
+		}
+	}
+
+	return nil
+}
+
+func (inf *inference) bind(name string, arg ast.Type, index int) error {
+
+	bound, ok := inf.bindings[name]
+
+	if !ok {
+		inf.bindings[name] = arg
+		inf.from[name] = index
+		return nil
+	}
+
+	if isAssignable(bound, arg) {
+		return nil
+	}
+
+	if isAssignable(arg, bound) {
+		inf.bindings[name] = arg
+		return nil
+	}
+
+	return makeTypeError(inf.arg(index), "type parameter %s of '%s' is '%s' from argument %d but argument %d is '%s'", name, inf.call.CallerName(), formatter.TypeName(bound), inf.from[name]+1, index+1, formatter.TypeName(arg))
+}
+
+// arg is the argument with the index, the named ones follow the positional ones
+func (inf *inference) arg(index int) ast.Node {
+	if index < len(inf.call.Args) {
+		return inf.call.Args[index]
+	}
+	return inf.call.NamedArgs[index-len(inf.call.Args)].Value
+}
+
+// substituteType replaces the type parameters in a type by the types bound
+// to them, the ones without a binding stay parameters
+func substituteType(t ast.Type, bindings map[string]ast.Type) ast.Type {
+
+	switch t := t.(type) {
+	case ast.TypeParamType:
+		if bound, ok := bindings[string(t.Kind)]; ok {
+			return bound
+		}
+	case ast.ArrayType:
+		if bound, ok := bindings[string(t.ElementType)]; ok {
+			return ast.ArrayType{Kind: ast.T_ARRAY, ElementType: bound.IType()}
+		}
+	case ast.GenericType:
+		args := make([]ast.Type, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = substituteType(arg, bindings)
+		}
+		return ast.GenericType{Kind: t.Kind, TypeArgs: args}
+	case ast.FunctionType:
+		params := make([]ast.FunctionParameter, len(t.Parameters))
+		for i, param := range t.Parameters {
+			param.Type = substituteType(param.Type, bindings)
+			params[i] = param
+		}
+		return ast.FunctionType{Kind: t.Kind, ReturnType: substituteType(t.ReturnType, bindings), Parameters: params}
+	}
+
+	return t
+}
+
+// instantiateEnum gives the payloads of a generic enum the types of the
+// arguments of a use of it, the variants of Option<i32> hold an i32
+func instantiateEnum(enum ast.EnumType, typeArgs []ast.Type) ast.EnumType {
+
+	bindings := make(map[string]ast.Type)
+
+	for i, param := range enum.TypeParams {
+		if i < len(typeArgs) {
+			bindings[param.Name] = typeArgs[i]
+		}
+	}
+
+	variants := make([]ast.EnumVariant, len(enum.Variants))
+
+	for i, variant := range enum.Variants {
+		fields := make([]ast.EnumField, len(variant.Fields))
+		for j, field := range variant.Fields {
+			field.Type = substituteType(field.Type, bindings)
+			fields[j] = field
+		}
+		variant.Fields = fields
+		variants[i] = variant
+	}
+
+	enum.Variants = variants
+
+	return enum
+}
+
+// typeOf is the type of an array element, arrays only keep the name of it
+func typeOf(name ast.DATA_TYPE) ast.Type {
+	switch name {
+	case ast.T_INTEGER8, ast.T_INTEGER16, ast.T_INTEGER32, ast.T_INTEGER64,
+		ast.T_UNSIGNED8, ast.T_UNSIGNED16, ast.T_UNSIGNED32, ast.T_UNSIGNED64:
+		size, _ := strconv.Atoi(string(name[1:]))
+		return ast.IntegerType{Kind: name, BitSize: uint8(size), IsSigned: name[0] == 'i'}
+	case ast.T_FLOAT32:
+		return ast.FloatType{Kind: name, BitSize: 32}
+	case ast.T_FLOAT64:
+		return ast.FloatType{Kind: name, BitSize: 64}
+	case ast.T_STRING:
+		return ast.StringType{Kind: name}
+	case ast.T_BOOLEAN:
+		return ast.BoolType{Kind: name}
+	case ast.T_CHARACTER:
+		return ast.CharType{Kind: name}
+	case ast.T_ANY:
+		return ast.AnyType{Kind: name}
+	}
+	return ast.StructType{Kind: name}
+}
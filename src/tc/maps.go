@@ -0,0 +1,120 @@
+package tc
+
+import (
+	"walrus/formatter"
+	"walrus/frontend/ast"
+)
+
+// checkMapLiteral returns the type of a map literal, the one written before
+// its braces or the one of its first entry. The other entries have to fit it.
+func checkMapLiteral(literal *ast.MapLiteral, env *TypeEnv) (ast.Type, error) {
+
+	mapType := literal.Type
+
+	for i, entry := range literal.Entries {
+
+		keyType, err := CheckType(entry.Key, env)
+
+		if err != nil {
+			return nil, err
+		}
+
+		valueType, err := CheckType(entry.Value, env)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 && mapType == nil && keyType != nil && valueType != nil {
+			mapType = ast.MapType{Kind: ast.T_MAP, KeyType: keyType, ValueType: valueType}
+			continue
+		}
+
+		if t, ok := mapType.(ast.MapType); ok {
+			if err := checkKey(t, entry.Key, keyType, env); err != nil {
+				return nil, err
+			}
+			if err := checkValue(t, entry.Value, valueType, env); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return mapType, nil
+}
+
+// checkIndex returns the type of array[index], the values of a map. Elements
+// of arrays are not tracked yet.
+func checkIndex(expr *ast.ArrayIndexAccess, env *TypeEnv) (ast.Type, error) {
+
+	arrayType, err := CheckType(expr.Array, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	indexType, err := CheckType(expr.Index, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := arrayType.(ast.MapType); ok {
+		return t.ValueType, checkKey(t, expr.Index, indexType, env)
+	}
+
+	return nil, nil
+}
+
+// checkIndexAssignment checks m[key] = value, the value has to fit the map.
+// The map can itself be indexed, m["a"]["x"] = value.
+func checkIndexAssignment(expr *ast.AssignmentExpr, target *ast.ArrayIndexAccess, env *TypeEnv) (ast.Type, error) {
+
+	valueType, err := CheckType(expr.Value, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := checkIndex(target, env); err != nil {
+		return nil, err
+	}
+
+	arrayType, _ := CheckType(target.Array, env)
+
+	if t, ok := arrayType.(ast.MapType); ok {
+		return valueType, checkValue(t, expr.Value, valueType, env)
+	}
+
+	return valueType, nil
+}
+
+// checkMembership checks key in map and value in array
+func checkMembership(expr *ast.BinaryExpr, leftType ast.Type, rightType ast.Type, env *TypeEnv) (ast.Type, error) {
+
+	switch t := rightType.(type) {
+	case ast.MapType:
+		if err := checkKey(t, expr.Left, leftType, env); err != nil {
+			return nil, err
+		}
+	case nil, ast.ArrayType, ast.AnyType, ast.TypeParamType:
+	default:
+		return nil, makeTypeError(expr.Right, "operator in needs a map or an array but got '%s'", formatter.TypeName(rightType))
+	}
+
+	return ast.BoolType{Kind: ast.T_BOOLEAN}, nil
+}
+
+func checkKey(t ast.MapType, key ast.Node, keyType ast.Type, env *TypeEnv) error {
+	if !env.accepts(t.KeyType, keyType) {
+		return makeTypeError(key, "map of type '%s' cannot have a key of type '%s'", formatter.TypeName(t), formatter.TypeName(keyType))
+	}
+	return nil
+}
+
+func checkValue(t ast.MapType, value ast.Node, valueType ast.Type, env *TypeEnv) error {
+	if !env.accepts(t.ValueType, valueType) {
+		return makeTypeError(value, "map of type '%s' cannot have a value of type '%s'", formatter.TypeName(t), formatter.TypeName(valueType))
+	}
+	return nil
+}
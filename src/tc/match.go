@@ -0,0 +1,540 @@
+package tc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"walrus/frontend/ast"
+)
+
+// checkEnumDecl declares an enum, its name is a value holding the variants
+func checkEnumDecl(decl *ast.EnumDeclStatement, env *TypeEnv) (ast.Type, error) {
+
+	enum := ast.EnumType{
+		Kind:       ast.DATA_TYPE(decl.EnumName),
+		TypeParams: decl.TypeParams,
+		Variants:   decl.Variants,
+	}
+
+	if err := checkTypeParams(decl.TypeParams, env); err != nil {
+		return nil, err
+	}
+
+	// redeclarations are reported by the evaluator with its own message
+	env.DeclareEnum(decl.EnumName, enum)
+	env.DeclareVar(decl.EnumName, enum, true)
+
+	return ast.VoidType{Kind: ast.T_VOID}, nil
+}
+
+// checkVariant returns the type of Enum.Variant, a value of the enum for a
+// plain variant and a constructor taking the payload for the others
+func checkVariant(expr *ast.PropertyExpr, enum ast.EnumType) (ast.Type, error) {
+
+	variant, ok := enum.Variant(expr.Property.Identifier)
+
+	if !ok {
+		return nil, makeTypeError(expr.Property, "enum '%s' has no variant '%s'", enum.Kind, expr.Property.Identifier)
+	}
+
+	valueType := ast.StructType{Kind: enum.Kind}
+
+	if len(variant.Fields) == 0 {
+		return valueType, nil
+	}
+
+	params := make([]ast.FunctionParameter, len(variant.Fields))
+
+	for i, field := range variant.Fields {
+		params[i] = ast.FunctionParameter{
+			BaseStmt: ast.BaseStmt{
+				Kind: ast.FUNCTION_PARAMETER,
+			},
+			Identifier: ast.IdentifierExpr{
+				BaseStmt: ast.BaseStmt{
+					Kind: ast.IDENTIFIER,
+				},
+				Identifier: field.Name,
+			},
+			Type: field.Type,
+		}
+	}
+
+	return ast.FunctionType{
+		Kind:       ast.T_NATIVE_FN,
+		TypeParams: enum.TypeParams,
+		ReturnType: valueType,
+		Parameters: params,
+	}, nil
+}
+
+// enumOf returns the enum a type names
+func enumOf(t ast.Type, env *TypeEnv) (ast.EnumType, bool) {
+	switch t := t.(type) {
+	case ast.StructType:
+		return env.GetEnum(string(t.Kind))
+	case ast.GenericType:
+		if enum, ok := env.GetEnum(string(t.Kind)); ok {
+			return instantiateEnum(enum, t.TypeArgs), true
+		}
+	}
+	return ast.EnumType{}, false
+}
+
+// enumDeclaring returns an enum of the scope declaring a variant, the closest one first
+func (t *TypeEnv) enumDeclaring(variant string) (ast.EnumType, bool) {
+
+	names := make([]string, 0, len(t.enums))
+
+	for name := range t.enums {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := t.enums[name].Variant(variant); ok {
+			return t.enums[name], true
+		}
+	}
+
+	if t.parent == nil {
+		return ast.EnumType{}, false
+	}
+
+	return t.parent.enumDeclaring(variant)
+}
+
+// checkMatch checks the patterns, guards and bodies of the arms, then that
+// every arm can match a value the arms before it leave and that together
+// they match every value of the subject. Arms with a guard may not match, so
+// they cover nothing.
+func checkMatch(expr *ast.MatchExpr, env *TypeEnv) (ast.Type, error) {
+
+	subjectType, err := CheckType(expr.Subject, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matched := matchedType(expr, subjectType, env)
+
+	types := []ast.Type{matched}
+
+	var rows [][]pattern
+
+	var resultType ast.Type
+
+	for i, arm := range expr.Arms {
+
+		scope := NewTypeEnv(env)
+
+		if err := checkPattern(arm.Pattern, matched, scope); err != nil {
+			return nil, err
+		}
+
+		if arm.Guard != nil {
+			if _, err := CheckType(arm.Guard, scope); err != nil {
+				return nil, err
+			}
+		}
+
+		bodyType, err := CheckType(arm.Body, scope)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the match has a type when every arm has the same one
+		if i == 0 {
+			resultType = bodyType
+		} else if resultType != nil && (bodyType == nil || bodyType.IType() != resultType.IType()) {
+			resultType = nil
+		}
+
+		row := []pattern{toPattern(arm.Pattern, matched, env)}
+
+		if !useful(rows, row, types, env) {
+			return nil, makeTypeError(arm.Pattern, "unreachable match arm, the arms before it match every value it does")
+		}
+
+		if arm.Guard == nil {
+			rows = append(rows, row)
+		}
+	}
+
+	if missing := witness(rows, types, env); missing != nil {
+		return nil, makeTypeError(expr.Subject, "match is not exhaustive: %s is not covered", missing[0].format(matched, env))
+	}
+
+	return resultType, nil
+}
+
+// matchedType is the type of the subject, or when it is not known the enum
+// or bool the patterns match
+func matchedType(expr *ast.MatchExpr, subjectType ast.Type, env *TypeEnv) ast.Type {
+
+	if subjectType != nil {
+		return subjectType
+	}
+
+	for _, arm := range expr.Arms {
+		switch p := arm.Pattern.(type) {
+		case ast.VariantPattern:
+			if p.EnumName != "" {
+				if enum, ok := env.GetEnum(p.EnumName); ok {
+					return ast.StructType{Kind: enum.Kind}
+				}
+			} else if enum, ok := env.enumDeclaring(p.Variant); ok {
+				return ast.StructType{Kind: enum.Kind}
+			}
+		case ast.BooleanLiteral:
+			return ast.BoolType{Kind: ast.T_BOOLEAN}
+		}
+	}
+
+	return nil
+}
+
+// checkPattern checks a pattern against the type of the value it matches and
+// declares the names it binds in the scope of the arm
+func checkPattern(node ast.Node, t ast.Type, scope *TypeEnv) error {
+
+	enum, isEnum := enumOf(t, scope)
+
+	switch p := node.(type) {
+	case ast.IdentifierExpr:
+		if p.Identifier == "_" {
+			return nil
+		}
+		if variant, ok := enum.Variant(p.Identifier); isEnum && ok {
+			if len(variant.Fields) > 0 {
+				return makeTypeError(p, "variant %s.%s has a payload, match it like %s(...)", enum.Kind, variant.Name, variant.Name)
+			}
+			return nil
+		}
+		if _, ok := scope.variables[p.Identifier]; ok {
+			return makeTypeError(p, "%s is bound twice in the pattern", p.Identifier)
+		}
+		scope.DeclareVar(p.Identifier, t, false)
+		return nil
+	case ast.VariantPattern:
+		if !isEnum {
+			if t == nil {
+				return makeTypeError(p, "variant %s is not declared by an enum", p.Variant)
+			}
+			return makeTypeError(p, "a value of type '%s' cannot match the variant %s", t.IType(), p.Variant)
+		}
+		if p.EnumName != "" && p.EnumName != string(enum.Kind) {
+			return makeTypeError(p, "a variant of %s cannot match a value of enum %s", p.EnumName, enum.Kind)
+		}
+		variant, ok := enum.Variant(p.Variant)
+		if !ok {
+			return makeTypeError(p, "enum '%s' has no variant '%s'", enum.Kind, p.Variant)
+		}
+		// Enum.Variant without parentheses
+		if p.Fields == nil {
+			if len(variant.Fields) > 0 {
+				return makeTypeError(p, "variant %s.%s has a payload, match it like %s(...)", enum.Kind, variant.Name, variant.Name)
+			}
+			return nil
+		}
+		if len(p.Fields) != len(variant.Fields) {
+			return makeTypeError(p, "variant %s.%s has %d fields but the pattern has %d", enum.Kind, variant.Name, len(variant.Fields), len(p.Fields))
+		}
+		for i, field := range p.Fields {
+			if err := checkPattern(field, variant.Fields[i].Type, scope); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if isEnum {
+		return makeTypeError(node, "a literal cannot match a value of enum %s", enum.Kind)
+	}
+
+	literalType, _ := CheckType(node, scope)
+
+	if t != nil && literalType != nil && !(isNumeric(t) && isNumeric(literalType)) && t.IType() != literalType.IType() {
+		return makeTypeError(node, "a %s literal cannot match a value of type '%s'", literalType.IType(), t.IType())
+	}
+
+	return nil
+}
+
+func isNumeric(t ast.Type) bool {
+	switch t.(type) {
+	case ast.IntegerType, ast.FloatType:
+		return true
+	}
+	return false
+}
+
+// pattern is a pattern as the exhaustiveness check sees it, a constructor
+// applied to patterns or a wildcard when ctor is empty. The constructors are
+// the variants of an enum, true and false, and literals of other types.
+type pattern struct {
+	ctor string
+	args []pattern
+}
+
+type constructor struct {
+	name   string
+	fields []ast.Type
+}
+
+// toPattern converts the pattern of an arm, names binding a value are wildcards
+func toPattern(node ast.Node, t ast.Type, env *TypeEnv) pattern {
+
+	switch p := node.(type) {
+	case ast.IdentifierExpr:
+		if enum, ok := enumOf(t, env); ok {
+			if _, ok := enum.Variant(p.Identifier); ok {
+				return pattern{ctor: p.Identifier}
+			}
+		}
+		return pattern{}
+	case ast.VariantPattern:
+		fields := fieldsOf(p.Variant, t, env)
+		args := wildcards(len(fields))
+		for i, field := range p.Fields {
+			if i < len(args) {
+				args[i] = toPattern(field, fields[i], env)
+			}
+		}
+		return pattern{ctor: p.Variant, args: args}
+	case ast.BooleanLiteral:
+		return pattern{ctor: strconv.FormatBool(p.Value)}
+	case ast.NumericLiteral:
+		return pattern{ctor: "=" + p.Value}
+	case ast.UnaryExpr:
+		if number, ok := p.Argument.(ast.NumericLiteral); ok {
+			return pattern{ctor: "=-" + number.Value}
+		}
+	case ast.StringLiteral:
+		return pattern{ctor: "=" + strconv.Quote(p.Value)}
+	case ast.CharacterLiteral:
+		return pattern{ctor: "='" + p.Value + "'"}
+	case ast.NullLiteral:
+		return pattern{ctor: "=null"}
+	}
+
+	return pattern{}
+}
+
+// constructors returns every constructor of a type, false when there are too many to list
+func constructors(t ast.Type, env *TypeEnv) ([]constructor, bool) {
+
+	if enum, ok := enumOf(t, env); ok {
+		ctors := make([]constructor, len(enum.Variants))
+		for i, variant := range enum.Variants {
+			fields := make([]ast.Type, len(variant.Fields))
+			for j, field := range variant.Fields {
+				fields[j] = field.Type
+			}
+			ctors[i] = constructor{name: variant.Name, fields: fields}
+		}
+		return ctors, true
+	}
+
+	if _, ok := t.(ast.BoolType); ok {
+		return []constructor{{name: "true"}, {name: "false"}}, true
+	}
+
+	return nil, false
+}
+
+// fieldsOf returns the types of the payload of a constructor, none for a literal
+func fieldsOf(name string, t ast.Type, env *TypeEnv) []ast.Type {
+	ctors, _ := constructors(t, env)
+	for _, c := range ctors {
+		if c.name == name {
+			return c.fields
+		}
+	}
+	return nil
+}
+
+func wildcards(n int) []pattern {
+	return make([]pattern, n)
+}
+
+// specialize keeps the rows matching a constructor, its arguments replacing the first column
+func specialize(rows [][]pattern, name string, arity int) [][]pattern {
+
+	var specialized [][]pattern
+
+	for _, row := range rows {
+
+		head := row[0]
+
+		var args []pattern
+
+		switch head.ctor {
+		case "":
+			args = wildcards(arity)
+		case name:
+			args = wildcards(arity)
+			copy(args, head.args)
+		default:
+			continue
+		}
+
+		specialized = append(specialized, append(args, row[1:]...))
+	}
+
+	return specialized
+}
+
+// defaultRows keeps the rows starting with a wildcard, without it
+func defaultRows(rows [][]pattern) [][]pattern {
+
+	var rest [][]pattern
+
+	for _, row := range rows {
+		if row[0].ctor == "" {
+			rest = append(rest, row[1:])
+		}
+	}
+
+	return rest
+}
+
+// covers tells if some row starts with a constructor
+func covers(rows [][]pattern, name string) bool {
+	for _, row := range rows {
+		if row[0].ctor == name {
+			return true
+		}
+	}
+	return false
+}
+
+// complete tells if the first column names every constructor of a finite type
+func complete(rows [][]pattern, ctors []constructor, finite bool) bool {
+
+	if !finite {
+		return false
+	}
+
+	for _, c := range ctors {
+		if !covers(rows, c.name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func concat(types ...[]ast.Type) []ast.Type {
+	var all []ast.Type
+	for _, t := range types {
+		all = append(all, t...)
+	}
+	return all
+}
+
+// useful tells if a row of patterns matches a value none of the rows match
+func useful(rows [][]pattern, row []pattern, types []ast.Type, env *TypeEnv) bool {
+
+	if len(row) == 0 {
+		return len(rows) == 0
+	}
+
+	head := row[0]
+
+	if head.ctor != "" {
+		fields := fieldsOf(head.ctor, types[0], env)
+		args := specialize([][]pattern{row}, head.ctor, len(fields))[0]
+		return useful(specialize(rows, head.ctor, len(fields)), args, concat(fields, types[1:]), env)
+	}
+
+	ctors, finite := constructors(types[0], env)
+
+	if complete(rows, ctors, finite) {
+		for _, c := range ctors {
+			if useful(specialize(rows, c.name, len(c.fields)), append(wildcards(len(c.fields)), row[1:]...), concat(c.fields, types[1:]), env) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return useful(defaultRows(rows), row[1:], types[1:], env)
+}
+
+// witness returns values of the types no row matches, nil when the rows match every value
+func witness(rows [][]pattern, types []ast.Type, env *TypeEnv) []pattern {
+
+	if len(types) == 0 {
+		if len(rows) == 0 {
+			return []pattern{}
+		}
+		return nil
+	}
+
+	ctors, finite := constructors(types[0], env)
+
+	if complete(rows, ctors, finite) {
+		for _, c := range ctors {
+			arity := len(c.fields)
+			if missing := witness(specialize(rows, c.name, arity), concat(c.fields, types[1:]), env); missing != nil {
+				return append([]pattern{{ctor: c.name, args: missing[:arity]}}, missing[arity:]...)
+			}
+		}
+		return nil
+	}
+
+	missing := witness(defaultRows(rows), types[1:], env)
+
+	if missing == nil {
+		return nil
+	}
+
+	// name a constructor no row starts with when there is one
+	head := pattern{}
+
+	for _, c := range ctors {
+		if !covers(rows, c.name) {
+			head = pattern{ctor: c.name, args: wildcards(len(c.fields))}
+			break
+		}
+	}
+
+	return append([]pattern{head}, missing...)
+}
+
+// format writes a pattern the way it is written in walrus, variants qualified by their enum
+func (p pattern) format(t ast.Type, env *TypeEnv) string {
+
+	if p.ctor == "" {
+		return "_"
+	}
+
+	if strings.HasPrefix(p.ctor, "=") {
+		return p.ctor[1:]
+	}
+
+	enum, ok := enumOf(t, env)
+
+	if !ok {
+		return p.ctor
+	}
+
+	name := string(enum.Kind) + "." + p.ctor
+
+	if len(p.args) == 0 {
+		return name
+	}
+
+	fields := fieldsOf(p.ctor, t, env)
+
+	args := make([]string, len(p.args))
+
+	for i, arg := range p.args {
+		args[i] = arg.format(fields[i], env)
+	}
+
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
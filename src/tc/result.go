@@ -0,0 +1,55 @@
+package tc
+
+import (
+	"walrus/formatter"
+	"walrus/frontend/ast"
+)
+
+// checkTry returns the type of value?, the type an Ok of the Result holds.
+// An Err is returned from the function around the expression, which has to
+// return a Result taking the error. At the top level an Err ends the script.
+func checkTry(expr *ast.TryExpr, env *TypeEnv) (ast.Type, error) {
+
+	valueType, err := CheckType(expr.Value, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch valueType.(type) {
+	case nil, ast.AnyType, ast.TypeParamType:
+		return nil, nil
+	}
+
+	if valueType.IType() != ast.T_RESULT {
+		return nil, makeTypeError(*expr, "operator ? needs a Result but got '%s'", formatter.TypeName(valueType))
+	}
+
+	returns := env.returnType()
+
+	if returns != nil && returns.IType() != ast.T_RESULT {
+		return nil, makeTypeError(*expr, "operator ? returns the error from the function, which returns '%s' instead of a Result", formatter.TypeName(returns))
+	}
+
+	result, ok := valueType.(ast.GenericType)
+
+	if !ok || len(result.TypeArgs) != 2 {
+		return nil, nil
+	}
+
+	if target, ok := returns.(ast.GenericType); ok && len(target.TypeArgs) == 2 && !env.accepts(target.TypeArgs[1], result.TypeArgs[1]) {
+		return nil, makeTypeError(*expr, "operator ? returns an error of type '%s' from a function whose errors are '%s'", formatter.TypeName(result.TypeArgs[1]), formatter.TypeName(target.TypeArgs[1]))
+	}
+
+	return result.TypeArgs[0], nil
+}
+
+// returnType is the return type of the function the scope is in, nil at the top level
+func (t *TypeEnv) returnType() ast.Type {
+	for scope := t; scope != nil; scope = scope.parent {
+		if scope.returns != nil {
+			return scope.returns
+		}
+	}
+	return nil
+}
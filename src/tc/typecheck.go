@@ -2,7 +2,11 @@ package tc
 
 import (
 	"fmt"
+	"strings"
+	"walrus/formatter"
 	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/utils"
 )
 
 /*
@@ -14,9 +18,51 @@ type TypeEnv struct {
 	variables 	map[string]ast.Type
 	constants 	map[string]bool
 	structs 	map[string]ast.Type
+	enums 		map[string]ast.EnumType
+	modules 	map[string]ast.ModuleType
+	traits 		map[string]bool
+	// the methods each trait declares, by the name of the trait
+	methods 	map[string][]string
+	impls 		map[string]map[string]bool
+	bounds 		map[string][]string
+	// the return type of the function whose body the scope is, nil in the other scopes
+	returns 	ast.Type
 }
 
-var typeEnv = new(TypeEnv)
+func NewTypeEnv(parent *TypeEnv) *TypeEnv {
+	return &TypeEnv{
+		parent:    parent,
+		variables: make(map[string]ast.Type),
+		constants: make(map[string]bool),
+		structs:   make(map[string]ast.Type),
+		enums:     make(map[string]ast.EnumType),
+		modules:   make(map[string]ast.ModuleType),
+		traits:    make(map[string]bool),
+		methods:   make(map[string][]string),
+		impls:     make(map[string]map[string]bool),
+		bounds:    make(map[string][]string),
+	}
+}
+
+// TypeError is a static error with the source range it was found at
+type TypeError struct {
+	Message string
+	Start   lexer.Position
+	End     lexer.Position
+}
+
+func (e TypeError) Error() string {
+	return e.Message
+}
+
+func makeTypeError(node ast.Node, format string, args ...interface{}) TypeError {
+	start, end := node.GetPos()
+	return TypeError{
+		Message: fmt.Sprintf(format, args...),
+		Start:   start,
+		End:     end,
+	}
+}
 
 func (t *TypeEnv) ResolveVar(name string) (*TypeEnv, error) {
 	if _, ok := t.variables[name]; ok {
@@ -31,16 +77,20 @@ func (t *TypeEnv) ResolveVar(name string) (*TypeEnv, error) {
 	return t.parent.ResolveVar(name)
 }
 
+// GetVar returns the declared type of a variable, nil when it is not known
+func (t *TypeEnv) GetVar(name string) ast.Type {
+	scope, err := t.ResolveVar(name)
+	if err != nil {
+		return nil
+	}
+	return scope.variables[name]
+}
+
 func (t *TypeEnv) DeclareVar(name string, valueType ast.Type, isConst bool) error {
 	if _, ok := t.variables[name]; ok {
 		return fmt.Errorf("%s already declared in this scope", name)
 	}
 
-	switch vType := valueType.(type) {
-	case ast.StructType:
-		t.DeclareStruct(name, vType)
-	}
-
 	t.variables[name] = valueType
 
 	if isConst {
@@ -50,28 +100,172 @@ func (t *TypeEnv) DeclareVar(name string, valueType ast.Type, isConst bool) erro
 	return nil
 }
 
+// SetVar declares a variable or replaces its type in this scope
+func (t *TypeEnv) SetVar(name string, valueType ast.Type, isConst bool) {
+	t.variables[name] = valueType
+	t.constants[name] = isConst
+}
+
 func (t *TypeEnv) DeclareStruct(name string, valueType ast.StructType) error {
-	declaredStruct := t.structs[name]
-	fmt.Printf("declared: %v\n", declaredStruct.(ast.StructType))
+	if _, ok := t.structs[name]; ok {
+		return fmt.Errorf("struct %s already declared in this scope", name)
+	}
+	t.structs[name] = valueType
 	return nil
 }
 
-func CheckType(astNode ast.Node, env *TypeEnv) (ast.Type, error) {
-	fmt.Println("Checking types")
-	fmt.Printf("%v\n", astNode)
+func (t *TypeEnv) DeclareEnum(name string, enum ast.EnumType) error {
+	if _, ok := t.enums[name]; ok {
+		return fmt.Errorf("enum %s already declared in this scope", name)
+	}
+	t.enums[name] = enum
+	return nil
+}
+
+// GetEnum returns the enum a type names, false when it is not an enum
+func (t *TypeEnv) GetEnum(name string) (ast.EnumType, bool) {
+	if enum, ok := t.enums[name]; ok {
+		return enum, true
+	}
+
+	if t.parent == nil {
+		return ast.EnumType{}, false
+	}
+
+	return t.parent.GetEnum(name)
+}
+
+func (t *TypeEnv) DeclareModule(name string, module ast.ModuleType) error {
+	if _, ok := t.modules[name]; ok {
+		return fmt.Errorf("module %s already declared", name)
+	}
+	t.modules[name] = module
+	return nil
+}
+
+func (t *TypeEnv) GetModule(name string) (ast.ModuleType, error) {
+	if module, ok := t.modules[name]; ok {
+		return module, nil
+	}
+
+	if t.parent == nil {
+		return ast.ModuleType{}, fmt.Errorf("module %s not found", name)
+	}
 
+	return t.parent.GetModule(name)
+}
+
+// CheckType returns the static type of a node. A nil type means the checker
+// could not work it out, such values are left for the evaluator to verify.
+func CheckType(astNode ast.Node, env *TypeEnv) (ast.Type, error) {
 
 	switch node := (astNode).(type) {
 	case ast.ProgramStmt:
-		return checkProgram(&node, typeEnv)
+		return checkProgram(&node, env)
 	case ast.VariableDclStml:
-		return checkVarDecl(&node, typeEnv)
+		return checkVarDecl(&node, env)
+	case ast.FunctionDeclStmt:
+		return checkFunctionDecl(&node, env)
+	case ast.FunctionCallExpr:
+		return checkFunctionCall(&node, env)
+	case ast.FunctionExpr:
+		return checkFunctionExpr(&node, env)
+	case ast.TestStmt:
+		return checkBlock(node.Block.Items, NewTypeEnv(env))
+	case ast.BlockStmt:
+		return checkBlock(node.Items, NewTypeEnv(env))
+	case ast.IfStmt:
+		return checkIf(&node, env)
+	case ast.ForeachStmt:
+		return checkForeach(&node, env)
+	case ast.ReturnStmt:
+		if node.Expression == nil {
+			return nil, nil
+		}
+		return checkReturn(&node, env)
+	case ast.DeferStmt:
+		// the call is made when the function returns, there is none at the top level
+		if env.returnType() == nil {
+			return nil, makeTypeError(node, "defer can only be used in a function")
+		}
+		_, err := CheckType(node.Call, env)
+		return ast.VoidType{Kind: ast.T_VOID}, err
+	case ast.BinaryExpr:
+		return checkBinary(&node, env)
+	case ast.AssignmentExpr:
+		if target, ok := node.Assigne.(ast.ArrayIndexAccess); ok {
+			return checkIndexAssignment(&node, &target, env)
+		}
+		return CheckType(node.Value, env)
+	case ast.MapLiteral:
+		return checkMapLiteral(&node, env)
+	case ast.ArrayLiterals:
+		return checkArrayLiteral(&node, env)
+	case ast.ArrayIndexAccess:
+		return checkIndex(&node, env)
+	case ast.PropertyExpr:
+		return checkProperty(&node, env)
+	case ast.IdentifierExpr:
+		return env.GetVar(node.Identifier), nil
+	case ast.NumericLiteral:
+		if node.Kind == ast.FLOAT_LITERAL {
+			return ast.FloatType{Kind: ast.T_FLOAT32, BitSize: node.BitSize}, nil
+		}
+		return ast.IntegerType{BitSize: node.BitSize, IsSigned: true}, nil
+	case ast.StringLiteral:
+		return ast.StringType{Kind: ast.T_STRING}, nil
+	case ast.CharacterLiteral:
+		return ast.CharType{Kind: ast.T_CHARACTER}, nil
+	case ast.BooleanLiteral:
+		return ast.BoolType{Kind: ast.T_BOOLEAN}, nil
+	case ast.NullLiteral:
+		return ast.NullType{Kind: ast.T_NULL}, nil
+	case ast.StructLiteral:
+		return ast.StructType{Kind: ast.DATA_TYPE(node.StructName)}, nil
+	case ast.EnumDeclStatement:
+		return checkEnumDecl(&node, env)
+	case ast.MatchExpr:
+		return checkMatch(&node, env)
+	case ast.TryExpr:
+		return checkTry(&node, env)
+	case ast.TraitDeclStatement:
+		return checkTraitDecl(&node, env)
+	case ast.ImplementStatement:
+		return checkImplement(&node, env)
+	case ast.StructDeclStatement:
+		return nil, checkTypeParams(node.TypeParams, env)
 	default:
-		return nil, fmt.Errorf("Error")
+		return nil, nil
 	}
 }
 
 func checkProgram(program *ast.ProgramStmt, env *TypeEnv) (ast.Type, error) {
+
+	for _, stmt := range program.Imports {
+		if err := checkImport(&stmt, env); err != nil {
+			return nil, err
+		}
+	}
+
+	// traits and their impls are known before the calls checking the bounds,
+	// the traits come first so an impl is checked against the methods of its
+	// traits wherever they are declared
+	for _, item := range (*program).Contents {
+		if _, ok := item.(ast.TraitDeclStatement); ok {
+			if _, err := CheckType(item, env); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, item := range (*program).Contents {
+		if _, ok := item.(ast.ImplementStatement); ok {
+			if _, err := CheckType(item, env); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	for _, item := range (*program).Contents {
 		_, err := CheckType(item, env)
 		if err != nil {
@@ -83,13 +277,542 @@ func checkProgram(program *ast.ProgramStmt, env *TypeEnv) (ast.Type, error) {
 	}, nil
 }
 
+func checkImport(stmt *ast.ImportStmt, env *TypeEnv) error {
+
+	module, err := env.GetModule(stmt.ModuleName)
+
+	if err != nil {
+		// modules written in walrus are not checked yet
+		return nil
+	}
+
+	if len(stmt.Identifiers) == 0 {
+		alias := stmt.ModuleName[strings.LastIndex(stmt.ModuleName, ":")+1:]
+		env.DeclareVar(alias, module, true)
+		return nil
+	}
+
+	for _, name := range stmt.Identifiers {
+		member, ok := module.Members[name]
+		if !ok {
+			return makeTypeError(*stmt, "module '%s' has no member '%s'", stmt.ModuleName, name)
+		}
+		env.DeclareVar(name, member, true)
+	}
+
+	return nil
+}
+
+func checkBlock(items []ast.Node, env *TypeEnv) (ast.Type, error) {
+	for _, item := range items {
+		if _, err := CheckType(item, env); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func checkVarDecl(varDecl *ast.VariableDclStml, env *TypeEnv) (ast.Type, error) {
 
 	iden := varDecl.Identifier
 	valueToSet := varDecl.Value
 
-	//env.DeclareVar(iden.Identifier, valueToSet)
+	varType := varDecl.ExplicitType
+
+	if valueToSet != nil {
+		valueType, err := CheckType(valueToSet, env)
+		if err != nil {
+			return nil, err
+		}
+		if varType == nil {
+			varType = valueType
+		}
+	}
+
+	// redeclarations are reported by the evaluator with its own message
+	env.DeclareVar(iden.Identifier, varType, varDecl.IsConstant)
+
+	return ast.VoidType{Kind: ast.T_VOID}, nil
+}
+
+func checkFunctionDecl(fn *ast.FunctionDeclStmt, env *TypeEnv) (ast.Type, error) {
+
+	fnType := ast.FunctionType{
+		Kind:       ast.T_FN,
+		TypeParams: fn.TypeParams,
+		ReturnType: fn.ReturnType,
+		Parameters: fn.Parameters,
+	}
+
+	if err := checkTypeParams(fn.TypeParams, env); err != nil {
+		return nil, err
+	}
+
+	env.DeclareVar(fn.Name.Identifier, fnType, true)
+
+	scope := NewTypeEnv(env)
+	scope.returns = fn.ReturnType
+
+	for _, param := range fn.TypeParams {
+		scope.bounds[param.Name] = param.Bounds
+	}
+
+	if err := declareParams(fn.Parameters, scope); err != nil {
+		return nil, err
+	}
+
+	if _, err := checkBlock(fn.Block.Items, scope); err != nil {
+		return nil, err
+	}
+
+	return fnType, nil
+}
+
+// checkReturn checks a returned value against a type parameter the function
+// returns, a value of a concrete type is not a value of every type it stands for
+func checkReturn(ret *ast.ReturnStmt, env *TypeEnv) (ast.Type, error) {
+
+	valueType, err := CheckType(ret.Expression, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	param, ok := env.returnType().(ast.TypeParamType)
+
+	if !ok {
+		return valueType, nil
+	}
+
+	switch t := valueType.(type) {
+	case nil, ast.AnyType:
+		return valueType, nil
+	case ast.TypeParamType:
+		if t.Kind == param.Kind {
+			return valueType, nil
+		}
+	}
+
+	return nil, makeTypeError(*ret, "cannot return a value of type '%s' from a function returning '%s'", formatter.TypeName(valueType), formatter.TypeName(param))
+}
+
+// checkFunctionExpr checks the body of an anonymous function in a scope of
+// the one it is written in, the names it closes over are visible there
+func checkFunctionExpr(fn *ast.FunctionExpr, env *TypeEnv) (ast.Type, error) {
+
+	scope := NewTypeEnv(env)
+	scope.returns = fn.ReturnType
+
+	if err := declareParams(fn.Parameters, scope); err != nil {
+		return nil, err
+	}
+
+	if _, err := checkBlock(fn.Block.Items, scope); err != nil {
+		return nil, err
+	}
+
+	return ast.FunctionType{
+		Kind:       ast.T_FN,
+		ReturnType: fn.ReturnType,
+		Parameters: fn.Parameters,
+	}, nil
+}
+
+// declareParams declares the parameters in the scope of a function body. A
+// variadic parameter is an array of its type, a default value is checked
+// against the type in the scope of the parameters before it.
+func declareParams(params []ast.FunctionParameter, scope *TypeEnv) error {
 
-	fmt.Printf("var: %v, value: %v\n", iden, valueToSet)
-	return ast.VoidType{}, nil
-}
\ No newline at end of file
+	for _, param := range params {
+
+		paramType := param.Type
+
+		if param.IsVariadic {
+			paramType = ast.ArrayType{Kind: ast.T_ARRAY, ElementType: param.Type.IType()}
+		}
+
+		if param.DefaultVal != nil {
+			valueType, err := CheckType(param.DefaultVal, scope)
+			if err != nil {
+				return err
+			}
+			if !isAssignable(param.Type, valueType) {
+				return makeTypeError(param.DefaultVal, "default value of parameter %s is '%s' but its type is '%s'", param.Identifier.Identifier, formatter.TypeName(valueType), formatter.TypeName(param.Type))
+			}
+		}
+
+		scope.DeclareVar(param.Identifier.Identifier, paramType, false)
+	}
+
+	return nil
+}
+
+func checkIf(stmt *ast.IfStmt, env *TypeEnv) (ast.Type, error) {
+
+	if _, err := CheckType(stmt.Condition, env); err != nil {
+		return nil, err
+	}
+
+	if _, err := checkBlock(stmt.Block.Items, NewTypeEnv(env)); err != nil {
+		return nil, err
+	}
+
+	switch alternate := stmt.Alternate.(type) {
+	case ast.IfStmt:
+		return checkIf(&alternate, env)
+	case ast.BlockStmt:
+		return checkBlock(alternate.Items, NewTypeEnv(env))
+	}
+
+	return nil, nil
+}
+
+func checkForeach(stmt *ast.ForeachStmt, env *TypeEnv) (ast.Type, error) {
+
+	iterableType, err := CheckType(stmt.Iterable, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scope := NewTypeEnv(env)
+
+	// a map gives its keys and their values, element types of arrays are not tracked yet
+	if t, ok := iterableType.(ast.MapType); ok {
+		scope.DeclareVar(stmt.Variable, t.KeyType, false)
+		if stmt.IndexVariable != "" {
+			scope.DeclareVar(stmt.IndexVariable, t.ValueType, false)
+		}
+	} else {
+		scope.DeclareVar(stmt.Variable, nil, false)
+		if stmt.IndexVariable != "" {
+			scope.DeclareVar(stmt.IndexVariable, ast.IntegerType{Kind: ast.T_INTEGER32, BitSize: 32, IsSigned: true}, false)
+		}
+	}
+
+	if stmt.WhereClause != nil {
+		if _, err := CheckType(stmt.WhereClause, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	return checkBlock(stmt.Block.Items, scope)
+}
+
+func checkBinary(expr *ast.BinaryExpr, env *TypeEnv) (ast.Type, error) {
+
+	leftType, err := CheckType(expr.Left, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rightType, err := CheckType(expr.Right, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch expr.Operator.Value {
+	case "in":
+		return checkMembership(expr, leftType, rightType, env)
+	case "==", "!=", "<", ">", "<=", ">=", "&&", "||":
+		return ast.BoolType{Kind: ast.T_BOOLEAN}, nil
+	case "+", "-", "*", "/", "%", "^":
+		return arithmeticType(leftType, rightType), nil
+	}
+
+	return nil, nil
+}
+
+// arithmeticType is the type the evaluator gives the result of an arithmetic
+// operator. The left operand decides between an integer and a float, the wider
+// operand gives the size, and a string on the left joins anything to it.
+func arithmeticType(left ast.Type, right ast.Type) ast.Type {
+
+	if _, ok := left.(ast.StringType); ok {
+		return ast.StringType{Kind: ast.T_STRING}
+	}
+
+	if !isNumeric(left) || !isNumeric(right) {
+		return nil
+	}
+
+	size := uint8(utils.Max(int(bitSize(left)), int(bitSize(right))))
+
+	if _, ok := left.(ast.FloatType); ok {
+		return ast.FloatType{Kind: ast.DATA_TYPE(fmt.Sprintf("f%d", size)), BitSize: size}
+	}
+
+	return ast.IntegerType{Kind: ast.DATA_TYPE(fmt.Sprintf("i%d", size)), BitSize: size, IsSigned: true}
+}
+
+func bitSize(t ast.Type) uint8 {
+	switch t := t.(type) {
+	case ast.IntegerType:
+		return t.BitSize
+	case ast.FloatType:
+		return t.BitSize
+	}
+	return 0
+}
+
+// checkArrayLiteral checks the elements of an array literal. Its elements have
+// their common type, the widest one when they are all integers, any otherwise.
+func checkArrayLiteral(literal *ast.ArrayLiterals, env *TypeEnv) (ast.Type, error) {
+
+	var elementType ast.Type
+
+	for i, element := range literal.Elements {
+
+		t, err := CheckType(element, env)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t == nil:
+			elementType = ast.AnyType{Kind: ast.T_ANY}
+		case i == 0:
+			elementType = t
+		case elementType.IType() == t.IType():
+		case isInteger(elementType) && isInteger(t):
+			elementType = arithmeticType(elementType, t)
+		default:
+			elementType = ast.AnyType{Kind: ast.T_ANY}
+		}
+	}
+
+	if elementType == nil {
+		elementType = ast.AnyType{Kind: ast.T_ANY}
+	}
+
+	return ast.ArrayType{Kind: ast.T_ARRAY, ElementType: elementType.IType()}, nil
+}
+
+func isInteger(t ast.Type) bool {
+	_, ok := t.(ast.IntegerType)
+	return ok
+}
+
+func checkProperty(expr *ast.PropertyExpr, env *TypeEnv) (ast.Type, error) {
+
+	objectType, err := CheckType(expr.Object, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if enum, ok := objectType.(ast.EnumType); ok {
+		return checkVariant(expr, enum)
+	}
+
+	module, ok := objectType.(ast.ModuleType)
+
+	if !ok {
+		return nil, nil
+	}
+
+	member, ok := module.Members[expr.Property.Identifier]
+
+	if !ok {
+		return nil, makeTypeError(*expr, "module '%s' has no member '%s'", module.Name, expr.Property.Identifier)
+	}
+
+	return member, nil
+}
+
+func checkFunctionCall(call *ast.FunctionCallExpr, env *TypeEnv) (ast.Type, error) {
+
+	callerType, err := CheckType(call.Caller, env)
+
+	if err != nil {
+		return nil, err
+	}
+
+	argTypes := make([]ast.Type, len(call.Args))
+
+	for i, arg := range call.Args {
+		argTypes[i], err = CheckType(arg, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	namedTypes := make([]ast.Type, len(call.NamedArgs))
+
+	for i, arg := range call.NamedArgs {
+		namedTypes[i], err = CheckType(arg.Value, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fnType, ok := callerType.(ast.FunctionType)
+
+	if !ok {
+		return nil, nil
+	}
+
+	name := call.CallerName()
+
+	if len(call.NamedArgs) > 0 && fnType.Kind == ast.T_NATIVE_FN {
+		return nil, makeTypeError(*call, "function '%s' does not take named arguments", name)
+	}
+
+	if len(fnType.TypeParams) > 0 {
+		if fnType, err = instantiate(call, fnType, argTypes, namedTypes, env); err != nil {
+			return nil, err
+		}
+	}
+
+	params := fnType.Parameters
+
+	positional := len(params)
+	isVariadic := len(params) > 0 && params[len(params)-1].IsVariadic
+
+	if isVariadic {
+		positional--
+	}
+
+	if len(call.NamedArgs) == 0 || !isVariadic && len(argTypes) > positional {
+
+		fewest, most := ast.Arity(params)
+		count := len(argTypes) + len(namedTypes)
+
+		switch {
+		case most < 0 && count < fewest:
+			return nil, makeTypeError(*call, "function '%s' expects at least %d arguments but %d were provided", name, fewest, count)
+		case most >= 0 && fewest == most && count != most:
+			return nil, makeTypeError(*call, "function '%s' expects %d arguments but %d were provided", name, most, count)
+		case most >= 0 && (count < fewest || count > most):
+			return nil, makeTypeError(*call, "function '%s' expects %d to %d arguments but %d were provided", name, fewest, most, count)
+		}
+	}
+
+	for i, argType := range argTypes {
+
+		param := params[utils.Min(i, len(params)-1)].Type
+
+		if !env.accepts(param, argType) {
+			return nil, makeTypeError(call.Args[i], "function '%s' expects argument %d to be of type '%s' but got '%s'", name, i+1, formatter.TypeName(param), formatter.TypeName(argType))
+		}
+	}
+
+	given := make([]bool, len(params))
+
+	for i := range argTypes {
+		given[utils.Min(i, len(params)-1)] = true
+	}
+
+	for j, arg := range call.NamedArgs {
+
+		i := ast.ParamIndex(params, arg.Name.Identifier)
+
+		switch {
+		case i < 0:
+			return nil, makeTypeError(arg.Name, "function '%s' has no parameter named %s", name, arg.Name.Identifier)
+		case params[i].IsVariadic:
+			return nil, makeTypeError(arg.Name, "variadic parameter %s cannot be given by name", arg.Name.Identifier)
+		case given[i]:
+			return nil, makeTypeError(arg.Name, "parameter %s of '%s' is given twice", arg.Name.Identifier, name)
+		}
+
+		given[i] = true
+
+		if !env.accepts(params[i].Type, namedTypes[j]) {
+			return nil, makeTypeError(arg.Value, "function '%s' expects parameter %s to be of type '%s' but got '%s'", name, arg.Name.Identifier, formatter.TypeName(params[i].Type), formatter.TypeName(namedTypes[j]))
+		}
+	}
+
+	for i, param := range params {
+		if !given[i] && param.DefaultVal == nil && !param.IsVariadic {
+			return nil, makeTypeError(*call, "function '%s' is missing a value for parameter %s", name, param.Identifier.Identifier)
+		}
+	}
+
+	return fnType.ReturnType, nil
+}
+
+// accepts tells if an argument can be given to a parameter, a trait names
+// every type implementing it
+func (t *TypeEnv) accepts(param ast.Type, arg ast.Type) bool {
+
+	if structType, ok := param.(ast.StructType); ok && t.hasTrait(string(structType.Kind)) && arg != nil && t.implements(arg, string(structType.Kind)) {
+		return true
+	}
+
+	return isAssignable(param, arg)
+}
+
+// isAssignable follows the same rules the evaluator applies to native arguments
+func isAssignable(param ast.Type, arg ast.Type) bool {
+
+	if param == nil || arg == nil {
+		return true
+	}
+
+	// the type a parameter stands for is checked where it is inferred
+	if _, ok := arg.(ast.TypeParamType); ok {
+		return true
+	}
+
+	switch t := param.(type) {
+	case ast.AnyType, ast.TypeParamType:
+		return true
+	case ast.FunctionType:
+		argFn, ok := arg.(ast.FunctionType)
+		if !ok {
+			return false
+		}
+		// natives like fs.walk take any function, they have no return type
+		if t.ReturnType == nil || argFn.ReturnType == nil {
+			return true
+		}
+		if len(t.Parameters) != len(argFn.Parameters) {
+			return false
+		}
+		for i, param := range t.Parameters {
+			if !sameType(param.Type, argFn.Parameters[i].Type) {
+				return false
+			}
+		}
+		return sameType(t.ReturnType, argFn.ReturnType)
+	case ast.IntegerType:
+		// integers widen to a larger parameter without loss
+		if argInt, ok := arg.(ast.IntegerType); ok {
+			return argInt.BitSize <= t.BitSize
+		}
+		return false
+	case ast.FloatType:
+		if argFloat, ok := arg.(ast.FloatType); ok {
+			return argFloat.BitSize <= t.BitSize
+		}
+		return false
+	case ast.MapType:
+		// maps of other key or value types are other types, natives take any of them
+		argMap, ok := arg.(ast.MapType)
+		return ok && sameType(t.KeyType, argMap.KeyType) && sameType(t.ValueType, argMap.ValueType)
+	}
+
+	return param.IType() == arg.IType()
+}
+
+// sameType compares the types of function signatures, any matches every type
+func sameType(a ast.Type, b ast.Type) bool {
+
+	if _, ok := a.(ast.AnyType); ok {
+		return true
+	}
+
+	if _, ok := b.(ast.AnyType); ok {
+		return true
+	}
+
+	if fnA, ok := a.(ast.FunctionType); ok {
+		return isAssignable(fnA, b)
+	}
+
+	return a.IType() == b.IType()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"walrus/builtins"
+	"walrus/tester"
+	"walrus/utils"
+)
+
+// runTest runs the tests of walrus files, walrus test [flags] [file or dir ...].
+// Without paths it looks for tests below the current directory. It exits with 1
+// when a test did not pass, --junit writes the results for CI as well.
+func runTest(args []string) {
+
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	run := flags.String("run", "", "run only the tests whose name matches the regular expression")
+	parallel := flags.Int("parallel", 1, "how many tests run at once")
+	timeout := flags.Duration("timeout", 10*time.Second, "the time a test may take, 0 for no limit")
+	junit := flags.String("junit", "", "write the results as JUnit XML to this file")
+	verbose := flags.Bool("v", false, "list every test and what it printed")
+	allowRead, allowWrite := sandboxFlags(flags)
+	flags.Parse(args)
+
+	fsCapability := builtins.NewFsCapability()
+	allowDirs(fsCapability, allowRead, allowWrite)
+
+	opts := tester.Options{
+		Parallel:     *parallel,
+		Timeout:      *timeout,
+		FsCapability: fsCapability,
+	}
+
+	if *run != "" {
+		filter, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Println(utils.Colorize(utils.RED, fmt.Sprintf("--run: %s", err)))
+			os.Exit(1)
+		}
+		opts.Filter = filter
+	}
+
+	paths := flags.Args()
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := tester.Discover(paths)
+
+	if err != nil {
+		reportError(err)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+
+	results := tester.Run(files, opts)
+
+	counts := map[tester.Status]int{}
+
+	for _, result := range results {
+		counts[result.Status]++
+		printResult(result, *verbose)
+	}
+
+	if *junit != "" {
+		if err := writeJUnit(*junit, results); err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+	}
+
+	if len(results) == 0 {
+		fmt.Println("no tests to run")
+		return
+	}
+
+	summary := fmt.Sprintf("%d passed, %d failed, %d errors in %v", counts[tester.PASS], counts[tester.FAIL], counts[tester.ERROR], time.Since(start).Round(time.Millisecond))
+
+	if counts[tester.PASS] < len(results) {
+		fmt.Println(utils.Colorize(utils.RED, "FAIL ") + summary)
+		os.Exit(1)
+	}
+
+	fmt.Println(utils.Colorize(utils.GREEN, "PASS ") + summary)
+}
+
+// printResult prints a test which did not pass with its report and output, every test with -v
+func printResult(result tester.Result, verbose bool) {
+
+	if result.Status == tester.PASS && !verbose {
+		return
+	}
+
+	status := map[tester.Status]string{
+		tester.PASS:  utils.Colorize(utils.GREEN, "--- PASS"),
+		tester.FAIL:  utils.Colorize(utils.RED, "--- FAIL"),
+		tester.ERROR: utils.Colorize(utils.RED, "--- ERROR"),
+	}[result.Status]
+
+	if result.Name == "" {
+		fmt.Printf("%s: %s\n", status, result.File)
+	} else {
+		fmt.Printf("%s: %s (%s, %v)\n", status, result.Name, result.File, result.Duration.Round(time.Microsecond))
+	}
+
+	if result.Report != "" {
+		fmt.Println(strings.TrimRight(result.Report, "\n"))
+	}
+
+	if result.Output != "" && (verbose || result.Status != tester.PASS) {
+		fmt.Print(indent(result.Output))
+	}
+}
+
+func indent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+func writeJUnit(path string, results []tester.Result) error {
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := tester.WriteJUnit(file, results); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,136 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"walrus/utils"
+)
+
+// the JUnit XML format CI servers read, one suite a file
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut *junitText    `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// WriteJUnit writes the results as JUnit XML. A file which could not be
+// loaded is a suite with a single errored case named after the file.
+func WriteJUnit(w io.Writer, results []Result) error {
+
+	suites := junitSuites{}
+
+	var total time.Duration
+
+	// the suites by file and the time each took
+	index := map[string]int{}
+	var durations []time.Duration
+
+	for _, result := range results {
+
+		i, ok := index[result.File]
+
+		if !ok {
+			i = len(suites.Suites)
+			index[result.File] = i
+			suites.Suites = append(suites.Suites, junitSuite{Name: result.File})
+			durations = append(durations, 0)
+		}
+
+		suite := &suites.Suites[i]
+
+		name := result.Name
+
+		if name == "" {
+			name = result.File
+		}
+
+		testCase := junitCase{
+			Name:      name,
+			ClassName: result.File,
+			Time:      seconds(result.Duration),
+		}
+
+		if result.Output != "" {
+			testCase.SystemOut = &junitText{Text: result.Output}
+		}
+
+		problem := &junitProblem{Message: result.Message, Body: utils.Decolorize(result.Report)}
+
+		switch result.Status {
+		case FAIL:
+			testCase.Failure = problem
+			suite.Failures++
+		case ERROR:
+			testCase.Error = problem
+			suite.Errors++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+
+		durations[i] += result.Duration
+		total += result.Duration
+	}
+
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.Time = seconds(durations[i])
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+	}
+
+	suites.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,357 @@
+// Package tester runs the tests of walrus programs, the test fn declarations
+// of a file. Every test runs in an environment of its own: the file is
+// evaluated afresh, then the body of the test, so tests can not see what
+// other tests changed and may run in parallel.
+package tester
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"walrus/builtins"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+	"walrus/tc"
+	"walrus/typechecker"
+)
+
+type Status string
+
+const (
+	PASS Status = "pass"
+	// an assertion failed
+	FAIL Status = "fail"
+	// the test could not run to its end, the file did not parse or an error was raised
+	ERROR Status = "error"
+)
+
+// Result is the outcome of a test
+type Result struct {
+	File string
+	// the test, empty for a file which could not be loaded
+	Name   string
+	Status Status
+	// the error of a test which did not pass
+	Message string
+	// the report of the error with the source line, in colors
+	Report string
+	// what the test printed
+	Output   string
+	Duration time.Duration
+}
+
+type Options struct {
+	// runs the tests whose name matches, nil runs every test
+	Filter *regexp.Regexp
+	// how many tests run at once, one after another below 2
+	Parallel int
+	// the time a test may take, zero for no limit
+	Timeout time.Duration
+	// the directories the tests may touch
+	FsCapability *builtins.FsCapability
+}
+
+// Discover returns the test files of paths. A file given by name is always a
+// test file, a directory contributes its _test.wal files and the .wal files
+// declaring a test fn.
+func Discover(paths []string) ([]string, error) {
+
+	var files []string
+
+	for _, path := range paths {
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || filepath.Ext(file) != ".wal" {
+				return nil
+			}
+			if strings.HasSuffix(file, "_test.wal") || declaresTests(file) {
+				files = append(files, file)
+			}
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// declaresTests looks for test fn in the tokens of a file, files which do not tokenize have no tests
+func declaresTests(file string) bool {
+
+	source, err := os.ReadFile(file)
+
+	if err != nil {
+		return false
+	}
+
+	tokens, _, err := lexer.Tokenize(string(source), file, false)
+
+	if err != nil {
+		return false
+	}
+
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind == lexer.IDENTIFIER_TOKEN && tokens[i].Value == "test" && tokens[i+1].Kind == lexer.FUNCTION_TOKEN {
+			return true
+		}
+	}
+
+	return false
+}
+
+// a test of a loaded file waiting to run
+type job struct {
+	file *testFile
+	test *ast.TestStmt
+	// where its result goes
+	index int
+}
+
+type testFile struct {
+	path    string
+	program ast.ProgramStmt
+	parser  *parser.Parser
+}
+
+// Run runs the tests of the files and returns their results in the order the
+// files and the tests are written, whatever order they ran in
+func Run(files []string, opts Options) []Result {
+
+	var results []Result
+	var jobs []*job
+
+	for _, path := range files {
+
+		file, tests, err := load(path)
+
+		if err != nil {
+			results = append(results, Result{File: path, Status: ERROR, Message: message(err), Report: report(err)})
+			continue
+		}
+
+		// a _test.wal file without test fn is a test itself
+		if len(tests) == 0 && strings.HasSuffix(path, "_test.wal") {
+			tests = []*ast.TestStmt{nil}
+		}
+
+		for _, test := range tests {
+			if opts.Filter != nil && !opts.Filter.MatchString(testName(file, test)) {
+				continue
+			}
+			jobs = append(jobs, &job{file: file, test: test, index: len(results)})
+			results = append(results, Result{})
+		}
+	}
+
+	workers := opts.Parallel
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan *job)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				results[j.index] = runTest(j.file, j.test, opts)
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		queue <- j
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// load parses and checks a test file and returns its tests
+func load(path string) (file *testFile, tests []*ast.TestStmt, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoveredError(recovered)
+		}
+	}()
+
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := parser.NewParserFromSource(string(source), path, false)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.PanicOnError = true
+
+	program := p.Parse()
+
+	globalTypes := tc.NewTypeEnv(nil)
+	builtins.DeclareTypes(globalTypes)
+
+	if _, err := tc.CheckType(program, tc.NewTypeEnv(globalTypes)); err != nil {
+		if typeErr, ok := err.(tc.TypeError); ok {
+			return nil, nil, parser.MakeError(p, typeErr.Start.Line, path, typeErr.Start, typeErr.End, typeErr.Message)
+		}
+		return nil, nil, err
+	}
+
+	for _, node := range program.Contents {
+		if test, ok := node.(ast.TestStmt); ok {
+			test := test
+			tests = append(tests, &test)
+		}
+	}
+
+	return &testFile{path: path, program: program, parser: p}, tests, nil
+}
+
+// testName is the name of a test fn, or of the file which is a test itself
+func testName(file *testFile, test *ast.TestStmt) string {
+	if test == nil {
+		return strings.TrimSuffix(filepath.Base(file.path), ".wal")
+	}
+	return test.Name.Identifier
+}
+
+// runTest evaluates the file in a fresh environment, then the test
+func runTest(file *testFile, test *ast.TestStmt, opts Options) (result Result) {
+
+	result = Result{File: file.path, Name: testName(file, test), Status: PASS}
+
+	var output bytes.Buffer
+
+	start := time.Now()
+
+	limits := typechecker.DefaultLimits
+
+	if opts.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+		limits.Context = ctx
+	}
+
+	defer func() {
+
+		result.Duration = time.Since(start)
+		result.Output = output.String()
+
+		if recovered := recover(); recovered != nil {
+
+			err := recoveredError(recovered)
+
+			var assertion *builtins.AssertionError
+
+			if errors.As(err, &assertion) {
+				result.Status = FAIL
+			} else {
+				result.Status = ERROR
+			}
+
+			result.Message = message(err)
+			result.Report = report(err)
+		}
+	}()
+
+	fsCapability := opts.FsCapability
+
+	if fsCapability == nil {
+		fsCapability = builtins.NewFsCapability()
+	}
+
+	globals := typechecker.NewEnvironment(nil, file.parser)
+	builtins.Declare(globals, fsCapability)
+	globals.SetVariable("print", builtins.Print(&output), true)
+
+	env := typechecker.NewEnvironment(globals, file.parser)
+	env.SetLimits(limits)
+
+	typechecker.Evaluate(file.program, env)
+
+	if test != nil {
+		typechecker.RunTest(*test, env)
+	}
+
+	return result
+}
+
+func recoveredError(recovered interface{}) error {
+	if err, ok := recovered.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", recovered)
+}
+
+// message is an error without its position
+func message(err error) string {
+
+	var message *parser.ErrorMessage
+	var runtimeErr *typechecker.RuntimeError
+
+	switch {
+	case errors.As(err, &message):
+		return strings.TrimSpace(message.Text)
+	case errors.As(err, &runtimeErr):
+		return runtimeErr.Message
+	}
+
+	return err.Error()
+}
+
+// report renders an error with its source line and trace, like the cli prints it
+func report(err error) string {
+
+	var message *parser.ErrorMessage
+	var lexErr *lexer.LexError
+	var runtimeErr *typechecker.RuntimeError
+
+	switch {
+	case errors.As(err, &message):
+		return message.Report()
+	case errors.As(err, &lexErr):
+		return lexErr.Report
+	case errors.As(err, &runtimeErr):
+		return fmt.Sprintf("Error: %s\n%s", err, parser.FormatTrace(runtimeErr.Trace))
+	}
+
+	return fmt.Sprintf("Error: %s\n", err)
+}
@@ -0,0 +1,150 @@
+package tester
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"walrus/utils"
+)
+
+const source = `let count := 0;
+
+fn fails() {
+    let values := [1];
+    print(values[1]);
+}
+
+test fn passes() {
+    count = count + 1;
+    assertEq(count, 1);
+}
+
+test fn isolated() {
+    assertEq(count, 0);
+}
+
+test fn failsEq() {
+    print("before");
+    assertEq(count, 2);
+}
+
+test fn errors() {
+    fails();
+}
+
+test fn expectsError() {
+    assertErr(fails, "invalid index range");
+}
+`
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+
+	dir := t.TempDir()
+
+	writeFile(t, dir, "math.wal", source)
+	writeFile(t, dir, "plain_test.wal", "assert(true);\n")
+	writeFile(t, dir, "broken_test.wal", "let x := ;\n")
+	writeFile(t, dir, "helpers.wal", "fn helper() {}\n")
+
+	files, err := Discover([]string{dir})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for parallel := 1; parallel <= 4; parallel += 3 {
+
+		results := Run(files, Options{Parallel: parallel})
+
+		got := map[string]Result{}
+
+		for _, result := range results {
+			got[filepath.Base(result.File)+"/"+result.Name] = result
+		}
+
+		want := map[string]Status{
+			"broken_test.wal/":          ERROR,
+			"math.wal/passes":           PASS,
+			"math.wal/isolated":         PASS,
+			"math.wal/failsEq":          FAIL,
+			"math.wal/errors":           ERROR,
+			"math.wal/expectsError":     PASS,
+			"plain_test.wal/plain_test": PASS,
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("parallel %d: got %d results, want %d", parallel, len(got), len(want))
+		}
+
+		for name, status := range want {
+			if got[name].Status != status {
+				t.Errorf("parallel %d: %s is %s, want %s: %s", parallel, name, got[name].Status, status, got[name].Message)
+			}
+		}
+
+		failed := got["math.wal/failsEq"]
+
+		if failed.Message != "assertEq: expected 2 (i32) but got 0 (i32)" {
+			t.Errorf("failsEq message: %q", failed.Message)
+		}
+
+		if failed.Output != "before\n" {
+			t.Errorf("failsEq output: %q", failed.Output)
+		}
+
+		if !strings.Contains(utils.Decolorize(failed.Report), "assertEq(count, 2);") {
+			t.Errorf("failsEq report has no source line:\n%s", failed.Report)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+
+	path := writeFile(t, t.TempDir(), "math.wal", source)
+
+	results := Run([]string{path}, Options{Filter: regexp.MustCompile("^(passes|isolated)$")})
+
+	if len(results) != 2 || results[0].Name != "passes" || results[1].Name != "isolated" {
+		t.Errorf("filtered results: %+v", results)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+
+	path := writeFile(t, t.TempDir(), "math.wal", source)
+
+	var out bytes.Buffer
+
+	if err := WriteJUnit(&out, Run([]string{path}, Options{})); err != nil {
+		t.Fatal(err)
+	}
+
+	xml := out.String()
+
+	for _, want := range []string{
+		`<testsuites tests="5" failures="1" errors="1"`,
+		`<failure message="assertEq: expected 2 (i32) but got 0 (i32)">`,
+		`<system-out><![CDATA[before`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("junit output lacks %s:\n%s", want, xml)
+		}
+	}
+
+	if strings.Contains(xml, "\033[") {
+		t.Errorf("junit output has colors:\n%s", xml)
+	}
+}
@@ -0,0 +1,73 @@
+package typechecker
+
+import (
+	"walrus/frontend/ast"
+)
+
+// Frame is a walrus function call on the stack of a run
+type Frame struct {
+	// the name of the called function, "<program>" for the top level of a program
+	Name string
+	// the call expression, nil for the program and for calls made by natives
+	Call ast.Node
+	// the statement the frame evaluates, nil before its first one
+	Stmt ast.Node
+	// the innermost scope the frame evaluates in, its parents lead through
+	// the scope of the call and the declaring scopes to the globals
+	Env *Environment
+}
+
+// the name of the frame running the top level statements of a program
+const programFrame = "<program>"
+
+// Debugger is told before each statement of a run is evaluated.
+// The call blocks the program, a debugger pauses it by not returning.
+type Debugger interface {
+	Statement(stmt ast.Node, env *Environment)
+}
+
+// SetDebugger installs a debugger on this environment and every scope sharing its execution
+func (e *Environment) SetDebugger(debugger Debugger) {
+	e.exec.debugger = debugger
+}
+
+// Stack returns the frames of the run, the innermost call last
+func (e *Environment) Stack() []Frame {
+	return append([]Frame(nil), e.exec.frames...)
+}
+
+// Parent returns the enclosing scope, nil for the globals
+func (e *Environment) Parent() *Environment {
+	return e.parent
+}
+
+// Variables returns the variables declared in this scope, not in its parents
+func (e *Environment) Variables() map[string]RuntimeValue {
+	return e.variables
+}
+
+// IsConstant tells if a variable of this scope was declared with const
+func (e *Environment) IsConstant(name string) bool {
+	return e.constants[name]
+}
+
+// statement records a statement about to be evaluated in the innermost frame and hands it to the debugger
+func (e *Environment) statement(stmt ast.Node) {
+
+	if frames := e.exec.frames; len(frames) > 0 {
+		frames[len(frames)-1].Stmt = stmt
+		frames[len(frames)-1].Env = e
+	}
+
+	if debugger := e.exec.debugger; debugger != nil {
+		debugger.Statement(stmt, e)
+	}
+}
+
+func (e *Environment) pushFrame(name string, call ast.Node, scope *Environment) {
+	e.exec.frames = append(e.exec.frames, Frame{Name: name, Call: call, Env: scope})
+}
+
+func (e *Environment) popFrame() {
+	e.exec.frames = e.exec.frames[:len(e.exec.frames)-1]
+}
@@ -0,0 +1,56 @@
+package typechecker
+
+import "walrus/frontend/ast"
+
+// deferredCall is the call of a defer statement. The statement evaluates the
+// function and the arguments, the call is made when the function returns.
+type deferredCall struct {
+	expr  ast.FunctionCallExpr
+	fn    RuntimeValue
+	args  []RuntimeValue
+	named []namedArgument
+	env   *Environment
+}
+
+// EvaluateDeferStmt leaves a call to the function body being evaluated
+func EvaluateDeferStmt(stmt ast.DeferStmt, env *Environment) RuntimeValue {
+
+	bodies := env.exec.bodies
+
+	if len(bodies) == 0 {
+		env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, "defer can only be used in a function").Display()
+	}
+
+	args, named := evaluateArguments(stmt.Call, env)
+
+	fn := Evaluate(stmt.Call.Caller, env)
+
+	bodies[len(bodies)-1] = append(bodies[len(bodies)-1], deferredCall{
+		expr:  stmt.Call,
+		fn:    fn,
+		args:  args,
+		named: named,
+		env:   env,
+	})
+
+	return MakeVOID()
+}
+
+func (e *Environment) enterBody() {
+	e.exec.bodies = append(e.exec.bodies, nil)
+}
+
+// leaveBody ends the evaluation of a function body and returns the calls it deferred
+func (e *Environment) leaveBody() []deferredCall {
+	bodies := e.exec.bodies
+	e.exec.bodies = bodies[:len(bodies)-1]
+	return bodies[len(bodies)-1]
+}
+
+// runDeferred makes the deferred calls of a body, the last deferred first
+func runDeferred(calls []deferredCall) {
+	for i := len(calls) - 1; i >= 0; i-- {
+		call := calls[i]
+		callValue(call.expr, call.fn, call.args, call.named, call.env)
+	}
+}
@@ -0,0 +1,161 @@
+package typechecker
+
+import (
+	"fmt"
+	"walrus/frontend/ast"
+)
+
+func EvaluateEnumDeclarationStmt(stmt ast.EnumDeclStatement, env *Environment) RuntimeValue {
+
+	enum := EnumValue{
+		Name:       stmt.EnumName,
+		TypeParams: stmt.TypeParams,
+		Variants:   stmt.Variants,
+		Type:       ast.T_ENUM,
+	}
+
+	env.structs[stmt.EnumName] = enum
+
+	// the name of the enum is a value, Shape.Circle reaches a variant through it
+	if _, err := env.DeclareVariable(stmt.EnumName, enum, true); err != nil {
+		env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, err.Error()).Display()
+	}
+
+	return MakeVOID()
+}
+
+// evaluateVariant returns the variant of an enum named by Enum.Variant, the
+// value itself for a plain variant and its constructor for one with a payload
+func evaluateVariant(enum EnumValue, expr ast.PropertyExpr, env *Environment) RuntimeValue {
+
+	variant, ok := enum.Variant(expr.Property.Identifier)
+
+	if !ok {
+		env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("enum '%s' has no variant '%s'", enum.Name, expr.Property.Identifier)).Display()
+	}
+
+	if len(variant.Fields) == 0 {
+		return EnumInstance{EnumName: enum.Name, Variant: variant.Name}
+	}
+
+	signature := NativeSignature{
+		Parameters: make([]ast.Type, len(variant.Fields)),
+		ReturnType: ast.StructType{Kind: ast.DATA_TYPE(enum.Name)},
+	}
+
+	for i, field := range variant.Fields {
+		signature.Parameters[i] = field.Type
+	}
+
+	return MakeNativeFUNCTION(func(args ...RuntimeValue) (RuntimeValue, error) {
+		fields := make([]RuntimeValue, len(args))
+		for i, arg := range args {
+			fields[i] = convertNumber(arg, variant.Fields[i].Type)
+		}
+		env.allocate(expr, structBytes(len(fields)))
+		return EnumInstance{EnumName: enum.Name, Variant: variant.Name, Fields: fields}, nil
+	}, signature)
+}
+
+// convertNumber gives a number the size of the type it is stored as, the
+// arguments of a native may be smaller than its parameters
+func convertNumber(value RuntimeValue, to ast.Type) RuntimeValue {
+	switch t := to.(type) {
+	case ast.IntegerType:
+		if v, ok := value.(IntegerValue); ok {
+			return MakeINT(v.Value, t.BitSize, t.IsSigned)
+		}
+	case ast.FloatType:
+		if v, ok := value.(FloatValue); ok {
+			return MakeFLOAT(v.Value, t.BitSize)
+		}
+	}
+	return value
+}
+
+// makeDefaultVariant is the zero value of an enum, its first variant with a zero payload
+func makeDefaultVariant(env *Environment, enum EnumValue, typeArgs []ast.Type) RuntimeValue {
+
+	variant := enum.Variants[0]
+
+	fields := make([]RuntimeValue, len(variant.Fields))
+
+	args := bindTypeArgs(enum.TypeParams, typeArgs)
+
+	for i, field := range variant.Fields {
+		fields[i] = makeDefaultField(env, substituteType(field.Type, args), enum.Name)
+	}
+
+	return EnumInstance{EnumName: enum.Name, Variant: variant.Name, Fields: fields}
+}
+
+// EvaluateMatchExpr evaluates the body of the first arm matching the subject.
+// The names a pattern binds are declared in a scope of the arm, its guard sees them.
+func EvaluateMatchExpr(expr ast.MatchExpr, env *Environment) RuntimeValue {
+
+	subject := Evaluate(expr.Subject, env)
+
+	for _, arm := range expr.Arms {
+
+		scope := NewEnvironment(env, env.parser)
+
+		if !matchPattern(arm.Pattern, subject, scope) {
+			continue
+		}
+
+		if arm.Guard != nil && !IsTruthy(Evaluate(arm.Guard, scope)) {
+			continue
+		}
+
+		return Evaluate(arm.Body, scope)
+	}
+
+	start, end := expr.Subject.GetPos()
+
+	env.makeError(expr.StartPos.Line, start, end, fmt.Sprintf("no arm of the match matches %s", FormatValue(subject))).Display()
+
+	return nil
+}
+
+// matchPattern tells if a value matches a pattern and declares the names it binds in the scope
+func matchPattern(pattern ast.Node, value RuntimeValue, scope *Environment) bool {
+
+	switch p := pattern.(type) {
+	case ast.IdentifierExpr:
+		if p.Identifier == "_" {
+			return true
+		}
+		// a plain variant of the enum matched, otherwise a binding
+		if instance, ok := value.(EnumInstance); ok && isVariantOf(scope, instance.EnumName, p.Identifier) {
+			return instance.Variant == p.Identifier
+		}
+		scope.DeclareVariable(p.Identifier, value, false)
+		return true
+	case ast.VariantPattern:
+		instance, ok := value.(EnumInstance)
+		if !ok || instance.Variant != p.Variant || (p.EnumName != "" && p.EnumName != instance.EnumName) {
+			return false
+		}
+		for i, field := range p.Fields {
+			if i >= len(instance.Fields) || !matchPattern(field, instance.Fields[i], scope) {
+				return false
+			}
+		}
+		return true
+	default:
+		return Equal(Evaluate(pattern, scope), value)
+	}
+}
+
+func isVariantOf(env *Environment, enumName string, name string) bool {
+	declared, err := env.GetStructType(enumName)
+	if err != nil {
+		return false
+	}
+	enum, ok := declared.(EnumValue)
+	if !ok {
+		return false
+	}
+	_, ok = enum.Variant(name)
+	return ok
+}
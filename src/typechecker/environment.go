@@ -12,16 +12,38 @@ type Environment struct {
 	constants map[string]bool
 	//user defined types declared with struct keyword
 	structs map[string]RuntimeValue
+	//native modules that can be imported by name, e.g. "core::fs"
+	modules map[string]ModuleValue
+	//traits each type implements, by the name of the type
+	impls map[string]map[string]bool
+	//methods the impls give each type, by the name of the type
+	methods map[string]map[string]FunctionValue
+	//types the type parameters of a generic function are bound to in a call
+	typeArgs map[string]ast.DATA_TYPE
 	parser    *parser.Parser
+	//limits and counters of the run, shared with the parent scope
+	exec *execution
 }
 
 func NewEnvironment(parent *Environment, p *parser.Parser) *Environment {
+
+	exec := &execution{limits: DefaultLimits}
+
+	if parent != nil {
+		exec = parent.exec
+	}
+
 	return &Environment{
 		parent:    parent,
 		variables: make(map[string]RuntimeValue),
 		constants: make(map[string]bool),
 		structs:   make(map[string]RuntimeValue),
+		modules:   make(map[string]ModuleValue),
+		impls:     make(map[string]map[string]bool),
+		methods:   make(map[string]map[string]FunctionValue),
+		typeArgs:  make(map[string]ast.DATA_TYPE),
 		parser:    p,
+		exec:      exec,
 	}
 }
 
@@ -45,9 +67,20 @@ func (e *Environment) DeclareVariable(name string, value RuntimeValue, isConstan
 	return value, nil
 }
 
+// SetVariable declares a variable or replaces its value in this scope without the assignment checks.
+// Hosts use it for globals they update between calls.
+func (e *Environment) SetVariable(name string, value RuntimeValue, isConstant bool) {
+	e.variables[name] = value
+	e.constants[name] = isConstant
+}
+
 func declareStruct(e *Environment, v *StructInstance) error {
 	// check all fields are initialized
-	structDeclaration := e.structs[v.StructName] // v is the provided value
+	structDeclaration, err := e.GetStructType(v.StructName) // v is the provided value
+
+	if err != nil {
+		return err
+	}
 
 	//check if provided field exist on struct
 	allFields := structDeclaration.(StructValue).Fields
@@ -106,7 +139,7 @@ func (e *Environment) AssignVariable(name string, value RuntimeValue) (RuntimeVa
 	return value, nil
 }
 
-func (e *Environment) DeclareFunction(name string, returnType ast.Type, parameters []ast.FunctionParameter, body ast.BlockStmt) error {
+func (e *Environment) DeclareFunction(name string, typeParams []ast.TypeParam, returnType ast.Type, parameters []ast.FunctionParameter, body ast.BlockStmt) error {
 
 	if e.variables[name] != nil {
 		return fmt.Errorf("identifier (function) %s already declared in this scope", name)
@@ -119,6 +152,7 @@ func (e *Environment) DeclareFunction(name string, returnType ast.Type, paramete
 		Type: 		ast.T_FN,
 		ReturnType: returnType.IType(),
 		DeclarationEnv: e,
+		TypeParams: typeParams,
 	}
 
 	e.constants[name] = true
@@ -139,6 +173,50 @@ func (e *Environment) DeclareNativeFn(name string, fn RuntimeValue) error {
 	return nil
 }
 
+// DeclareStructType declares or replaces a struct type, hosts use it for types converted from go
+func (e *Environment) DeclareStructType(name string, structValue StructValue) {
+	e.structs[name] = structValue
+}
+
+// DeclareEnum declares a builtin enum like Result, its name is a value holding the variants
+func (e *Environment) DeclareEnum(enum EnumValue) {
+	e.structs[enum.Name] = enum
+	e.variables[enum.Name] = enum
+	e.constants[enum.Name] = true
+}
+
+// DeclareImpl records that a builtin type implements a trait, str implements error
+func (e *Environment) DeclareImpl(name ast.DATA_TYPE, trait string) {
+	if e.impls[string(name)] == nil {
+		e.impls[string(name)] = make(map[string]bool)
+	}
+	e.impls[string(name)][trait] = true
+}
+
+func (e *Environment) DeclareModule(name string, module ModuleValue) error {
+
+	if _, ok := e.modules[name]; ok {
+		return fmt.Errorf("module %s already declared", name)
+	}
+
+	e.modules[name] = module
+
+	return nil
+}
+
+func (e *Environment) GetModule(name string) (ModuleValue, error) {
+
+	if module, ok := e.modules[name]; ok {
+		return module, nil
+	}
+
+	if e.parent == nil {
+		return ModuleValue{}, fmt.Errorf("module %s was not found", name)
+	}
+
+	return e.parent.GetModule(name)
+}
+
 func (e *Environment) ResolveVariable(name string) (*Environment, error) {
 
 	if _, ok := e.variables[name]; ok {
@@ -4,3 +4,19 @@ import "fmt"
 
 var errorDivisionByZero error = fmt.Errorf("division by zero is forbidden")
 var invalidOperationMsg string = "cannot evaluate numeric operation. unsupported operator %v"
+
+// ArgumentError is returned by a native blaming one of its arguments,
+// the error is reported under that argument instead of the whole call
+type ArgumentError struct {
+	// the index of the argument
+	Index int
+	Err   error
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
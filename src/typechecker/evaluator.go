@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strconv"
 	"walrus/frontend/ast"
-	"walrus/frontend/parser"
 )
 
 func GetRuntimeType(runtimeValue RuntimeValue) ast.DATA_TYPE {
@@ -31,8 +30,16 @@ func GetRuntimeType(runtimeValue RuntimeValue) ast.DATA_TYPE {
 		return t.Type
 	case ArrayValue:
 		return ast.T_ARRAY
+	case MapValue:
+		return t.Type.IType()
 	case StructInstance:
 		return ast.DATA_TYPE(t.StructName)
+	case ModuleValue:
+		return t.Type
+	case EnumValue:
+		return t.Type
+	case EnumInstance:
+		return ast.DATA_TYPE(t.EnumName)
 	default:
 		panic(fmt.Sprintf("This runtime value is not implemented yet: %T", runtimeValue))
 	}
@@ -130,12 +137,17 @@ func CastToStringValue(value RuntimeValue) (StringValue, error) {
 		return MakeSTRING(strconv.FormatBool(t.Value)), nil
 	case CharacterValue:
 		return MakeSTRING(string(t.Value)), nil
+	case EnumInstance, MapValue:
+		return MakeSTRING(FormatValue(t)), nil
 	default:
 		return StringValue{}, fmt.Errorf("cannot cast %T to string", value)
 	}
 }
 
 func Evaluate(astNode ast.Node, env *Environment) RuntimeValue {
+
+	env.step(astNode)
+
 	switch node := astNode.(type) {
 	case ast.NumericLiteral:
 		// Check if the number is an integer or a float
@@ -146,14 +158,14 @@ func Evaluate(astNode ast.Node, env *Environment) RuntimeValue {
 			val, _ := strconv.ParseFloat(node.Value, 64)
 			return MakeFLOAT(val, node.BitSize)
 		} else {
-			parser.MakeError(env.parser, node.StartPos.Line, env.parser.FilePath, node.StartPos, node.EndPos, "invalid numeric literal").Display()
+			env.makeError(node.StartPos.Line, node.StartPos, node.EndPos, "invalid numeric literal").Display()
 			return nil
 		}
 	case ast.StringLiteral:
 		return MakeSTRING(node.Value)
 	case ast.CharacterLiteral:
 		if len(node.Value) > 1 {
-			parser.MakeError(env.parser, node.StartPos.Line, env.parser.FilePath, node.StartPos, node.EndPos, "character literals can only have one character").Display()
+			env.makeError(node.StartPos.Line, node.StartPos, node.EndPos, "character literals can only have one character").Display()
 		}
 		return MakeCHAR(node.Value[0])
 	case ast.BooleanLiteral:
@@ -164,6 +176,8 @@ func Evaluate(astNode ast.Node, env *Environment) RuntimeValue {
 		return MakeVOID()
 	case ast.ProgramStmt:
 		return EvaluateProgramBlock(node, env)
+	case ast.ImportStmt:
+		return EvaluateImportStmt(node, env)
 	case ast.VariableDclStml:
 		return EvaluateVariableDeclarationStmt(node, env)
 	case ast.AssignmentExpr:
@@ -182,20 +196,49 @@ func Evaluate(astNode ast.Node, env *Environment) RuntimeValue {
 		return EvaluateFunctionDeclarationStmt(node, env)
 	case ast.FunctionCallExpr:
 		return EvaluateFunctionCallExpr(node, env)
+	case ast.FunctionExpr:
+		return EvaluateFunctionExpr(node, env)
+	case ast.TestStmt:
+		// tests only run under walrus test
+		return MakeVOID()
 	case ast.ReturnStmt:
 		return EvaluateReturnStmt(node, env)
+	case ast.DeferStmt:
+		return EvaluateDeferStmt(node, env)
 	case ast.StructDeclStatement:
 		return EvaluateStructDeclarationStmt(node, env)
 	case ast.StructLiteral:
 		return EvaluateStructLiteral(node, env)
+	case ast.EnumDeclStatement:
+		return EvaluateEnumDeclarationStmt(node, env)
+	case ast.TraitDeclStatement:
+		return EvaluateTraitDeclarationStmt(node, env)
+	case ast.ImplementStatement:
+		return EvaluateImplementStmt(node, env)
+	case ast.MatchExpr:
+		return EvaluateMatchExpr(node, env)
+	case ast.TryExpr:
+		return EvaluateTryExpr(node, env)
 	case ast.PropertyExpr:
 		return EvaluatePropertyExpr(node, env)
 	case ast.ArrayLiterals:
 		return EvaluateArrayLiterals(node, env)
+	case ast.MapLiteral:
+		return EvaluateMapLiteral(node, env)
 	case ast.ArrayIndexAccess:
 		return EvaluateArrayAccess(node, env)
+	case ast.ForeachStmt:
+		return EvaluateForeachStmt(node, env)
+	case ast.WhileLoopStmt:
+		return EvaluateWhileLoopStmt(node, env)
+	case ast.BreakStmt:
+		return BreakValue{}
+	case ast.ContinueStmt:
+		return ContinueValue{}
 	default:
-		panic(fmt.Sprintf("This ast node is not implemented yet: %v", node))
+		start, end := node.GetPos()
+		env.makeError(start.Line, start, end, fmt.Sprintf("%s is not supported", node.INodeType())).Display()
+		return nil
 	}
 }
 
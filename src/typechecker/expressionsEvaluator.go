@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"walrus/frontend/ast"
 	"walrus/frontend/lexer"
-	"walrus/frontend/parser"
 	"walrus/helpers"
 )
 
@@ -13,13 +12,13 @@ func EvaluateIdenitifierExpr(expr ast.IdentifierExpr, env *Environment) RuntimeV
 
 		msg := fmt.Sprintf("%v is not declared in this scope\n", expr.Identifier)
 
-		parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, msg).Display()
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, msg).Display()
 	}
 
 	runtimeVal, err := env.GetRuntimeValue(expr.Identifier)
 
 	if err != nil {
-		parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, err.Error()).Display()
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, err.Error()).Display()
 	}
 
 	return runtimeVal
@@ -106,12 +105,41 @@ func EvaluateBinaryExpr(binop ast.BinaryExpr, env *Environment) RuntimeValue {
 		return handleBinaryArithmeticExpr(left, right, binop, env)
 	// Relational operators
 	case "==", "!=", ">", "<", ">=", "<=":
+		if helpers.TypesMatchT[StructInstance](left, right) {
+			left, right = orderingKeys(left.(StructInstance), right.(StructInstance), binop, env)
+		}
+		// values of an enum are equal when their variants and payloads are, maps when their entries are
+		if (helpers.TypesMatchT[EnumInstance](left, right) || helpers.TypesMatchT[MapValue](left, right)) && (binop.Operator.Value == "==" || binop.Operator.Value == "!=") {
+			return MakeBOOL(Equal(left, right) == (binop.Operator.Value == "=="))
+		}
 		result, err := evaluateComparisonExpr(left, right, binop.Operator)
 		if err != nil {
 			handleBinaryExprError(err, binop, env)
 		}
 		return result
 
+	// Membership, key in map
+	case "in":
+		return evaluateMembership(left, right, binop, env)
+
+	// Range operator, 0..10 produces the integers from 0 up to 9
+	case "..":
+		if !IsBothINT(left, right) {
+			handleBinaryExprError(fmt.Errorf("range bounds must be integers, got %v and %v", GetRuntimeType(left), GetRuntimeType(right)), binop, env)
+		}
+		// account for the whole range before building it, 0..1e12 must not exhaust the host
+		if count := right.(IntegerValue).Value - left.(IntegerValue).Value; count > 0 {
+			env.allocate(binop, arrayBytes(count))
+		}
+		var values []RuntimeValue
+		for i := left.(IntegerValue).Value; i < right.(IntegerValue).Value; i++ {
+			values = append(values, MakeINT(i, left.(IntegerValue).Size, true))
+		}
+		return ArrayValue{
+			Values: values,
+			Type:   ast.T_ARRAY,
+		}
+
 	// Logical operators
 	case "&&":
 		if IsTruthy(left) {
@@ -132,6 +160,29 @@ func EvaluateBinaryExpr(binop ast.BinaryExpr, env *Environment) RuntimeValue {
 	return MakeNULL()
 }
 
+// orderingKeys returns the fields two struct instances are compared by.
+// Only structs declaring an ordering, like core::time's Instant, can be compared
+func orderingKeys(left StructInstance, right StructInstance, binop ast.BinaryExpr, env *Environment) (RuntimeValue, RuntimeValue) {
+
+	if left.StructName != right.StructName {
+		handleBinaryExprError(fmt.Errorf("cannot compare '%s' with '%s'", left.StructName, right.StructName), binop, env)
+	}
+
+	declared, err := env.GetStructType(left.StructName)
+
+	if err != nil {
+		handleBinaryExprError(err, binop, env)
+	}
+
+	field := declared.(StructValue).OrderedBy
+
+	if field == "" {
+		handleBinaryExprError(fmt.Errorf("values of struct '%s' cannot be compared", left.StructName), binop, env)
+	}
+
+	return left.Fields[field], right.Fields[field]
+}
+
 func handleBinaryArithmeticExpr(left RuntimeValue, right RuntimeValue, binop ast.BinaryExpr, env *Environment) RuntimeValue {
 
 	leftType := GetRuntimeType(left)
@@ -152,6 +203,9 @@ func handleBinaryArithmeticExpr(left RuntimeValue, right RuntimeValue, binop ast
 		if err != nil {
 			handleBinaryExprError(err, binop, env)
 		}
+		if str, ok := result.(StringValue); ok {
+			env.allocate(binop, int64(len(str.Value)))
+		}
 		return result
 	} else {
 		handleBinaryExprError(fmt.Errorf("operand types mismatch: %v and %v", leftType, rightType), binop, env)
@@ -161,7 +215,7 @@ func handleBinaryArithmeticExpr(left RuntimeValue, right RuntimeValue, binop ast
 }
 
 func handleBinaryExprError(err error, binop ast.BinaryExpr, env *Environment) {
-	parser.MakeError(env.parser, binop.StartPos.Line, env.parser.FilePath, binop.Operator.StartPos, binop.Operator.EndPos, err.Error()).Display()
+	env.makeError(binop.StartPos.Line, binop.Operator.StartPos, binop.Operator.EndPos, err.Error()).Display()
 }
 
 func evaluateNumericArithmeticExpr(left RuntimeValue, right RuntimeValue, operator lexer.Token) (RuntimeValue, error) {
@@ -182,6 +236,10 @@ func evaluateNumericArithmeticExpr(left RuntimeValue, right RuntimeValue, operat
 
 func EvaluateAssignmentExpr(assignNode ast.AssignmentExpr, env *Environment) RuntimeValue {
 
+	if target, ok := assignNode.Assigne.(ast.ArrayIndexAccess); ok {
+		return evaluateIndexAssignment(assignNode, target, env)
+	}
+
 	var err error
 
 	var variableToAssign ast.IdentifierExpr
@@ -198,27 +256,27 @@ func EvaluateAssignmentExpr(assignNode ast.AssignmentExpr, env *Environment) Run
 		object, ok := assignNode.Assigne.(ast.PropertyExpr).Object.(ast.IdentifierExpr)
 		if !ok {
 			err = fmt.Errorf("invalid left-hand side in assignment expression. expected identifier got %s", assignNode.Assigne.(ast.PropertyExpr).Object.INodeType())
-			parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
+			env.makeError(assignNode.StartPos.Line, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
 		}
 
 		variableNameString = object.Identifier
 
 	default:
 		err = fmt.Errorf("invalid left-hand side in assignment expression")
-		parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
+		env.makeError(assignNode.StartPos.Line, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
 	}
 
 	//if assigne is any of "false", "true", "null";
 	if helpers.ContainsIn([]string{"false", "true", "null"}, variableToAssign.Identifier) {
 		err = fmt.Errorf("cannot assign to built-in constant %v", variableToAssign.Identifier)
-		parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
+		env.makeError(assignNode.StartPos.Line, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
 	}
 
 	currentValueOfIdentifier, err := env.GetRuntimeValue(variableNameString)
 
 	if err != nil {
 		valStart, valEnd := assignNode.Value.GetPos()
-		parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, valStart, valEnd, err.Error()).Display()
+		env.makeError(assignNode.StartPos.Line, valStart, valEnd, err.Error()).Display()
 	}
 
 	valueToSet := Evaluate(assignNode.Value, env)
@@ -229,7 +287,7 @@ func EvaluateAssignmentExpr(assignNode ast.AssignmentExpr, env *Environment) Run
 		// if object is declared in the current scope
 		if !env.HasVariable(variableNameString) {
 			err = fmt.Errorf("%v is not declared in this scope", variableNameString)
-			parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
+			env.makeError(assignNode.StartPos.Line, variableToAssign.StartPos, variableToAssign.EndPos, err.Error()).Display()
 		}
 
 		structInstance := env.variables[variableNameString]
@@ -244,39 +302,42 @@ func EvaluateAssignmentExpr(assignNode ast.AssignmentExpr, env *Environment) Run
 
 	switch assignNode.Operator.Kind {
 	case lexer.PLUS_EQUALS_TOKEN, lexer.MINUS_EQUALS_TOKEN, lexer.TIMES_EQUALS_TOKEN, lexer.DIVIDE_EQUALS_TOKEN, lexer.MODULO_EQUALS_TOKEN, lexer.POWER_EQUALS_TOKEN:
-
-		//remove the = from the operator
-		opChar := assignNode.Operator.Value[:len(assignNode.Operator.Value)-1]
-
-		operationValue := EvaluateBinaryExpr(ast.BinaryExpr{
-			BaseStmt: ast.BaseStmt{
-				Kind:     ast.BINARY_EXPRESSION,
-				StartPos: assignNode.StartPos,
-				EndPos:   assignNode.EndPos,
-			},
-			Left:  assignNode.Assigne,
-			Right: assignNode.Value,
-			Operator: lexer.Token{
-				Kind:     lexer.TOKEN_KIND(opChar),
-				Value:    opChar,
-				StartPos: assignNode.Operator.StartPos,
-				EndPos:   assignNode.Operator.EndPos,
-			},
-		}, env)
-
-		valueToSet = operationValue
+		valueToSet = compoundValue(assignNode, env)
 	}
 
 	runtimeVal, err := env.AssignVariable(variableToAssign.Identifier, valueToSet)
 
 	if err != nil {
 		start, end := assignNode.Value.GetPos()
-		parser.MakeError(env.parser, assignNode.StartPos.Line, env.parser.FilePath, start, end, err.Error()).Display()
+		env.makeError(assignNode.StartPos.Line, start, end, err.Error()).Display()
 	}
 
 	return runtimeVal
 }
 
+// compoundValue is the value a += b stores, a + b
+func compoundValue(assignNode ast.AssignmentExpr, env *Environment) RuntimeValue {
+
+	//remove the = from the operator
+	opChar := assignNode.Operator.Value[:len(assignNode.Operator.Value)-1]
+
+	return EvaluateBinaryExpr(ast.BinaryExpr{
+		BaseStmt: ast.BaseStmt{
+			Kind:     ast.BINARY_EXPRESSION,
+			StartPos: assignNode.StartPos,
+			EndPos:   assignNode.EndPos,
+		},
+		Left:  assignNode.Assigne,
+		Right: assignNode.Value,
+		Operator: lexer.Token{
+			Kind:     lexer.TOKEN_KIND(opChar),
+			Value:    opChar,
+			StartPos: assignNode.Operator.StartPos,
+			EndPos:   assignNode.Operator.EndPos,
+		},
+	}, env)
+}
+
 func evaluateIntInt(left IntegerValue, right IntegerValue, operator lexer.Token) (RuntimeValue, error) {
 
 	highestBit := uint8(0)
@@ -0,0 +1,143 @@
+package typechecker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatValue renders a value the way it would be written in walrus,
+// strings are quoted and struct fields are sorted by name
+func FormatValue(value RuntimeValue) string {
+
+	switch v := value.(type) {
+	case StringValue:
+		return strconv.Quote(v.Value)
+	case CharacterValue:
+		return fmt.Sprintf("'%c'", v.Value)
+	case NullValue:
+		return "null"
+	case ArrayValue:
+		values := make([]string, len(v.Values))
+		for i, element := range v.Values {
+			values[i] = FormatValue(element)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case MapValue:
+		entries := make([]string, 0, v.Len())
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
+			entries = append(entries, FormatValue(key)+": "+FormatValue(value))
+		}
+		return "map{" + strings.Join(entries, ", ") + "}"
+	case StructInstance:
+		names := make([]string, 0, len(v.Fields))
+		for name := range v.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + ": " + FormatValue(v.Fields[name])
+		}
+		return v.StructName + "{" + strings.Join(fields, ", ") + "}"
+	case EnumInstance:
+		if len(v.Fields) == 0 {
+			return v.EnumName + "." + v.Variant
+		}
+		fields := make([]string, len(v.Fields))
+		for i, field := range v.Fields {
+			fields[i] = FormatValue(field)
+		}
+		return v.EnumName + "." + v.Variant + "(" + strings.Join(fields, ", ") + ")"
+	case EnumValue:
+		return "enum " + v.Name
+	case FunctionValue:
+		if v.Name == "" {
+			return "fn"
+		}
+		return fmt.Sprintf("fn %s", v.Name)
+	case NativeFunctionValue:
+		return "native fn"
+	case ModuleValue:
+		return fmt.Sprintf("module %s", v.Name)
+	case StructValue:
+		return "struct"
+	}
+
+	if str, err := CastToStringValue(value); err == nil {
+		return str.Value
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// Equal tells if two values are equal the way == compares them, arrays,
+// maps and struct instances are equal when their elements are
+func Equal(a RuntimeValue, b RuntimeValue) bool {
+
+	switch x := a.(type) {
+	case ArrayValue:
+		y, ok := b.(ArrayValue)
+		if !ok || len(x.Values) != len(y.Values) {
+			return false
+		}
+		for i := range x.Values {
+			if !Equal(x.Values[i], y.Values[i]) {
+				return false
+			}
+		}
+		return true
+	case MapValue:
+		y, ok := b.(MapValue)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for _, key := range x.Keys() {
+			value, _ := x.Get(key)
+			other, ok := y.Get(key)
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case StructInstance:
+		y, ok := b.(StructInstance)
+		if !ok || x.StructName != y.StructName || len(x.Fields) != len(y.Fields) {
+			return false
+		}
+		for name, field := range x.Fields {
+			other, ok := y.Fields[name]
+			if !ok || !Equal(field, other) {
+				return false
+			}
+		}
+		return true
+	case EnumInstance:
+		y, ok := b.(EnumInstance)
+		if !ok || x.EnumName != y.EnumName || x.Variant != y.Variant || len(x.Fields) != len(y.Fields) {
+			return false
+		}
+		for i := range x.Fields {
+			if !Equal(x.Fields[i], y.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	case StringValue:
+		y, ok := b.(StringValue)
+		return ok && x.Value == y.Value
+	case NullValue:
+		_, ok := b.(NullValue)
+		return ok
+	}
+
+	if IsArithmetic(a) && IsArithmetic(b) {
+		x, _ := GetNumericValue(a)
+		y, _ := GetNumericValue(b)
+		return x == y
+	}
+
+	return false
+}
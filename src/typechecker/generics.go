@@ -0,0 +1,256 @@
+package typechecker
+
+import (
+	"fmt"
+	"walrus/frontend/ast"
+)
+
+// EvaluateTraitDeclarationStmt declares nothing yet, the methods of a trait
+// are not dispatched. Its name is known to the checker for the bounds.
+func EvaluateTraitDeclarationStmt(stmt ast.TraitDeclStatement, env *Environment) RuntimeValue {
+	return MakeVOID()
+}
+
+// EvaluateImplementStmt records the traits a type implements and its
+// methods. The bounds of the type parameters of generic functions are
+// checked against the traits, value.method() calls the methods.
+func EvaluateImplementStmt(stmt ast.ImplementStatement, env *Environment) RuntimeValue {
+
+	if env.impls[stmt.Impliments] == nil {
+		env.impls[stmt.Impliments] = make(map[string]bool)
+	}
+
+	for _, trait := range stmt.Traits {
+		env.impls[stmt.Impliments][trait] = true
+	}
+
+	if env.methods[stmt.Impliments] == nil {
+		env.methods[stmt.Impliments] = make(map[string]FunctionValue)
+	}
+
+	for name, method := range stmt.Methods {
+		env.methods[stmt.Impliments][name] = FunctionValue{
+			Name:           stmt.Impliments + "." + name,
+			Parameters:     method.Parameters,
+			Body:           method.Block,
+			Type:           ast.T_FN,
+			ReturnType:     method.ReturnType.IType(),
+			DeclarationEnv: env,
+			TypeParams:     method.TypeParams,
+		}
+	}
+
+	return MakeVOID()
+}
+
+// method returns the method an impl of the scope or its parents gives a type
+func (e *Environment) method(name ast.DATA_TYPE, method string) (FunctionValue, bool) {
+
+	// the source names booleans bool
+	if name == ast.T_BOOLEAN {
+		name = "bool"
+	}
+
+	for scope := e; scope != nil; scope = scope.parent {
+		if fn, ok := scope.methods[string(name)][method]; ok {
+			return fn, true
+		}
+	}
+
+	return FunctionValue{}, false
+}
+
+// implements tells if an impl of the scope or its parents gives a type a trait
+func (e *Environment) implements(name ast.DATA_TYPE, trait string) bool {
+
+	// the source names booleans bool
+	if name == ast.T_BOOLEAN {
+		name = "bool"
+	}
+
+	for scope := e; scope != nil; scope = scope.parent {
+		if scope.impls[string(name)][trait] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeArg returns the type a type parameter is bound to in the running call
+func (e *Environment) typeArg(name string) (ast.DATA_TYPE, bool) {
+	for scope := e; scope != nil; scope = scope.parent {
+		if bound, ok := scope.typeArgs[name]; ok {
+			return bound, true
+		}
+	}
+	return "", false
+}
+
+// inferTypeArgs binds the type parameters of a generic function to the types
+// of the arguments in the scope of the call and checks their bounds. A
+// parameter given integers or floats of two sizes is bound to the wider one.
+func inferTypeArgs(function FunctionValue, args []RuntimeValue, scope *Environment) error {
+
+	isParam := make(map[string]bool)
+
+	for _, param := range function.TypeParams {
+		isParam[param.Name] = true
+	}
+
+	from := make(map[string]RuntimeValue)
+	index := make(map[string]int)
+
+	for i, param := range function.Parameters {
+
+		// the parameters left out take their default values
+		if args[i] == nil {
+			continue
+		}
+
+		var name string
+		var arg RuntimeValue
+
+		switch t := param.Type.(type) {
+		case ast.TypeParamType:
+			name, arg = string(t.Kind), args[i]
+			// the arguments of args: ...T are an array of them
+			if param.IsVariadic {
+				array := args[i].(ArrayValue)
+				if len(array.Values) == 0 {
+					continue
+				}
+				arg = array.Values[0]
+			}
+		case ast.ArrayType:
+			array, ok := args[i].(ArrayValue)
+			if !ok || len(array.Values) == 0 || !isParam[string(t.ElementType)] {
+				continue
+			}
+			name, arg = string(t.ElementType), array.Values[0]
+		default:
+			continue
+		}
+
+		previous, bound := from[name]
+
+		if !bound || IsBothINT(previous, arg) && arg.(IntegerValue).Size > previous.(IntegerValue).Size || IsBothFLOAT(previous, arg) && arg.(FloatValue).Size > previous.(FloatValue).Size {
+			from[name], index[name] = arg, i
+			scope.typeArgs[name] = GetRuntimeType(arg)
+			continue
+		}
+
+		if IsBothINT(previous, arg) || IsBothFLOAT(previous, arg) || GetRuntimeType(previous) == GetRuntimeType(arg) {
+			continue
+		}
+
+		return fmt.Errorf("type parameter %s of '%s' is '%s' from argument %d but argument %d is '%s'", name, function.DisplayName(), GetRuntimeType(previous), index[name]+1, i+1, GetRuntimeType(arg))
+	}
+
+	for _, param := range function.TypeParams {
+
+		bound, ok := scope.typeArgs[param.Name]
+
+		if !ok {
+			continue
+		}
+
+		for _, trait := range param.Bounds {
+			if !scope.implements(bound, trait) {
+				return fmt.Errorf("'%s' does not implement trait '%s' required by type parameter %s of '%s'", bound, trait, param.Name, function.DisplayName())
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTypeArgReturn checks the value a generic function returns for its type
+// parameter against the type the call bound the parameter to. Integers and
+// floats of another size are the same type, like when the type is inferred.
+func checkTypeArgReturn(function FunctionValue, returned ReturnValue, scope *Environment) {
+
+	bound, ok := scope.typeArgs[string(function.ReturnType)]
+
+	if !ok {
+		return
+	}
+
+	got := GetRuntimeType(returned.Value)
+
+	if got == bound || IsINT(returned.Value) && isIntegerType(bound) || IsFLOAT(returned.Value) && isFloatType(bound) {
+		return
+	}
+
+	stmt := returned.Stmt
+	scope.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, fmt.Sprintf("cannot return value of type '%s' from function with return type %s bound to '%s'", got, function.ReturnType, bound)).Display()
+}
+
+func isIntegerType(t ast.DATA_TYPE) bool {
+	switch t {
+	case ast.T_INTEGER8, ast.T_INTEGER16, ast.T_INTEGER32, ast.T_INTEGER64:
+		return true
+	}
+	return false
+}
+
+func isFloatType(t ast.DATA_TYPE) bool {
+	return t == ast.T_FLOAT32 || t == ast.T_FLOAT64
+}
+
+// bindTypeArgs pairs the type parameters of a generic type with the type
+// arguments a use of it gives, Box<i32> binds T of struct Box<T> to i32
+func bindTypeArgs(params []ast.TypeParam, typeArgs []ast.Type) map[string]ast.Type {
+
+	args := make(map[string]ast.Type)
+
+	for i, param := range params {
+		if i < len(typeArgs) {
+			args[param.Name] = typeArgs[i]
+		}
+	}
+
+	return args
+}
+
+// substituteType replaces a type parameter by its type argument, the ones
+// without an argument stay parameters
+func substituteType(t ast.Type, args map[string]ast.Type) ast.Type {
+
+	switch t := t.(type) {
+	case ast.TypeParamType:
+		if arg, ok := args[string(t.Kind)]; ok {
+			return arg
+		}
+	case ast.ArrayType:
+		if arg, ok := args[string(t.ElementType)]; ok {
+			return ast.ArrayType{Kind: ast.T_ARRAY, ElementType: arg.IType()}
+		}
+	case ast.GenericType:
+		typeArgs := make([]ast.Type, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			typeArgs[i] = substituteType(arg, args)
+		}
+		return ast.GenericType{Kind: t.Kind, TypeArgs: typeArgs}
+	}
+
+	return t
+}
+
+// makeDefaultField is the zero value of a parameter or field, the instances
+// of declared structs are built but not the ones of the type declaring it
+func makeDefaultField(env *Environment, t ast.Type, declaring string) RuntimeValue {
+
+	switch t := t.(type) {
+	case ast.StructType:
+		if HasStruct(string(t.Kind), env) && string(t.Kind) != declaring {
+			return makeDefaultInstance(env, t, nil)
+		}
+	case ast.GenericType:
+		if HasStruct(string(t.Kind), env) && string(t.Kind) != declaring {
+			return makeDefaultInstance(env, ast.StructType{Kind: t.Kind}, t.TypeArgs)
+		}
+	}
+
+	return MakeDefaultRuntimeValue(t)
+}
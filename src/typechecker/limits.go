@@ -0,0 +1,197 @@
+package typechecker
+
+import (
+	"context"
+	"fmt"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+// Limits bounds the work a program may do, hosts set them before running untrusted scripts.
+// A zero field means no limit.
+type Limits struct {
+	// cancels the program when done, e.g. on a timeout
+	Context context.Context
+	// evaluated nodes
+	MaxSteps int64
+	// nested calls of walrus functions
+	MaxCallDepth int
+	// approximate bytes allocated for strings, arrays and struct instances
+	MaxMemory int64
+}
+
+// DefaultLimits applies to every program unless a host sets its own,
+// the call depth keeps deep recursion from overflowing the go stack
+var DefaultLimits = Limits{
+	MaxCallDepth: 10000,
+}
+
+type LIMIT_KIND string
+
+const (
+	STEP_LIMIT       LIMIT_KIND = "step limit"
+	CALL_DEPTH_LIMIT LIMIT_KIND = "call depth limit"
+	MEMORY_LIMIT     LIMIT_KIND = "memory limit"
+	CANCELLED        LIMIT_KIND = "cancelled"
+)
+
+// RuntimeError is raised when a program exceeds one of its limits or its context is done
+type RuntimeError struct {
+	Kind     LIMIT_KIND
+	Message  string
+	FilePath string
+	StartPos lexer.Position
+	EndPos   lexer.Position
+	// the calls in progress, the innermost first
+	Trace []parser.TraceFrame
+	// the context error for cancelled programs
+	Err error
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.FilePath, e.StartPos.Line, e.StartPos.Column, e.Message)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// execution is the state of a run shared by every scope of a program
+type execution struct {
+	limits Limits
+	steps  int64
+	depth  int
+	memory int64
+	// the calls in progress, the innermost last
+	frames []Frame
+	// the calls deferred by the function bodies being evaluated, the
+	// innermost last. A ? returns from the innermost body.
+	bodies [][]deferredCall
+	// told about every statement when the run is debugged
+	debugger Debugger
+}
+
+// the context is polled every this many steps
+const contextPollInterval = 64
+
+// SetLimits applies limits to this environment and every scope sharing its execution,
+// the counters start again from zero
+func (e *Environment) SetLimits(limits Limits) {
+	e.exec.limits = limits
+	e.exec.steps, e.exec.depth, e.exec.memory = 0, 0, 0
+}
+
+func (e *Environment) step(node ast.Node) {
+
+	exec := e.exec
+
+	exec.steps++
+
+	if max := exec.limits.MaxSteps; max > 0 && exec.steps > max {
+		e.raise(node, STEP_LIMIT, fmt.Sprintf("step limit of %d exceeded", max), nil)
+	}
+
+	if ctx := exec.limits.Context; ctx != nil && exec.steps%contextPollInterval == 0 {
+		if err := ctx.Err(); err != nil {
+			e.raise(node, CANCELLED, fmt.Sprintf("execution cancelled: %s", err), err)
+		}
+	}
+}
+
+// enterCall pushes the frame of a call to a walrus function, scope is the scope of its body
+func (e *Environment) enterCall(node ast.Node, name string, call ast.Node, scope *Environment) {
+
+	exec := e.exec
+
+	exec.depth++
+
+	if max := exec.limits.MaxCallDepth; max > 0 && exec.depth > max {
+		e.raise(node, CALL_DEPTH_LIMIT, fmt.Sprintf("call depth limit of %d exceeded", max), nil)
+	}
+
+	e.pushFrame(name, call, scope)
+}
+
+func (e *Environment) leaveCall() {
+	e.exec.depth--
+	e.popFrame()
+}
+
+func (e *Environment) allocate(node ast.Node, bytes int64) {
+
+	exec := e.exec
+
+	exec.memory += bytes
+
+	if max := exec.limits.MaxMemory; max > 0 && exec.memory > max {
+		e.raise(node, MEMORY_LIMIT, fmt.Sprintf("memory limit of %d bytes exceeded", max), nil)
+	}
+}
+
+// raise reports an exceeded limit. Like other evaluation errors it ends the
+// process unless the parser was asked to panic, then hosts recover the *RuntimeError.
+func (e *Environment) raise(node ast.Node, kind LIMIT_KIND, message string, err error) {
+
+	start, end := node.GetPos()
+
+	if e.parser != nil && !e.parser.PanicOnError {
+		e.makeError(start.Line, start, end, message).Display()
+	}
+
+	runtimeErr := &RuntimeError{
+		Kind:     kind,
+		Message:  message,
+		StartPos: start,
+		EndPos:   end,
+		Trace:    e.Trace(start),
+		Err:      err,
+	}
+
+	if e.parser != nil {
+		runtimeErr.FilePath = e.parser.FilePath
+	}
+
+	panic(runtimeErr)
+}
+
+// rough sizes used for the memory limit
+const valueBytes = 16
+
+func arrayBytes(length int64) int64 {
+	return length * valueBytes
+}
+
+func mapBytes(entries int) int64 {
+	return int64(entries) * 2 * valueBytes
+}
+
+func structBytes(fields int) int64 {
+	return int64(fields) * 2 * valueBytes
+}
+
+// nativeBytes is the size of the value a native returns. The arrays, strings
+// and maps natives return are new, like append(xs, x) or keys(m), the values
+// inside the arrays are the ones they were given.
+func nativeBytes(value RuntimeValue) int64 {
+
+	switch v := value.(type) {
+	case ArrayValue:
+		return arrayBytes(int64(len(v.Values)))
+	case StringValue:
+		return int64(len(v.Value))
+	case MapValue:
+		return mapBytes(v.Len())
+	case StructInstance:
+		return structBytes(len(v.Fields))
+	case EnumInstance:
+		// the Ok of fs.readFile holds the contents it read
+		bytes := structBytes(len(v.Fields))
+		for _, field := range v.Fields {
+			bytes += nativeBytes(field)
+		}
+		return bytes
+	}
+
+	return 0
+}
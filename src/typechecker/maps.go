@@ -0,0 +1,292 @@
+package typechecker
+
+import (
+	"fmt"
+	"strings"
+	"walrus/formatter"
+	"walrus/frontend/ast"
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+// MapValue is a map[K]V. Its entries are shared by the copies of the value,
+// like the fields of a struct instance, and keep the order the keys were
+// inserted in, so iterating a map is deterministic.
+type MapValue struct {
+	Type    ast.MapType
+	entries *mapEntries
+}
+
+type mapEntries struct {
+	keys   []RuntimeValue
+	values map[any]RuntimeValue
+}
+
+func (m MapValue) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
+func MakeMAP(t ast.MapType) MapValue {
+	return MapValue{Type: t, entries: &mapEntries{values: make(map[any]RuntimeValue)}}
+}
+
+// mapKey is what a key is stored under, keys are equal when their values are
+func mapKey(key RuntimeValue) any {
+	switch k := key.(type) {
+	case StringValue:
+		return k.Value
+	case IntegerValue:
+		return k.Value
+	case CharacterValue:
+		return k.Value
+	case BooleanValue:
+		return k.Value
+	}
+	return nil
+}
+
+func (m MapValue) Len() int {
+	return len(m.entries.keys)
+}
+
+// Keys returns the keys in the order they were inserted
+func (m MapValue) Keys() []RuntimeValue {
+	keys := make([]RuntimeValue, len(m.entries.keys))
+	copy(keys, m.entries.keys)
+	return keys
+}
+
+// Get returns the value of a key, ok is false when the map does not have it
+func (m MapValue) Get(key RuntimeValue) (RuntimeValue, bool) {
+	value, ok := m.entries.values[mapKey(key)]
+	return value, ok
+}
+
+// Set gives a key a value, it tells if the key is new. Both must have the
+// types of the map.
+func (m MapValue) Set(key RuntimeValue, value RuntimeValue) (bool, error) {
+
+	key, err := m.CheckKey(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := fit(m.Type.ValueType, value)
+
+	if !ok {
+		return false, fmt.Errorf("map of type '%s' cannot have a value of type '%s'", m.Type.IType(), GetRuntimeType(value))
+	}
+
+	k := mapKey(key)
+
+	_, exists := m.entries.values[k]
+
+	if !exists {
+		m.entries.keys = append(m.entries.keys, key)
+	}
+
+	m.entries.values[k] = value
+
+	return !exists, nil
+}
+
+// Delete removes a key, it tells if the map had it
+func (m MapValue) Delete(key RuntimeValue) bool {
+
+	k := mapKey(key)
+
+	if _, ok := m.entries.values[k]; !ok {
+		return false
+	}
+
+	delete(m.entries.values, k)
+
+	for i, existing := range m.entries.keys {
+		if mapKey(existing) == k {
+			m.entries.keys = append(m.entries.keys[:i], m.entries.keys[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// CheckKey converts a key to the key type of the map, it fails for a key of another type
+func (m MapValue) CheckKey(key RuntimeValue) (RuntimeValue, error) {
+	key, ok := fit(m.Type.KeyType, key)
+	if !ok {
+		return nil, fmt.Errorf("map of type '%s' cannot have a key of type '%s'", m.Type.IType(), GetRuntimeType(key))
+	}
+	return key, nil
+}
+
+// Is tells if the map has a type, any stands for every key or value type
+func (m MapValue) Is(t ast.MapType) bool {
+	return (isAny(t.KeyType) || t.KeyType.IType() == m.Type.KeyType.IType()) && (isAny(t.ValueType) || t.ValueType.IType() == m.Type.ValueType.IType())
+}
+
+func isAny(t ast.Type) bool {
+	_, ok := t.(ast.AnyType)
+	return ok
+}
+
+// fit gives a value the key or value type of a map, integers and floats
+// widen to a larger size
+func fit(t ast.Type, value RuntimeValue) (RuntimeValue, bool) {
+	switch t := t.(type) {
+	case ast.AnyType:
+		return value, true
+	case ast.IntegerType:
+		v, ok := value.(IntegerValue)
+		if !ok || v.Size > t.BitSize {
+			return value, false
+		}
+		return MakeINT(v.Value, t.BitSize, t.IsSigned), true
+	case ast.FloatType:
+		v, ok := value.(FloatValue)
+		if !ok || v.Size > t.BitSize {
+			return value, false
+		}
+		return MakeFLOAT(v.Value, t.BitSize), true
+	case ast.FunctionType:
+		return value, IsFunction(value)
+	}
+	return value, GetRuntimeType(value) == t.IType()
+}
+
+// typeOfValue is the type a map literal takes from its first entry
+func typeOfValue(value RuntimeValue) ast.Type {
+	switch v := value.(type) {
+	case IntegerValue:
+		return ast.IntegerType{Kind: v.Type, BitSize: v.Size, IsSigned: !strings.HasPrefix(string(v.Type), "u")}
+	case FloatValue:
+		return ast.FloatType{Kind: v.Type, BitSize: v.Size}
+	case StringValue:
+		return ast.StringType{Kind: ast.T_STRING}
+	case CharacterValue:
+		return ast.CharType{Kind: ast.T_CHARACTER}
+	case BooleanValue:
+		return ast.BoolType{Kind: ast.T_BOOLEAN}
+	case ArrayValue:
+		return ast.ArrayType{Kind: ast.T_ARRAY, ElementType: ast.T_ANY}
+	case MapValue:
+		return v.Type
+	case FunctionValue, NativeFunctionValue:
+		return ast.FunctionType{Kind: ast.T_FN}
+	}
+	return ast.StructType{Kind: GetRuntimeType(value)}
+}
+
+func EvaluateMapLiteral(literal ast.MapLiteral, env *Environment) RuntimeValue {
+
+	var m MapValue
+
+	if t, ok := literal.Type.(ast.MapType); ok {
+		m = MakeMAP(t)
+	}
+
+	for i, entry := range literal.Entries {
+
+		key := Evaluate(entry.Key, env)
+		value := Evaluate(entry.Value, env)
+
+		// a literal without a type has the one of its first entry
+		if i == 0 && literal.Type == nil {
+			m = MakeMAP(ast.MapType{Kind: ast.T_MAP, KeyType: typeOfValue(key), ValueType: typeOfValue(value)})
+		}
+
+		if _, err := m.Set(key, value); err != nil {
+			start, _ := entry.Key.GetPos()
+			_, end := entry.Value.GetPos()
+			env.makeError(start.Line, start, end, err.Error()).Display()
+		}
+	}
+
+	env.allocate(literal, mapBytes(m.Len()))
+
+	return m
+}
+
+// evaluateMapAccess reads the value of a key, reading a key the map does not have is an error
+func evaluateMapAccess(access ast.ArrayIndexAccess, m MapValue, env *Environment) RuntimeValue {
+
+	key, err := m.CheckKey(Evaluate(access.Index, env))
+
+	if err != nil {
+		start, end := access.Index.GetPos()
+		env.makeError(start.Line, start, end, err.Error()).Display()
+	}
+
+	value, ok := m.Get(key)
+
+	if !ok {
+		env.makeError(access.StartPos.Line, access.StartPos, access.EndPos, fmt.Sprintf("map has no key %s", FormatValue(key))).AddHint(fmt.Sprintf("check for it first with %s in %s\n", FormatValue(key), formatter.Expr(access.Array)), parser.TEXT_HINT).Display()
+	}
+
+	return value
+}
+
+// evaluateIndexAssignment sets an element of an array or the value of a key in a map
+func evaluateIndexAssignment(assignNode ast.AssignmentExpr, target ast.ArrayIndexAccess, env *Environment) RuntimeValue {
+
+	var valueToSet RuntimeValue
+
+	if assignNode.Operator.Kind == lexer.ASSIGNMENT_TOKEN {
+		valueToSet = Evaluate(assignNode.Value, env)
+	} else {
+		valueToSet = compoundValue(assignNode, env)
+	}
+
+	// the elements of arrays and the entries of maps are shared by the copies
+	// of the value, setting them through the one the expression gives is enough
+	switch collection := Evaluate(target.Array, env).(type) {
+	case MapValue:
+		added, err := collection.Set(Evaluate(target.Index, env), valueToSet)
+		if err != nil {
+			start, end := assignNode.GetPos()
+			env.makeError(start.Line, start, end, err.Error()).Display()
+		}
+		if added {
+			env.allocate(assignNode, mapBytes(1))
+		}
+	case ArrayValue:
+		index, ok := Evaluate(target.Index, env).(IntegerValue)
+		if !ok {
+			env.makeError(target.StartPos.Line, target.StartPos, target.EndPos, "invalid index value").AddHint("index must be a valid integer\n", parser.TEXT_HINT).Display()
+		}
+		if index.Value < 0 || index.Value > int64(len(collection.Values)-1) {
+			env.makeError(target.StartPos.Line, target.StartPos, target.EndPos, fmt.Sprintf("invalid index range %d", index.Value)).AddHint(fmt.Sprintf("index must be within the range of 0 to %d\n", len(collection.Values)-1), parser.TEXT_HINT).Display()
+		}
+		collection.Values[index.Value] = valueToSet
+	default:
+		env.makeError(target.StartPos.Line, target.StartPos, target.EndPos, fmt.Sprintf("cannot index a value of type '%s'", GetRuntimeType(collection))).Display()
+	}
+
+	return valueToSet
+}
+
+// evaluateMembership is key in map, or value in array
+func evaluateMembership(left RuntimeValue, right RuntimeValue, binop ast.BinaryExpr, env *Environment) RuntimeValue {
+
+	switch collection := right.(type) {
+	case MapValue:
+		key, err := collection.CheckKey(left)
+		if err != nil {
+			handleBinaryExprError(err, binop, env)
+		}
+		_, ok := collection.Get(key)
+		return MakeBOOL(ok)
+	case ArrayValue:
+		for _, element := range collection.Values {
+			if Equal(left, element) {
+				return MakeBOOL(true)
+			}
+		}
+		return MakeBOOL(false)
+	}
+
+	handleBinaryExprError(fmt.Errorf("operator in needs a map or an array but got '%s'", GetRuntimeType(right)), binop, env)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package typechecker
+
+import (
+	"fmt"
+	"walrus/frontend/ast"
+)
+
+// propagation carries an Err from a ? out of the expressions around it to
+// the body of the function it is written in, which returns it
+type propagation struct {
+	err EnumInstance
+}
+
+func MakeOK(value RuntimeValue) EnumInstance {
+	return EnumInstance{EnumName: string(ast.T_RESULT), Variant: ast.OK_VARIANT, Fields: []RuntimeValue{value}}
+}
+
+func MakeERR(err RuntimeValue) EnumInstance {
+	return EnumInstance{EnumName: string(ast.T_RESULT), Variant: ast.ERR_VARIANT, Fields: []RuntimeValue{err}}
+}
+
+// EvaluateTryExpr unwraps an Ok and returns an Err from the function around
+// the expression. At the top level of a script an Err ends it.
+func EvaluateTryExpr(expr ast.TryExpr, env *Environment) RuntimeValue {
+
+	value := Evaluate(expr.Value, env)
+
+	result, ok := value.(EnumInstance)
+
+	if !ok || result.EnumName != string(ast.T_RESULT) {
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, fmt.Sprintf("operator ? needs a Result but got '%s'", GetRuntimeType(value))).Display()
+	}
+
+	if result.Variant == ast.OK_VARIANT {
+		return result.Fields[0]
+	}
+
+	if len(env.exec.bodies) == 0 {
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, fmt.Sprintf("unhandled error: %s", errorMessage(result.Fields[0]))).Display()
+	}
+
+	panic(propagation{err: result})
+}
+
+// errorMessage is an error as a report shows it, strings are not quoted
+func errorMessage(err RuntimeValue) string {
+	if str, ok := err.(StringValue); ok {
+		return str.Value
+	}
+	return FormatValue(err)
+}
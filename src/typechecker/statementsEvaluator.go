@@ -1,14 +1,24 @@
 package typechecker
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"walrus/frontend/ast"
 	"walrus/frontend/lexer"
 	"walrus/frontend/parser"
+	"walrus/utils"
 )
 
 func EvaluateProgramBlock(block ast.ProgramStmt, env *Environment) RuntimeValue {
+	for _, stmt := range block.Imports {
+		EvaluateImportStmt(stmt, env)
+	}
+	env.pushFrame(programFrame, nil, env)
+	defer env.popFrame()
+
 	for _, stmt := range block.Contents {
+		env.statement(stmt)
 		rVal := Evaluate(stmt, env)
 		if _, ok := rVal.(ReturnValue); ok {
 			return rVal
@@ -17,6 +27,42 @@ func EvaluateProgramBlock(block ast.ProgramStmt, env *Environment) RuntimeValue
 	return MakeVOID()
 }
 
+// EvaluateImportStmt binds a native module into the scope.
+// import "core::fs"; binds the module as fs, import { walk } from "core::fs"; binds the members directly.
+func EvaluateImportStmt(stmt ast.ImportStmt, env *Environment) RuntimeValue {
+
+	module, err := env.GetModule(stmt.ModuleName)
+
+	if err != nil {
+		env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, err.Error()).Display()
+	}
+
+	// types produced by the module members must be known to the scope
+	for name, structValue := range module.Structs {
+		env.structs[name] = structValue
+	}
+
+	if len(stmt.Identifiers) == 0 {
+		alias := stmt.ModuleName[strings.LastIndex(stmt.ModuleName, ":")+1:]
+		if _, err := env.DeclareVariable(alias, module, true); err != nil {
+			env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, err.Error()).Display()
+		}
+		return MakeVOID()
+	}
+
+	for _, name := range stmt.Identifiers {
+		member, ok := module.Members[name]
+		if !ok {
+			env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, fmt.Sprintf("module '%s' has no member '%s'", stmt.ModuleName, name)).Display()
+		}
+		if _, err := env.DeclareVariable(name, member, true); err != nil {
+			env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, err.Error()).Display()
+		}
+	}
+
+	return MakeVOID()
+}
+
 func EvaluateVariableDeclarationStmt(stmt ast.VariableDclStml, env *Environment) RuntimeValue {
 
 	var value RuntimeValue
@@ -65,7 +111,7 @@ func EvaluateVariableDeclarationStmt(stmt ast.VariableDclStml, env *Environment)
 	val, err := env.DeclareVariable(stmt.Identifier.Identifier, value, stmt.IsConstant)
 
 	if err != nil {
-		parser.MakeError(env.parser, stmt.StartPos.Line, env.parser.FilePath, stmt.Identifier.StartPos, stmt.Identifier.EndPos, err.Error()).Display()
+		env.makeError(stmt.StartPos.Line, stmt.Identifier.StartPos, stmt.Identifier.EndPos, err.Error()).Display()
 	}
 
 	return val
@@ -80,6 +126,17 @@ func checkTypes(env *Environment, explicitType ast.Type, value RuntimeValue, sta
 		checkFloatType(env, t, value, startPos, endPos)
 	case ast.StructType:
 		checkStructType(env, t, value, startPos, endPos)
+	case ast.FunctionType:
+		if !IsFunction(value) {
+			displayTypeMismatchError(env, explicitType, value, startPos, endPos, "")
+		}
+	case ast.AnyType:
+		// any value is accepted
+	case ast.TypeParamType:
+		// the type a parameter stands for is known in a call of a generic function
+		if bound, ok := env.typeArg(string(t.Kind)); ok && GetRuntimeType(value) != bound {
+			displayTypeMismatchError(env, explicitType, value, startPos, endPos, string(bound))
+		}
 	default:
 		checkGeneralType(env, t, value, startPos, endPos)
 	}
@@ -88,20 +145,20 @@ func checkTypes(env *Environment, explicitType ast.Type, value RuntimeValue, sta
 func checkIntegerType(env *Environment, explicitType ast.IntegerType, value RuntimeValue, startPos lexer.Position, endPos lexer.Position) {
 	if IsINT(value) {
 		if explicitType.BitSize != value.(IntegerValue).Size {
-			displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, fmt.Sprintf("integer of size %d", explicitType.BitSize))
+			displayTypeMismatchError(env, explicitType, value, startPos, endPos, fmt.Sprintf("integer of size %d", explicitType.BitSize))
 		}
 	} else {
-		displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, "")
+		displayTypeMismatchError(env, explicitType, value, startPos, endPos, "")
 	}
 }
 
 func checkFloatType(env *Environment, explicitType ast.FloatType, value RuntimeValue, startPos lexer.Position, endPos lexer.Position) {
 	if IsFLOAT(value) {
 		if explicitType.BitSize != value.(FloatValue).Size {
-			displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, fmt.Sprintf("float of size %d", explicitType.BitSize))
+			displayTypeMismatchError(env, explicitType, value, startPos, endPos, fmt.Sprintf("float of size %d", explicitType.BitSize))
 		}
 	} else {
-		displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, "")
+		displayTypeMismatchError(env, explicitType, value, startPos, endPos, "")
 	}
 }
 
@@ -110,26 +167,26 @@ func checkStructType(env *Environment, explicitType ast.StructType, value Runtim
 	got := string(GetRuntimeType(value))
 
 	if !HasStruct(expected, env) {
-		parser.MakeError(env.parser, startPos.Line, env.parser.FilePath, startPos, endPos, fmt.Sprintf("failed to validate types. struct '%s' is not defined", expected)).Display()
+		env.makeError(startPos.Line, startPos, endPos, fmt.Sprintf("failed to validate types. struct '%s' is not defined", expected)).Display()
 	} else if !HasStruct(got, env) {
-		parser.MakeError(env.parser, startPos.Line, env.parser.FilePath, startPos, endPos, fmt.Sprintf("failed to validate types. struct '%s' is not defined", got)).Display()
+		env.makeError(startPos.Line, startPos, endPos, fmt.Sprintf("failed to validate types. struct '%s' is not defined", got)).Display()
 	} else if expected != got {
-		displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, "")
+		displayTypeMismatchError(env, explicitType, value, startPos, endPos, "")
 	}
 }
 
 func checkGeneralType(env *Environment, explicitType ast.Type, value RuntimeValue, startPos lexer.Position, endPos lexer.Position) {
 	if GetRuntimeType(value) != explicitType.IType() {
-		displayTypeMismatchError(env.parser, explicitType, value, startPos, endPos, "")
+		displayTypeMismatchError(env, explicitType, value, startPos, endPos, "")
 	}
 }
 
-func displayTypeMismatchError(p *parser.Parser, explicitType ast.Type, value RuntimeValue, startPos lexer.Position, endPos lexer.Position, additionalInfo string) {
+func displayTypeMismatchError(env *Environment, explicitType ast.Type, value RuntimeValue, startPos lexer.Position, endPos lexer.Position, additionalInfo string) {
 	msg := strFormatter(explicitType, value)
 	if additionalInfo != "" {
 		msg += fmt.Sprintf(" to %s", additionalInfo)
 	}
-	parser.MakeError(p, startPos.Line, p.FilePath, startPos, endPos, msg).Display()
+	env.makeError(startPos.Line, startPos, endPos, msg).Display()
 }
 
 func strFormatter(expected ast.Type, got RuntimeValue) string {
@@ -154,19 +211,20 @@ func EvaluateBlockStmt(block ast.BlockStmt, env *Environment) RuntimeValue {
 		switch stmt := stmt.(type) {
 		case ast.ReturnStmt:
 			// Evaluate the return expression and return its value immediately
+			env.statement(stmt)
 			return Evaluate(stmt, env)
 		default:
+			env.statement(stmt)
 			rVal := Evaluate(stmt, env)
-			//check if the runtime value is a return value
-			if _, ok := rVal.(ReturnValue); ok {
+			//return, break and continue unwind the block
+			switch rVal.(type) {
+			case ReturnValue, BreakValue, ContinueValue:
 				return rVal
 			}
 		}
 	}
 
-	return ReturnValue{
-		Value: MakeVOID(),
-	}
+	return MakeVOID()
 }
 
 func EvaluateControlFlowStmt(astNode ast.IfStmt, env *Environment) RuntimeValue {
@@ -196,31 +254,131 @@ func EvaluateFunctionDeclarationStmt(stmt ast.FunctionDeclStmt, env *Environment
 		return MakeVOID()
 	}
 
+	// the parameters of a generic function have no type until it is called
+	if len(stmt.TypeParams) > 0 {
+		checkReturnAtEnd(stmt.ReturnType, stmt.Block, env)
+		return MakeVOID()
+	}
+
 	funcEnv := createFunctionEnvironment(stmt, env)
+	checkFunctionBody(stmt, funcEnv)
+
+	return MakeVOID()
+}
+
+// checkFunctionBody evaluates the declarations and the return of a body with
+// the default arguments to check the return type. An Err given to ? ends it
+// early, the function would return the Err.
+func checkFunctionBody(stmt ast.FunctionDeclStmt, funcEnv *Environment) {
+
+	funcEnv.enterBody()
+
+	defer func() {
+		funcEnv.leaveBody()
+		if recovered := recover(); recovered != nil {
+			if _, ok := recovered.(propagation); !ok {
+				panic(recovered)
+			}
+		}
+	}()
+
 	processFunctionBody(stmt.Block, funcEnv)
 	checkFunctionReturnType(stmt, funcEnv)
+}
 
-	return MakeVOID()
+// EvaluateFunctionExpr makes a closure of an anonymous function. The function
+// keeps the environment it is evaluated in, so it sees later changes to the
+// variables it uses and its own assignments to them outlive the call.
+func EvaluateFunctionExpr(expr ast.FunctionExpr, env *Environment) RuntimeValue {
+
+	checkReturnAtEnd(expr.ReturnType, expr.Block, env)
+
+	return FunctionValue{
+		Parameters:     expr.Parameters,
+		Body:           expr.Block,
+		Type:           ast.T_FN,
+		ReturnType:     expr.ReturnType.IType(),
+		DeclarationEnv: env,
+	}
+}
+
+// checkReturnAtEnd reports a function with a return type whose body does not
+// end with a return, for functions whose body is not evaluated when declared
+func checkReturnAtEnd(returnType ast.Type, block ast.BlockStmt, env *Environment) {
+
+	if returnType.IType() == ast.T_VOID {
+		return
+	}
+
+	items := block.Items
+
+	if len(items) == 0 {
+		env.makeError(block.StartPos.Line, block.StartPos, block.EndPos, "no return statement found").AddHint("function is empty", parser.TEXT_HINT).Display()
+	}
+
+	if _, ok := items[len(items)-1].(ast.ReturnStmt); !ok {
+		env.makeError(block.StartPos.Line, block.StartPos, block.EndPos, "function must have a return value at the end").Display()
+	}
 }
 
 func declareFunction(stmt ast.FunctionDeclStmt, env *Environment) error {
-	return env.DeclareFunction(stmt.Name.Identifier, stmt.ReturnType, stmt.Parameters, stmt.Block)
+	return env.DeclareFunction(stmt.Name.Identifier, stmt.TypeParams, stmt.ReturnType, stmt.Parameters, stmt.Block)
 }
 
 func handleFunctionDeclarationError(stmt ast.FunctionDeclStmt, env *Environment, err error) {
-	parser.MakeError(env.parser, stmt.StartPos.Line, env.parser.FilePath, stmt.Name.StartPos, stmt.Name.EndPos, err.Error()).Display()
+	env.makeError(stmt.StartPos.Line, stmt.Name.StartPos, stmt.Name.EndPos, err.Error()).Display()
 }
 
 func createFunctionEnvironment(stmt ast.FunctionDeclStmt, env *Environment) *Environment {
 	funcEnv := NewEnvironment(env, env.parser)
 
 	for _, param := range stmt.Parameters {
-		funcEnv.DeclareVariable(param.Identifier.Identifier, MakeDefaultRuntimeValue(param.Type), false)
+		switch {
+		case param.IsVariadic:
+			funcEnv.DeclareVariable(param.Identifier.Identifier, ArrayValue{Values: []RuntimeValue{}, Type: ast.T_ARRAY}, false)
+		case param.DefaultVal != nil:
+			// the default value is checked against the type once, when the function is declared
+			value := widen(param.Type, Evaluate(param.DefaultVal, funcEnv))
+			_, end := param.DefaultVal.GetPos()
+			checkTypes(funcEnv, param.Type, value, ast.StartOf(param.DefaultVal), end)
+			funcEnv.DeclareVariable(param.Identifier.Identifier, value, false)
+		default:
+			funcEnv.DeclareVariable(param.Identifier.Identifier, makeDefaultField(env, param.Type, ""), false)
+		}
 	}
 
 	return funcEnv
 }
 
+// makeDefaultInstance builds a zero valued instance of a declared struct,
+// so a function body can be checked against a parameter of that type.
+// The type arguments of a generic struct give the types of its fields.
+func makeDefaultInstance(env *Environment, structType ast.StructType, typeArgs []ast.Type) RuntimeValue {
+
+	declared, err := env.GetStructType(string(structType.Kind))
+
+	if err != nil {
+		return MakeDefaultRuntimeValue(structType)
+	}
+
+	if enum, ok := declared.(EnumValue); ok {
+		return makeDefaultVariant(env, enum, typeArgs)
+	}
+
+	fields := make(map[string]RuntimeValue)
+
+	args := bindTypeArgs(declared.(StructValue).TypeParams, typeArgs)
+
+	for name, property := range declared.(StructValue).Fields {
+		fields[name] = makeDefaultField(env, substituteType(property.Type, args), string(structType.Kind))
+	}
+
+	return StructInstance{
+		StructName: string(structType.Kind),
+		Fields:     fields,
+	}
+}
+
 func processFunctionBody(body ast.BlockStmt, funcEnv *Environment) {
 	var returnStmt *ast.ReturnStmt
 
@@ -235,7 +393,7 @@ func processFunctionBody(body ast.BlockStmt, funcEnv *Environment) {
 	}
 
 	if returnStmt != nil && returnStmt.Kind == ast.NODE_TYPE(ast.T_VOID) {
-		parser.MakeError(funcEnv.parser, returnStmt.StartPos.Line, funcEnv.parser.FilePath, returnStmt.StartPos, returnStmt.EndPos, "void function must not have a return statement with a value").Display()
+		funcEnv.makeError(returnStmt.StartPos.Line, returnStmt.StartPos, returnStmt.EndPos, "void function must not have a return statement with a value").Display()
 	}
 }
 
@@ -243,91 +401,430 @@ func checkFunctionReturnType(stmt ast.FunctionDeclStmt, funcEnv *Environment) {
 	if stmt.ReturnType.IType() != ast.T_VOID {
 		if len(stmt.Block.Items) == 0 {
 			fmt.Println(stmt.StartPos, stmt.EndPos)
-			parser.MakeError(funcEnv.parser, stmt.StartPos.Line, funcEnv.parser.FilePath, stmt.StartPos, stmt.EndPos, "no return statement found").AddHint("function is empty", parser.TEXT_HINT).Display()
+			funcEnv.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, "no return statement found").AddHint("function is empty", parser.TEXT_HINT).Display()
 		}
 		lastStmt := stmt.Block.Items[len(stmt.Block.Items)-1]
 		if returnStmt, ok := lastStmt.(ast.ReturnStmt); ok {
 			returnVal := Evaluate(returnStmt.Expression, funcEnv)
 			expectedType := fmt.Sprintf("%s", stmt.ReturnType)
 			returnType := GetRuntimeType(returnVal)
+			if _, ok := stmt.ReturnType.(ast.FunctionType); ok && IsFunction(returnVal) {
+				return
+			}
 			if GetRuntimeType(returnVal) != stmt.ReturnType.IType() {
-				parser.MakeError(funcEnv.parser, returnStmt.StartPos.Line, funcEnv.parser.FilePath, returnStmt.StartPos, returnStmt.EndPos, fmt.Sprintf("cannot return value of type '%s' from function with return type '%s'", returnType, expectedType)).Display()
+				funcEnv.makeError(returnStmt.StartPos.Line, returnStmt.StartPos, returnStmt.EndPos, fmt.Sprintf("cannot return value of type '%s' from function with return type '%s'", returnType, expectedType)).Display()
 			}
 		} else {
-			parser.MakeError(funcEnv.parser, stmt.StartPos.Line, funcEnv.parser.FilePath, stmt.Name.StartPos, stmt.Name.EndPos, "function must have a return value at the end").Display()
+			funcEnv.makeError(stmt.StartPos.Line, stmt.Name.StartPos, stmt.Name.EndPos, "function must have a return value at the end").Display()
 		}
 	}
 }
 
 func EvaluateFunctionCallExpr(expr ast.FunctionCallExpr, env *Environment) RuntimeValue {
 
+	args, named := evaluateArguments(expr, env)
+
+	fn := Evaluate(expr.Caller, env)
+
+	return callValue(expr, fn, args, named, env)
+}
+
+func evaluateArguments(expr ast.FunctionCallExpr, env *Environment) ([]RuntimeValue, []namedArgument) {
+
 	var args []RuntimeValue
 
 	for _, arg := range expr.Args {
 		args = append(args, Evaluate(arg, env))
 	}
 
-	fn := Evaluate(expr.Caller, env)
+	var named []namedArgument
 
-	if !IsFunction(fn) {
-		parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, fmt.Sprintf("could not call. %s not a function", expr.Caller.Identifier)).Display()
+	for _, arg := range expr.NamedArgs {
+		named = append(named, namedArgument{Name: arg.Name.Identifier, Value: Evaluate(arg.Value, env)})
+	}
+
+	return args, named
+}
+
+// callValue calls the function a call expression evaluated to with its evaluated arguments
+func callValue(expr ast.FunctionCallExpr, fn RuntimeValue, args []RuntimeValue, named []namedArgument, env *Environment) RuntimeValue {
+
+	if fn == nil || !IsFunction(fn) {
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, fmt.Sprintf("could not call. %s not a function", expr.CallerName())).Display()
 	}
 
 	if GetRuntimeType(fn) == ast.T_NATIVE_FN {
-		return fn.(NativeFunctionValue).Caller(args...)
+		native := fn.(NativeFunctionValue)
+		if len(named) > 0 {
+			env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, fmt.Sprintf("function '%s' does not take named arguments", expr.CallerName())).Display()
+		}
+		if err := checkNativeArguments(expr.CallerName(), native, args); err != nil {
+			env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, err.Error()).Display()
+		}
+		result, err := native.Caller(args...)
+		if err != nil {
+			start, end := expr.StartPos, expr.EndPos
+			var argErr *ArgumentError
+			if errors.As(err, &argErr) && argErr.Index < len(expr.Args) {
+				_, end = expr.Args[argErr.Index].GetPos()
+				start = ast.StartOf(expr.Args[argErr.Index])
+			}
+			env.makeError(start.Line, start, end, fmt.Sprintf("%s: %s", expr.CallerName(), err.Error())).WithCause(err).Display()
+		}
+		env.allocate(expr, nativeBytes(result))
+		return result
 	}
 
 	function := fn.(FunctionValue)
-	scope := NewEnvironment(function.DeclarationEnv, env.parser)
+	// the body reports its errors against the source it was declared in
+	scope := NewEnvironment(function.DeclarationEnv, function.DeclarationEnv.parser)
+
+	if err := bindArguments(function, args, named, scope); err != nil {
+		env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, err.Error()).Display()
+	}
+
+	env.enterCall(expr, function.DisplayName(), expr, scope)
+	defer env.leaveCall()
+
+	return evaluateFunctionBody(function, scope)
+}
+
+// CallFunction calls a function value with already evaluated arguments.
+// Natives use it to call back into walrus code, like the visitor given to fs.walk.
+func CallFunction(fn RuntimeValue, args ...RuntimeValue) (RuntimeValue, error) {
+
+	switch function := fn.(type) {
+	case NativeFunctionValue:
+		if err := checkNativeArguments("native", function, args); err != nil {
+			return nil, err
+		}
+		return function.Caller(args...)
+	case FunctionValue:
+		scope := NewEnvironment(function.DeclarationEnv, function.DeclarationEnv.parser)
+		if err := bindArguments(function, args, nil, scope); err != nil {
+			return nil, err
+		}
+		scope.enterCall(function.Body, function.DisplayName(), nil, scope)
+		defer scope.leaveCall()
+		return evaluateFunctionBody(function, scope), nil
+	default:
+		return nil, fmt.Errorf("could not call. value of type '%s' is not a function", GetRuntimeType(fn))
+	}
+}
+
+// TryCall calls a function like CallFunction and returns the errors of its
+// evaluation instead of ending the program. Exceeded limits are not caught.
+func TryCall(fn RuntimeValue, args ...RuntimeValue) (result RuntimeValue, err error) {
+
+	// the evaluator reports errors by panicking only when its parser asks for it
+	if function, ok := fn.(FunctionValue); ok {
+		if p := function.DeclarationEnv.parser; p != nil && !p.PanicOnError {
+			p.PanicOnError = true
+			defer func() { p.PanicOnError = false }()
+		}
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			message, ok := recovered.(*parser.ErrorMessage)
+			if !ok {
+				panic(recovered)
+			}
+			result, err = nil, message
+		}
+	}()
+
+	return CallFunction(fn, args...)
+}
+
+// namedArgument is an argument given by the name of its parameter
+type namedArgument struct {
+	Name  string
+	Value RuntimeValue
+}
+
+// bindArguments checks the arguments against the function parameters and declares them in the scope
+func bindArguments(function FunctionValue, args []RuntimeValue, named []namedArgument, scope *Environment) error {
 
 	params := function.Parameters
 
-	// check if the number of arguments match the number of parameters
-	if len(args) != len(params) {
-		parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, fmt.Sprintf("function '%s' expects %d arguments but %d were provided", function.Name, len(params), len(args))).Display()
+	slots, err := placeArguments(function, args, named)
+
+	if err != nil {
+		return err
+	}
+
+	if len(function.TypeParams) > 0 {
+		if err := inferTypeArgs(function, slots, scope); err != nil {
+			return err
+		}
 	}
 
 	// check and set the arguments to the function parameters
 	for i := 0; i < len(params); i++ {
 		param := params[i].Identifier
-		arg := args[i]
 
-		expected := params[i].Type.IType()
-		got := GetRuntimeType(arg)
+		arg := slots[i]
 
-		if expected != got {
-			fmt.Printf("param: %v, arg: %v", params[i].Type.IType(), GetRuntimeType(arg))
-			parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, "function parameter and arguments type mismatched").AddHint(fmt.Sprintf("expected type '%s' but got '%s'", expected, got), parser.TEXT_HINT).Display()
+		if arg == nil {
+			// a default value is evaluated at each call, it sees the parameters before it
+			arg = Evaluate(params[i].DefaultVal, scope)
+		}
+
+		values := []RuntimeValue{arg}
+
+		if params[i].IsVariadic {
+			values = arg.(ArrayValue).Values
+			for j, value := range values {
+				values[j] = widen(params[i].Type, value)
+			}
+		} else {
+			arg = widen(params[i].Type, arg)
+			values[0] = arg
+		}
+
+		for _, value := range values {
+			if expected, got := argumentType(params[i].Type, value); expected != got {
+				return fmt.Errorf("function parameter and arguments type mismatched. expected type '%s' but got '%s'", expected, got)
+			}
 		}
 
 		scope.DeclareVariable(param.Identifier, arg, false)
 	}
 
+	return nil
+}
+
+// placeArguments gives each parameter the argument for it, the positional
+// arguments in order and the named ones by name. The arguments from the
+// variadic parameter on arrive as an array, the parameters left without one
+// are nil and take their default values.
+func placeArguments(function FunctionValue, args []RuntimeValue, named []namedArgument) ([]RuntimeValue, error) {
+
+	params := function.Parameters
+	name := function.DisplayName()
+
+	slots := make([]RuntimeValue, len(params))
+
+	positional := len(params)
+	isVariadic := len(params) > 0 && params[len(params)-1].IsVariadic
+
+	if isVariadic {
+		positional--
+	}
+
+	if len(named) == 0 || !isVariadic && len(args) > positional {
+		if err := checkArity(name, params, len(args)+len(named)); err != nil {
+			return nil, err
+		}
+	}
+
+	copy(slots, args[:utils.Min(len(args), positional)])
+
+	if isVariadic {
+		rest := []RuntimeValue{}
+		if len(args) > positional {
+			rest = append(rest, args[positional:]...)
+		}
+		slots[positional] = ArrayValue{Values: rest, Type: ast.T_ARRAY}
+	}
+
+	for _, arg := range named {
+
+		i := ast.ParamIndex(params, arg.Name)
+
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("function '%s' has no parameter named %s", name, arg.Name)
+		case params[i].IsVariadic:
+			return nil, fmt.Errorf("variadic parameter %s cannot be given by name", arg.Name)
+		case slots[i] != nil:
+			return nil, fmt.Errorf("parameter %s of '%s' is given twice", arg.Name, name)
+		}
+
+		slots[i] = arg.Value
+	}
+
+	for i, param := range params {
+		if slots[i] == nil && param.DefaultVal == nil {
+			return nil, fmt.Errorf("function '%s' is missing a value for parameter %s", name, param.Identifier.Identifier)
+		}
+	}
+
+	return slots, nil
+}
+
+// argumentType returns the type a parameter expects and the type of an
+// argument given to it, they are the same when the argument is accepted
+func argumentType(param ast.Type, arg RuntimeValue) (ast.DATA_TYPE, ast.DATA_TYPE) {
+
+	expected := param.IType()
+	got := GetRuntimeType(arg)
+
+	switch param.(type) {
+	case ast.AnyType, ast.TypeParamType:
+		// the arguments of a type parameter are checked when it is inferred
+		return expected, expected
+	case ast.FunctionType:
+		// a parameter of a function type takes declared, anonymous and native functions
+		if IsFunction(arg) {
+			return expected, expected
+		}
+	}
+
+	return expected, got
+}
+
+// widen gives an integer or a float the size of the parameter it is given to
+// when that is larger, like a declaration with an explicit type does. A
+// literal default of an i64 parameter is an i64.
+func widen(t ast.Type, value RuntimeValue) RuntimeValue {
+	switch t := t.(type) {
+	case ast.IntegerType:
+		if v, ok := value.(IntegerValue); ok && v.Size <= t.BitSize && (t.IsSigned || v.Value >= 0) {
+			return MakeINT(v.Value, t.BitSize, t.IsSigned)
+		}
+	case ast.FloatType:
+		if v, ok := value.(FloatValue); ok && v.Size <= t.BitSize {
+			return MakeFLOAT(v.Value, t.BitSize)
+		}
+	}
+	return value
+}
+
+// checkArity reports a call with fewer or more arguments than the parameters take
+func checkArity(name string, params []ast.FunctionParameter, count int) error {
+
+	fewest, most := ast.Arity(params)
+
+	switch {
+	case most < 0 && count < fewest:
+		return fmt.Errorf("function '%s' expects at least %d arguments but %d were provided", name, fewest, count)
+	case most >= 0 && fewest == most && count != most:
+		return fmt.Errorf("function '%s' expects %d arguments but %d were provided", name, most, count)
+	case most >= 0 && (count < fewest || count > most):
+		return fmt.Errorf("function '%s' expects %d to %d arguments but %d were provided", name, fewest, most, count)
+	}
+
+	return nil
+}
+
+// checkNativeArguments validates the arguments against the signature of a typed native
+func checkNativeArguments(name string, native NativeFunctionValue, args []RuntimeValue) error {
+
+	signature := native.Signature
+
+	params := signature.Parameters
+
+	if signature.IsVariadic {
+		if len(args) < len(params)-1 {
+			return fmt.Errorf("function '%s' expects at least %d arguments but %d were provided", name, len(params)-1, len(args))
+		}
+	} else if len(args) != len(params) {
+		return fmt.Errorf("function '%s' expects %d arguments but %d were provided", name, len(params), len(args))
+	}
+
+	for i, arg := range args {
+
+		param := params[utils.Min(i, len(params)-1)]
+
+		expected := param.IType()
+		got := GetRuntimeType(arg)
+
+		switch t := param.(type) {
+		case ast.AnyType, ast.TypeParamType:
+			continue
+		case ast.FunctionType:
+			if IsFunction(arg) {
+				continue
+			}
+		case ast.IntegerType:
+			// integers widen to a larger parameter without loss
+			if IsINT(arg) && arg.(IntegerValue).Size <= t.BitSize {
+				continue
+			}
+		case ast.FloatType:
+			if IsFLOAT(arg) && arg.(FloatValue).Size <= t.BitSize {
+				continue
+			}
+		case ast.MapType:
+			if m, ok := arg.(MapValue); ok && m.Is(t) {
+				continue
+			}
+		}
+
+		if expected != got {
+			return fmt.Errorf("function '%s' expects argument %d to be of type '%s' but got '%s'", name, i+1, expected, got)
+		}
+	}
+
+	return nil
+}
+
+func evaluateFunctionBody(function FunctionValue, scope *Environment) (result RuntimeValue) {
+
+	scope.enterBody()
+
+	// an Err given to ? is the value of the call. The deferred calls are
+	// made on every return, not when the evaluation fails.
+	defer func() {
+		calls := scope.leaveBody()
+		if recovered := recover(); recovered != nil {
+			propagated, ok := recovered.(propagation)
+			if !ok {
+				panic(recovered)
+			}
+			result = propagated.err
+		}
+		runDeferred(calls)
+	}()
+
 	for _, stmt := range function.Body.Items {
+		scope.statement(stmt)
 		rVal := Evaluate(stmt, scope)
-		if _, ok := rVal.(ReturnValue); ok {
-			return rVal.(ReturnValue).Value
+		if returned, ok := rVal.(ReturnValue); ok {
+			checkTypeArgReturn(function, returned, scope)
+			return returned.Value
 		}
 	}
 
 	return MakeVOID()
 }
 
+// RunTest runs the body of a test in a scope of env, the program declaring
+// the test is evaluated in env before. Failures are reported like other errors.
+func RunTest(test ast.TestStmt, env *Environment) {
+
+	scope := NewEnvironment(env, env.parser)
+
+	env.enterCall(test, "test "+test.Name.Identifier, nil, scope)
+	defer env.leaveCall()
+
+	for _, stmt := range test.Block.Items {
+		scope.statement(stmt)
+		if _, ok := Evaluate(stmt, scope).(ReturnValue); ok {
+			return
+		}
+	}
+}
+
 func EvaluateReturnStmt(stmt ast.ReturnStmt, env *Environment) RuntimeValue {
 	expr := stmt.Expression
 	val := Evaluate(expr, env)
 
 	return ReturnValue{
 		Value: val,
+		Stmt:  stmt,
 	}
 }
 
 func EvaluateStructDeclarationStmt(stmt ast.StructDeclStatement, env *Environment) RuntimeValue {
 
 	env.structs[stmt.StructName] = StructValue{
-		Fields:  stmt.Properties,
-		Methods: stmt.Methods,
-		Type:    ast.DATA_TYPE(stmt.StructName),
+		Fields:     stmt.Properties,
+		Methods:    stmt.Methods,
+		Type:       ast.DATA_TYPE(stmt.StructName),
+		TypeParams: stmt.TypeParams,
 	}
 
 	return MakeVOID()
@@ -337,7 +834,7 @@ func EvaluateStructLiteral(stmt ast.StructLiteral, env *Environment) RuntimeValu
 
 	//check if the struct is defined
 	if !HasStruct(stmt.StructName, env) {
-		parser.MakeError(env.parser, stmt.StartPos.Line, env.parser.FilePath, stmt.StartPos, stmt.EndPos, fmt.Sprintf("cannot evaluate struct literal. struct '%s' is not defined", stmt.StructName)).Display()
+		env.makeError(stmt.StartPos.Line, stmt.StartPos, stmt.EndPos, fmt.Sprintf("cannot evaluate struct literal. struct '%s' is not defined", stmt.StructName)).Display()
 	}
 
 	properties := make(map[string]RuntimeValue)
@@ -346,6 +843,8 @@ func EvaluateStructLiteral(stmt ast.StructLiteral, env *Environment) RuntimeValu
 		properties[name] = Evaluate(value, env)
 	}
 
+	env.allocate(stmt, structBytes(len(properties)))
+
 	return StructInstance{
 		StructName: stmt.StructName,
 		Fields:     properties,
@@ -356,26 +855,43 @@ func EvaluatePropertyExpr(expr ast.PropertyExpr, env *Environment) RuntimeValue
 
 	propname := expr.Property.Identifier
 
-	switch obj := Evaluate(expr.Object, env).(type) {
+	object := Evaluate(expr.Object, env)
+
+	// a method of an impl for the type of the value, fields and members come first
+	if object != nil && !hasMember(object, propname) {
+		if method, ok := env.method(GetRuntimeType(object), propname); ok {
+			return method
+		}
+	}
+
+	switch obj := object.(type) {
 	case StructInstance:
 	
 		if obj.Fields[propname] == nil {
-			parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' does not exist in type '%s'", propname, obj.StructName)).Display()
+			env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' does not exist in type '%s'", propname, obj.StructName)).Display()
 		}
 	
 		structValue, err := env.GetStructType(obj.StructName)
 	
 		if err != nil {
-			parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.StartPos, expr.EndPos, err.Error()).Display()
+			env.makeError(expr.StartPos.Line, expr.StartPos, expr.EndPos, err.Error()).Display()
 		}
 	
 		// check if the property is public
 		if structValue.(StructValue).Fields[propname].IsPublic {
 			return obj.Fields[propname]
 		} else {
-			parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' is private in struct '%s'", propname, obj.StructName)).Display()
+			env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' is private in struct '%s'", propname, obj.StructName)).Display()
 			return nil
 		}
+	case EnumValue:
+		return evaluateVariant(obj, expr, env)
+	case ModuleValue:
+		member, ok := obj.Members[propname]
+		if !ok {
+			env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("module '%s' has no member '%s'", obj.Name, propname)).Display()
+		}
+		return member
 	case ArrayValue:
 		switch propname {
 		case "length":
@@ -383,12 +899,31 @@ func EvaluatePropertyExpr(expr ast.PropertyExpr, env *Environment) RuntimeValue
 			size := len(obj.Values)
 			return MakeINT(int64(size), 32, true)
 		default:
-			parser.MakeError(env.parser, expr.StartPos.Line, env.parser.FilePath, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' does not exist in type array", propname)).Display()
+			env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' does not exist in type array", propname)).Display()
+		}
+	case MapValue:
+		switch propname {
+		case "length":
+			return MakeINT(int64(obj.Len()), 32, true)
+		default:
+			env.makeError(expr.StartPos.Line, expr.Property.StartPos, expr.Property.EndPos, fmt.Sprintf("property '%s' does not exist in type map", propname)).Display()
 		}
 	}
 	return nil
 }
 
+// hasMember tells if a property names a field of a struct instance or a member of a module
+func hasMember(object RuntimeValue, name string) bool {
+	switch obj := object.(type) {
+	case StructInstance:
+		return obj.Fields[name] != nil
+	case ModuleValue:
+		_, ok := obj.Members[name]
+		return ok
+	}
+	return false
+}
+
 func EvaluateArrayLiterals(node ast.Node, env *Environment) RuntimeValue {
 	var values []RuntimeValue
 
@@ -396,6 +931,8 @@ func EvaluateArrayLiterals(node ast.Node, env *Environment) RuntimeValue {
 		values = append(values, Evaluate(value, env))
 	}
 
+	env.allocate(node, arrayBytes(int64(len(values))))
+
 	return ArrayValue{
 		Values: values,
 		Type:   ast.T_ARRAY,
@@ -404,27 +941,106 @@ func EvaluateArrayLiterals(node ast.Node, env *Environment) RuntimeValue {
 
 func EvaluateArrayAccess(node ast.Node, env *Environment) RuntimeValue {
 	arr := node.(ast.ArrayIndexAccess)
-	name := arr.ArrayName
-	scope, err := env.ResolveVariable(name)
-	errorPrinter := parser.MakeError(env.parser, arr.StartPos.Line, env.parser.FilePath, arr.StartPos, arr.EndPos, "array was not declared\n")
-	if err != nil {
-		errorPrinter.Display()
+
+	var values []RuntimeValue
+
+	switch collection := Evaluate(arr.Array, env).(type) {
+	case MapValue:
+		return evaluateMapAccess(arr, collection, env)
+	case ArrayValue:
+		values = collection.Values
+	default:
+		start, end := arr.Array.GetPos()
+		env.makeError(start.Line, start, end, fmt.Sprintf("cannot index a value of type '%s'", GetRuntimeType(collection))).Display()
 	}
 
 	indexNumber := Evaluate(arr.Index, env)
 
 	if _, ok := indexNumber.(IntegerValue); !ok {
-		errorPrinter.Message = "invalid index value\n"
-		errorPrinter.AddHint("index must be a valid integer\n", parser.TEXT_HINT).Display()
+		env.makeError(arr.StartPos.Line, arr.StartPos, arr.EndPos, "invalid index value").AddHint("index must be a valid integer\n", parser.TEXT_HINT).Display()
 	}
 
 	index := indexNumber.(IntegerValue).Value
-	values := scope.variables[name].(ArrayValue).Values
 
-	if index > int64(len(values)-1) {
-		errorPrinter.Message = fmt.Sprintf("invalid index range %d\n", index)
-		errorPrinter.AddHint(fmt.Sprintf("index must be within the range of 0 to %d\n", len(values)-1), parser.TEXT_HINT).Display()
+	if index < 0 || index > int64(len(values)-1) {
+		env.makeError(arr.StartPos.Line, arr.StartPos, arr.EndPos, fmt.Sprintf("invalid index range %d", index)).AddHint(fmt.Sprintf("index must be within the range of 0 to %d\n", len(values)-1), parser.TEXT_HINT).Display()
 	}
 
 	return values[index]
 }
+
+
+func EvaluateForeachStmt(stmt ast.ForeachStmt, env *Environment) RuntimeValue {
+
+	value := Evaluate(stmt.Iterable, env)
+
+	// a map gives its keys and their values in the order they were inserted
+	if m, ok := value.(MapValue); ok {
+		for _, key := range m.Keys() {
+			element, ok := m.Get(key)
+			// deleted by an earlier iteration
+			if !ok {
+				continue
+			}
+			if result, done := iterate(stmt, key, element, env); done {
+				return result
+			}
+		}
+		return MakeVOID()
+	}
+
+	iterable, ok := value.(ArrayValue)
+
+	if !ok {
+		start, end := stmt.Iterable.GetPos()
+		env.makeError(start.Line, start, end, "foreach expects an array, a range or a map to iterate over").Display()
+	}
+
+	for i, value := range iterable.Values {
+		if result, done := iterate(stmt, value, MakeINT(int64(i), 32, true), env); done {
+			return result
+		}
+	}
+
+	return MakeVOID()
+}
+
+// iterate runs the block of a foreach for one element, it tells if the loop ends
+func iterate(stmt ast.ForeachStmt, value RuntimeValue, index RuntimeValue, env *Environment) (RuntimeValue, bool) {
+
+	// every iteration gets its own scope for the loop variables
+	scope := NewEnvironment(env, env.parser)
+	scope.DeclareVariable(stmt.Variable, value, false)
+
+	if stmt.IndexVariable != "" {
+		scope.DeclareVariable(stmt.IndexVariable, index, false)
+	}
+
+	if stmt.WhereClause != nil && !IsTruthy(Evaluate(stmt.WhereClause, scope)) {
+		return nil, false
+	}
+
+	switch rVal := EvaluateBlockStmt(stmt.Block, scope).(type) {
+	case BreakValue:
+		return MakeVOID(), true
+	case ReturnValue:
+		return rVal, true
+	}
+
+	return nil, false
+}
+
+func EvaluateWhileLoopStmt(stmt ast.WhileLoopStmt, env *Environment) RuntimeValue {
+
+	for IsTruthy(Evaluate(stmt.Condition, env)) {
+
+		switch rVal := EvaluateBlockStmt(stmt.Block, NewEnvironment(env, env.parser)).(type) {
+		case BreakValue:
+			return MakeVOID()
+		case ReturnValue:
+			return rVal
+		}
+	}
+
+	return MakeVOID()
+}
@@ -0,0 +1,74 @@
+package typechecker
+
+import (
+	"errors"
+
+	"walrus/frontend/lexer"
+	"walrus/frontend/parser"
+)
+
+// Trace returns the calls in progress the way an error at pos reports them,
+// the innermost first. The innermost frame is at pos, every other frame at
+// the call which made the frame above it.
+func (e *Environment) Trace(pos lexer.Position) []parser.TraceFrame {
+
+	frames := e.exec.frames
+
+	trace := make([]parser.TraceFrame, 0, len(frames))
+
+	for i := len(frames) - 1; i >= 0; i-- {
+
+		at := pos
+
+		if i < len(frames)-1 {
+			switch {
+			case frames[i+1].Call != nil:
+				at, _ = frames[i+1].Call.GetPos()
+			case frames[i].Stmt != nil:
+				// called back by a native, the statement which called the native
+				at, _ = frames[i].Stmt.GetPos()
+			default:
+				at = lexer.Position{}
+			}
+		}
+
+		frame := parser.TraceFrame{Function: frames[i].Name, Pos: at}
+
+		if env := frames[i].Env; env != nil && env.parser != nil {
+			frame.FilePath = env.parser.FilePath
+		}
+
+		trace = append(trace, frame)
+	}
+
+	return trace
+}
+
+// makeError creates the report of a runtime error with the trace of the calls leading to it
+func (e *Environment) makeError(lineNo int, start lexer.Position, end lexer.Position, message string) *parser.ErrorMessage {
+	return parser.MakeError(e.parser, lineNo, e.parser.FilePath, start, end, message).WithTrace(e.Trace(start))
+}
+
+// StackTrace returns the calls in progress when a runtime error was raised,
+// the innermost first, nil for errors of parsing and checking. Hosts get the
+// errors from the vm:
+//
+//	if _, err := machine.Call("main"); err != nil {
+//		for _, frame := range typechecker.StackTrace(err) {
+//			log.Printf("%s %s:%d", frame.Function, frame.FilePath, frame.Pos.Line)
+//		}
+//	}
+func StackTrace(err error) []parser.TraceFrame {
+
+	var message *parser.ErrorMessage
+	var runtimeErr *RuntimeError
+
+	switch {
+	case errors.As(err, &message):
+		return message.Trace
+	case errors.As(err, &runtimeErr):
+		return runtimeErr.Trace
+	}
+
+	return nil
+}
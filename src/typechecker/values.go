@@ -74,12 +74,26 @@ func (v VoidValue) rVal() {
 
 type ReturnValue struct {
 	Value RuntimeValue
+	// the statement returning the value, errors about the value point to it
+	Stmt ast.ReturnStmt
 }
 
 func (r ReturnValue) rVal() {
 	// empty function implements RuntimeValue interface
 }
 
+type BreakValue struct{}
+
+func (b BreakValue) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
+type ContinueValue struct{}
+
+func (c ContinueValue) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
 type FunctionValue struct {
 	Name           string
 	Parameters     []ast.FunctionParameter
@@ -87,12 +101,22 @@ type FunctionValue struct {
 	Type           ast.DATA_TYPE
 	ReturnType     ast.DATA_TYPE
 	DeclarationEnv *Environment
+	// the type parameters of a generic function, bound at each call
+	TypeParams []ast.TypeParam
 }
 
 func (f FunctionValue) rVal() {
 	// empty function implements RuntimeValue interface
 }
 
+// DisplayName is the name errors and traces use, anonymous functions have none
+func (f FunctionValue) DisplayName() string {
+	if f.Name == "" {
+		return "anonymous fn"
+	}
+	return f.Name
+}
+
 type ArrayValue struct {
 	Values []RuntimeValue
 	Type   ast.DATA_TYPE
@@ -106,6 +130,10 @@ type StructValue struct {
 	Fields  map[string]ast.Property
 	Methods map[string]ast.FunctionType
 	Type    ast.DATA_TYPE
+	// numeric field the comparison operators use, set for native structs like Instant
+	OrderedBy string
+	// the type parameters of a generic struct, Box<T>
+	TypeParams []ast.TypeParam
 }
 
 func (s StructValue) rVal() {
@@ -122,17 +150,96 @@ func (s StructInstance) rVal() {
 	// empty function implements RuntimeValue interface
 }
 
-type FunctionCall = func(...RuntimeValue) RuntimeValue
+// EnumValue is a declared enum, its name evaluates to it
+type EnumValue struct {
+	Name       string
+	TypeParams []ast.TypeParam
+	Variants   []ast.EnumVariant
+	Type       ast.DATA_TYPE
+}
+
+func (e EnumValue) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
+// Variant returns a variant of the enum by name
+func (e EnumValue) Variant(name string) (ast.EnumVariant, bool) {
+	return ast.EnumType{Variants: e.Variants}.Variant(name)
+}
+
+// EnumInstance is a value of a variant, Fields is the payload in the order the variant declares it
+type EnumInstance struct {
+	EnumName string
+	Variant  string
+	Fields   []RuntimeValue
+}
+
+func (e EnumInstance) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
+// FunctionCall is the go implementation of a native. A returned error is reported at the call site
+type FunctionCall = func(...RuntimeValue) (RuntimeValue, error)
+
+// NativeSignature declares the parameters and return type of a native function.
+// When variadic, the last parameter type repeats for the remaining arguments.
+type NativeSignature struct {
+	Parameters []ast.Type
+	IsVariadic bool
+	ReturnType ast.Type
+}
+
+// FunctionType converts the signature to the type the static checker works with
+func (s NativeSignature) FunctionType() ast.FunctionType {
+
+	params := make([]ast.FunctionParameter, len(s.Parameters))
+
+	for i, paramType := range s.Parameters {
+		params[i] = ast.FunctionParameter{
+			BaseStmt: ast.BaseStmt{
+				Kind: ast.FUNCTION_PARAMETER,
+			},
+			Identifier: ast.IdentifierExpr{
+				BaseStmt: ast.BaseStmt{
+					Kind: ast.IDENTIFIER,
+				},
+				Identifier: fmt.Sprintf("arg%d", i),
+			},
+			IsVariadic: s.IsVariadic && i == len(s.Parameters)-1,
+			Type:       paramType,
+		}
+	}
+
+	return ast.FunctionType{
+		Kind:       ast.T_NATIVE_FN,
+		ReturnType: s.ReturnType,
+		Parameters: params,
+	}
+}
 
 type NativeFunctionValue struct {
 	Caller 	FunctionCall
 	Type	ast.DATA_TYPE
+	Signature NativeSignature
 }
 
 func (n NativeFunctionValue) rVal() {
 	// empty function implements RuntimeValue interface
 }
 
+// ModuleValue is a namespace of native members such as core::fs.
+// Structs holds the user visible types the members produce, they are declared on import.
+type ModuleValue struct {
+	Name    string
+	Members map[string]RuntimeValue
+	Structs map[string]StructValue
+	Type    ast.DATA_TYPE
+}
+
+func (m ModuleValue) rVal() {
+	// empty function implements RuntimeValue interface
+}
+
 func MakeINT(value int64, size uint8, signed bool) IntegerValue {
 
 	initial := "i"
@@ -169,10 +276,20 @@ func MakeVOID() VoidValue {
 	return VoidValue{Type: ast.T_VOID}
 }
 
-func MakeNativeFUNCTION(call FunctionCall) NativeFunctionValue {
+func MakeNativeFUNCTION(call FunctionCall, signature NativeSignature) NativeFunctionValue {
 	return NativeFunctionValue{
-		Caller: call,
-		Type: 	ast.T_NATIVE_FN,
+		Caller:    call,
+		Type:      ast.T_NATIVE_FN,
+		Signature: signature,
+	}
+}
+
+func MakeMODULE(name string, members map[string]RuntimeValue, structs map[string]StructValue) ModuleValue {
+	return ModuleValue{
+		Name:    name,
+		Members: members,
+		Structs: structs,
+		Type:    ast.T_MODULE,
 	}
 }
 
@@ -193,7 +310,7 @@ func MakeDefaultRuntimeValue(node ast.Type) RuntimeValue {
 		return MakeNULL()
 	case ast.VoidType:
 		return MakeVOID()
-	case ast.StructType:
+	case ast.StructType, ast.GenericType:
 		return StructValue{
 			Fields:  make(map[string]ast.Property),
 			Methods: make(map[string]ast.FunctionType),
@@ -203,6 +320,24 @@ func MakeDefaultRuntimeValue(node ast.Type) RuntimeValue {
 		return ArrayValue{
 			Values: make([]RuntimeValue, 0),
 		}
+	case ast.MapType:
+		return MakeMAP(t)
+	case ast.FunctionType:
+		// the zero function returns the zero value of its return type
+		var returnType ast.Type = ast.VoidType{Kind: ast.T_VOID}
+		if t.ReturnType != nil {
+			returnType = t.ReturnType
+		}
+		params := make([]ast.Type, len(t.Parameters))
+		for i, param := range t.Parameters {
+			params[i] = param.Type
+		}
+		return MakeNativeFUNCTION(func(args ...RuntimeValue) (RuntimeValue, error) {
+			return MakeDefaultRuntimeValue(returnType), nil
+		}, NativeSignature{Parameters: params, ReturnType: returnType})
+	case ast.TypeParamType:
+		// nothing tells the type a parameter stands for
+		return MakeNULL()
 	default:
 		panic(fmt.Sprintf("unsupported type %T", t))
 	}
@@ -228,6 +363,10 @@ func IsTruthy(value RuntimeValue) bool {
 		return false
 	case ArrayValue:
 		return len(value.Values) > 0
+	case MapValue:
+		return value.Len() > 0
+	case StructInstance, EnumInstance:
+		return true
 	default:
 		panic(fmt.Sprintf("unsupported type %T", value))
 	}
@@ -34,6 +34,13 @@ func Colorize(color, text string) string {
 	return fmt.Sprintf("%s%s%s", color, text, RESET)
 }
 
+var colorRegex = regexp.MustCompile(`\033\[[0-9;]*m`)
+
+// Decolorize removes the colors of a text, for output which is not a terminal
+func Decolorize(text string) string {
+	return colorRegex.ReplaceAllString(text, "")
+}
+
 func IF(conddition bool, a, b any) any {
 	if conddition {
 		return a
@@ -0,0 +1,549 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+)
+
+var runtimeValueType = reflect.TypeOf((*typechecker.RuntimeValue)(nil)).Elem()
+
+// ToValue converts a go value to a runtime value. Numbers keep their bit size,
+// slices and arrays become arrays, maps become maps of the same key and value
+// types, and structs become struct instances whose type is declared for the
+// scripts on the fly.
+// Struct fields are named like encoding/json does, a `walrus:"name"` tag renames
+// a field and `walrus:"-"` hides it.
+func (vm *VM) ToValue(value interface{}) (typechecker.RuntimeValue, error) {
+
+	if value == nil {
+		return typechecker.MakeNULL(), nil
+	}
+
+	if runtimeValue, ok := value.(typechecker.RuntimeValue); ok {
+		return runtimeValue, nil
+	}
+
+	return vm.toValue(reflect.ValueOf(value))
+}
+
+func (vm *VM) toValue(value reflect.Value) (typechecker.RuntimeValue, error) {
+
+	if value.Type().Implements(runtimeValueType) && value.CanInterface() {
+		return value.Interface().(typechecker.RuntimeValue), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return typechecker.MakeBOOL(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typechecker.MakeINT(value.Int(), uint8(value.Type().Bits()), true), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d does not fit in a walrus integer", value.Uint())
+		}
+		return typechecker.MakeINT(int64(value.Uint()), uint8(value.Type().Bits()), false), nil
+	case reflect.Float32, reflect.Float64:
+		return typechecker.MakeFLOAT(value.Float(), uint8(value.Type().Bits())), nil
+	case reflect.String:
+		return typechecker.MakeSTRING(value.String()), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return typechecker.ArrayValue{Values: []typechecker.RuntimeValue{}, Type: ast.T_ARRAY}, nil
+		}
+		values := make([]typechecker.RuntimeValue, value.Len())
+		for i := range values {
+			element, err := vm.toValue(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = element
+		}
+		return typechecker.ArrayValue{Values: values, Type: ast.T_ARRAY}, nil
+	case reflect.Map:
+		return vm.mapToValue(value)
+	case reflect.Struct:
+		return vm.structToValue(value)
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return typechecker.MakeNULL(), nil
+		}
+		return vm.toValue(value.Elem())
+	default:
+		return nil, fmt.Errorf("values of go type '%s' can not be converted", value.Type())
+	}
+}
+
+// mapToValue converts a go map to a map of the same key and value types.
+// Go maps have no order, the keys are inserted sorted so scripts iterate
+// them the same way every run.
+func (vm *VM) mapToValue(value reflect.Value) (typechecker.RuntimeValue, error) {
+
+	mapType, ok := goType(value.Type()).(ast.MapType)
+
+	if !ok {
+		return nil, fmt.Errorf("maps with keys of go type '%s' can not be converted", value.Type().Key())
+	}
+
+	m := typechecker.MakeMAP(mapType)
+
+	keys := value.MapKeys()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return lessKey(keys[i], keys[j])
+	})
+
+	for _, goKey := range keys {
+
+		key, err := vm.toValue(goKey)
+		if err != nil {
+			return nil, err
+		}
+
+		element, err := vm.toValue(value.MapIndex(goKey))
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", goKey, err)
+		}
+
+		if _, err := m.Set(key, element); err != nil {
+			return nil, fmt.Errorf("key %v: %w", goKey, err)
+		}
+	}
+
+	return m, nil
+}
+
+// lessKey orders the keys of a go map, false comes before true
+func lessKey(a reflect.Value, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return false
+}
+
+func (vm *VM) structToValue(value reflect.Value) (typechecker.RuntimeValue, error) {
+
+	goStruct := value.Type()
+
+	structName := goStruct.Name()
+
+	if structName == "" {
+		structName = goStruct.String()
+	}
+
+	structValue := vm.structType(structName)
+
+	instance := typechecker.StructInstance{
+		StructName: structName,
+		Fields:     map[string]typechecker.RuntimeValue{},
+		Type:       ast.T_STRUCT,
+	}
+
+	for i := 0; i < goStruct.NumField(); i++ {
+
+		name, ok := fieldName(goStruct.Field(i))
+
+		if !ok {
+			continue
+		}
+
+		field, err := vm.toValue(value.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", name, err)
+		}
+
+		instance.Fields[name] = field
+		structValue.Fields[name] = ast.Property{Name: name, IsPublic: true, Type: goType(goStruct.Field(i).Type)}
+	}
+
+	vm.host.DeclareStructType(structName, structValue)
+
+	return instance, nil
+}
+
+// structType returns the struct type declared for a go type, or a new empty one
+func (vm *VM) structType(name string) typechecker.StructValue {
+
+	if declared, err := vm.host.GetStructType(name); err == nil {
+		if structValue, ok := declared.(typechecker.StructValue); ok {
+			return structValue
+		}
+	}
+
+	return typechecker.StructValue{
+		Fields:  map[string]ast.Property{},
+		Methods: map[string]ast.FunctionType{},
+		Type:    ast.DATA_TYPE(name),
+	}
+}
+
+// fieldName returns the walrus name of an exported struct field
+func fieldName(field reflect.StructField) (string, bool) {
+
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get("walrus")
+
+	if tag == "-" {
+		return "", false
+	}
+
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+
+	return field.Name, true
+}
+
+// goType returns the static walrus type values of a go type convert to
+func goType(t reflect.Type) ast.Type {
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return ast.BoolType{Kind: ast.T_BOOLEAN}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ast.IntegerType{Kind: ast.DATA_TYPE(fmt.Sprintf("i%d", t.Bits())), BitSize: uint8(t.Bits()), IsSigned: true}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return ast.IntegerType{Kind: ast.DATA_TYPE(fmt.Sprintf("u%d", t.Bits())), BitSize: uint8(t.Bits()), IsSigned: false}
+	case reflect.Float32, reflect.Float64:
+		return ast.FloatType{Kind: ast.DATA_TYPE(fmt.Sprintf("f%d", t.Bits())), BitSize: uint8(t.Bits())}
+	case reflect.String:
+		return ast.StringType{Kind: ast.T_STRING}
+	case reflect.Slice, reflect.Array:
+		return ast.ArrayType{Kind: ast.T_ARRAY, ElementType: goType(t.Elem()).IType()}
+	case reflect.Map:
+		// maps are keyed by strings, integers or booleans, like the ones of the scripts
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return ast.MapType{Kind: ast.T_MAP, KeyType: goType(t.Key()), ValueType: goType(t.Elem())}
+		}
+		return ast.AnyType{Kind: ast.T_ANY}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return ast.StructType{Kind: ast.DATA_TYPE(t.String())}
+		}
+		return ast.StructType{Kind: ast.DATA_TYPE(t.Name())}
+	case reflect.Pointer:
+		return goType(t.Elem())
+	default:
+		return ast.AnyType{Kind: ast.T_ANY}
+	}
+}
+
+// paramTypes returns the declared types of the parameters of a function
+func paramTypes(fn typechecker.RuntimeValue) []ast.Type {
+
+	switch f := fn.(type) {
+	case typechecker.FunctionValue:
+		types := make([]ast.Type, len(f.Parameters))
+		for i, param := range f.Parameters {
+			types[i] = param.Type
+		}
+		return types
+	case typechecker.NativeFunctionValue:
+		return f.Signature.Parameters
+	}
+
+	return nil
+}
+
+// narrow gives a converted argument the integer or float type of the
+// parameter it is passed to, go ints are i64 and go floats f64. A value out
+// of the range of the parameter is an error, other values are left as they are.
+func narrow(value typechecker.RuntimeValue, t ast.Type) (typechecker.RuntimeValue, error) {
+
+	switch t := t.(type) {
+	case ast.IntegerType:
+		v, ok := value.(typechecker.IntegerValue)
+		if !ok || v.Type == t.IType() {
+			return value, nil
+		}
+		min, max := int64(math.MinInt64), int64(math.MaxInt64)
+		switch {
+		case t.IsSigned && t.BitSize < 64:
+			min, max = -1<<(t.BitSize-1), 1<<(t.BitSize-1)-1
+		case !t.IsSigned && t.BitSize < 64:
+			min, max = 0, 1<<t.BitSize-1
+		case !t.IsSigned:
+			min = 0
+		}
+		if v.Value < min || v.Value > max {
+			return nil, fmt.Errorf("%d does not fit in '%s'", v.Value, t.IType())
+		}
+		return typechecker.MakeINT(v.Value, t.BitSize, t.IsSigned), nil
+	case ast.FloatType:
+		v, ok := value.(typechecker.FloatValue)
+		if !ok || v.Type == t.IType() {
+			return value, nil
+		}
+		if t.BitSize == 32 && math.Abs(v.Value) > math.MaxFloat32 {
+			return nil, fmt.Errorf("%g does not fit in '%s'", v.Value, t.IType())
+		}
+		return typechecker.MakeFLOAT(v.Value, t.BitSize), nil
+	}
+
+	return value, nil
+}
+
+// FromValue converts a runtime value to plain go values: int64, uint64, float64,
+// bool, string, byte, nil, []interface{}, map[string]interface{} for struct
+// instances and maps with string keys, and map[interface{}]interface{} for
+// maps with other keys
+func FromValue(value typechecker.RuntimeValue) (interface{}, error) {
+
+	switch v := value.(type) {
+	case nil, typechecker.NullValue, typechecker.VoidValue:
+		return nil, nil
+	case typechecker.IntegerValue:
+		if v.Type[0] == 'u' {
+			return uint64(v.Value), nil
+		}
+		return v.Value, nil
+	case typechecker.FloatValue:
+		return v.Value, nil
+	case typechecker.BooleanValue:
+		return v.Value, nil
+	case typechecker.StringValue:
+		return v.Value, nil
+	case typechecker.CharacterValue:
+		return v.Value, nil
+	case typechecker.ArrayValue:
+		values := make([]interface{}, len(v.Values))
+		for i, element := range v.Values {
+			converted, err := FromValue(element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = converted
+		}
+		return values, nil
+	case typechecker.StructInstance:
+		fields := make(map[string]interface{}, len(v.Fields))
+		for name, field := range v.Fields {
+			converted, err := FromValue(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = converted
+		}
+		return fields, nil
+	case typechecker.MapValue:
+		return mapFromValue(v)
+	default:
+		return nil, fmt.Errorf("values of type '%s' can not be converted to go", typechecker.GetRuntimeType(value))
+	}
+}
+
+func mapFromValue(m typechecker.MapValue) (interface{}, error) {
+
+	_, stringKeys := m.Type.KeyType.(ast.StringType)
+
+	byName := make(map[string]interface{}, m.Len())
+	others := make(map[interface{}]interface{}, m.Len())
+
+	for _, key := range m.Keys() {
+
+		element, _ := m.Get(key)
+
+		converted, err := FromValue(element)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", typechecker.FormatValue(key), err)
+		}
+
+		if stringKeys {
+			byName[key.(typechecker.StringValue).Value] = converted
+			continue
+		}
+
+		goKey, err := FromValue(key)
+		if err != nil {
+			return nil, err
+		}
+
+		others[goKey] = converted
+	}
+
+	if stringKeys {
+		return byName, nil
+	}
+
+	return others, nil
+}
+
+// Decode stores a runtime value in the go value target points to, the reverse of ToValue
+func Decode(value typechecker.RuntimeValue, target interface{}) error {
+
+	pointer := reflect.ValueOf(target)
+
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() {
+		return fmt.Errorf("decode target must be a non nil pointer, got %T", target)
+	}
+
+	return decode(value, pointer.Elem())
+}
+
+func decode(value typechecker.RuntimeValue, target reflect.Value) error {
+
+	mismatch := func() error {
+		return fmt.Errorf("can not decode value of type '%s' into go type '%s'", typechecker.GetRuntimeType(value), target.Type())
+	}
+
+	switch value.(type) {
+	case typechecker.NullValue, typechecker.VoidValue:
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if target.Type().Implements(runtimeValueType) {
+			target.Set(reflect.ValueOf(value))
+			return nil
+		}
+		converted, err := FromValue(value)
+		if err != nil {
+			return err
+		}
+		if converted != nil {
+			target.Set(reflect.ValueOf(converted))
+		}
+		return nil
+	case reflect.Pointer:
+		element := reflect.New(target.Type().Elem())
+		if err := decode(value, element.Elem()); err != nil {
+			return err
+		}
+		target.Set(element)
+		return nil
+	case reflect.Bool:
+		if v, ok := value.(typechecker.BooleanValue); ok {
+			target.SetBool(v.Value)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := value.(typechecker.IntegerValue); ok {
+			if target.OverflowInt(v.Value) {
+				return fmt.Errorf("%d overflows go type '%s'", v.Value, target.Type())
+			}
+			target.SetInt(v.Value)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch v := value.(type) {
+		case typechecker.IntegerValue:
+			if v.Value < 0 || target.OverflowUint(uint64(v.Value)) {
+				return fmt.Errorf("%d overflows go type '%s'", v.Value, target.Type())
+			}
+			target.SetUint(uint64(v.Value))
+			return nil
+		case typechecker.CharacterValue:
+			target.SetUint(uint64(v.Value))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case typechecker.FloatValue:
+			target.SetFloat(v.Value)
+			return nil
+		case typechecker.IntegerValue:
+			target.SetFloat(float64(v.Value))
+			return nil
+		}
+	case reflect.String:
+		switch v := value.(type) {
+		case typechecker.StringValue:
+			target.SetString(v.Value)
+			return nil
+		case typechecker.CharacterValue:
+			target.SetString(string(v.Value))
+			return nil
+		}
+	case reflect.Slice:
+		if v, ok := value.(typechecker.ArrayValue); ok {
+			slice := reflect.MakeSlice(target.Type(), len(v.Values), len(v.Values))
+			for i, element := range v.Values {
+				if err := decode(element, slice.Index(i)); err != nil {
+					return fmt.Errorf("index %d: %w", i, err)
+				}
+			}
+			target.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if v, ok := value.(typechecker.ArrayValue); ok {
+			if len(v.Values) > target.Len() {
+				return fmt.Errorf("array of %d elements does not fit in go type '%s'", len(v.Values), target.Type())
+			}
+			for i, element := range v.Values {
+				if err := decode(element, target.Index(i)); err != nil {
+					return fmt.Errorf("index %d: %w", i, err)
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if v, ok := value.(typechecker.MapValue); ok {
+			m := reflect.MakeMapWithSize(target.Type(), v.Len())
+			for _, key := range v.Keys() {
+				goKey := reflect.New(target.Type().Key()).Elem()
+				if err := decode(key, goKey); err != nil {
+					return fmt.Errorf("key %s: %w", typechecker.FormatValue(key), err)
+				}
+				element := reflect.New(target.Type().Elem()).Elem()
+				field, _ := v.Get(key)
+				if err := decode(field, element); err != nil {
+					return fmt.Errorf("key %s: %w", typechecker.FormatValue(key), err)
+				}
+				m.SetMapIndex(goKey, element)
+			}
+			target.Set(m)
+			return nil
+		}
+		if v, ok := value.(typechecker.StructInstance); ok && target.Type().Key().Kind() == reflect.String {
+			m := reflect.MakeMapWithSize(target.Type(), len(v.Fields))
+			for name, field := range v.Fields {
+				element := reflect.New(target.Type().Elem()).Elem()
+				if err := decode(field, element); err != nil {
+					return fmt.Errorf("key '%s': %w", name, err)
+				}
+				m.SetMapIndex(reflect.ValueOf(name).Convert(target.Type().Key()), element)
+			}
+			target.Set(m)
+			return nil
+		}
+	case reflect.Struct:
+		if v, ok := value.(typechecker.StructInstance); ok {
+			for i := 0; i < target.NumField(); i++ {
+				name, ok := fieldName(target.Type().Field(i))
+				if !ok {
+					continue
+				}
+				field, ok := v.Fields[name]
+				if !ok {
+					continue
+				}
+				if err := decode(field, target.Field(i)); err != nil {
+					return fmt.Errorf("field '%s': %w", name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return mismatch()
+}
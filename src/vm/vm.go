@@ -0,0 +1,330 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"walrus/builtins"
+	"walrus/frontend/ast"
+	"walrus/frontend/parser"
+	"walrus/tc"
+	"walrus/typechecker"
+	"walrus/utils"
+)
+
+// VM hosts walrus scripts inside a go program. Scripts loaded later see the
+// declarations of earlier ones, and no failure ever exits the process.
+//
+//	machine := vm.New()
+//	machine.SetGlobal("limit", 10)
+//	machine.LoadSource("main.wal", source)
+//	result, err := machine.Call("main")
+//
+// A VM runs one script or call at a time and is not safe for concurrent use.
+type VM struct {
+	// natives and values registered by the host, shadowing the builtins
+	host      *typechecker.Environment
+	hostTypes *tc.TypeEnv
+	// scope of the last loaded script
+	scope *typechecker.Environment
+	types *tc.TypeEnv
+	// applied afresh to every load and call
+	limits typechecker.Limits
+	// paths the fs natives may touch, nothing until the host allows it
+	fs *builtins.FsCapability
+}
+
+func New() *VM {
+
+	fs := builtins.NewFsCapability()
+
+	globals := typechecker.NewEnvironment(nil, nil)
+	builtins.Declare(globals, fs)
+
+	globalTypes := tc.NewTypeEnv(nil)
+	builtins.DeclareTypes(globalTypes)
+
+	host := typechecker.NewEnvironment(globals, nil)
+	hostTypes := tc.NewTypeEnv(globalTypes)
+
+	return &VM{
+		host:      host,
+		hostTypes: hostTypes,
+		scope:     host,
+		types:     hostTypes,
+		limits:    typechecker.DefaultLimits,
+		fs:        fs,
+	}
+}
+
+// Fs returns the capability the fs natives of this VM check paths against.
+// It denies everything until the host allows directories:
+//
+//	machine.Fs().AllowRead("/srv/data")
+//	machine.Fs().AllowWrite("/srv/data/out")
+func (vm *VM) Fs() *builtins.FsCapability {
+	return vm.fs
+}
+
+// SetLimits bounds every later load and call. Exceeding a limit fails the
+// run with a *typechecker.RuntimeError.
+func (vm *VM) SetLimits(limits typechecker.Limits) {
+	vm.limits = limits
+}
+
+// start resets the counters of the limits for a new run
+func (vm *VM) start(ctx context.Context) {
+
+	limits := vm.limits
+
+	if ctx != nil {
+		limits.Context = ctx
+	}
+
+	vm.host.SetLimits(limits)
+}
+
+// LoadFile reads a script from disk and loads it
+func (vm *VM) LoadFile(filePath string) error {
+
+	bytes, err := os.ReadFile(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	return vm.LoadSource(filePath, string(bytes))
+}
+
+// LoadSource parses, checks and runs the top level of a script.
+// The name is only used in error reports.
+func (vm *VM) LoadSource(name string, source string) error {
+	return vm.LoadSourceContext(context.Background(), name, source)
+}
+
+// LoadSourceContext is LoadSource stopping the script once ctx is done
+func (vm *VM) LoadSourceContext(ctx context.Context, name string, source string) (err error) {
+	_, err = vm.EvalContext(ctx, name, source)
+	return err
+}
+
+// Eval loads a script like LoadSource and returns the value of its last
+// statement when that is an expression, nil otherwise
+func (vm *VM) Eval(name string, source string) (typechecker.RuntimeValue, error) {
+	return vm.EvalContext(context.Background(), name, source)
+}
+
+// EvalContext is Eval stopping the script once ctx is done
+func (vm *VM) EvalContext(ctx context.Context, name string, source string) (result typechecker.RuntimeValue, err error) {
+
+	defer recoverError(&err)
+
+	vm.start(ctx)
+
+	program, p, types, err := vm.check(name, source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the trailing expression is evaluated on its own to keep its value
+	var last ast.Node
+
+	if n := len(program.Contents); n > 0 && ast.IsExpression(program.Contents[n-1]) {
+		last = program.Contents[n-1]
+		program.Contents = program.Contents[:n-1]
+	}
+
+	scope := typechecker.NewEnvironment(vm.scope, p)
+
+	typechecker.Evaluate(program, scope)
+
+	if last != nil {
+		result = typechecker.Evaluate(last, scope)
+	}
+
+	// only a script that ran to the end becomes visible to later ones
+	vm.scope, vm.types = scope, types
+
+	return result, nil
+}
+
+// TypeOf returns the static type of the last statement of a source without
+// running it, nil when the checker can not tell
+func (vm *VM) TypeOf(name string, source string) (t ast.Type, err error) {
+
+	defer recoverError(&err)
+
+	program, _, types, err := vm.check(name, source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(program.Contents)
+
+	if n == 0 || !ast.IsExpression(program.Contents[n-1]) {
+		return nil, fmt.Errorf("%s: not an expression", name)
+	}
+
+	// the declarations before it are already in types, the expression only needs its own type
+	return tc.CheckType(program.Contents[n-1], types)
+}
+
+// Reset forgets every loaded script, values and natives registered by the host stay
+func (vm *VM) Reset() {
+	vm.scope, vm.types = vm.host, vm.hostTypes
+}
+
+// check parses a source and checks it in a scope below the loaded scripts
+func (vm *VM) check(name string, source string) (ast.ProgramStmt, *parser.Parser, *tc.TypeEnv, error) {
+
+	p, err := parser.NewParserFromSource(source, name, false)
+
+	if err != nil {
+		return ast.ProgramStmt{}, nil, nil, err
+	}
+
+	p.PanicOnError = true
+
+	program := p.Parse()
+
+	types := tc.NewTypeEnv(vm.types)
+
+	if _, err := tc.CheckType(program, types); err != nil {
+		if typeErr, ok := err.(tc.TypeError); ok {
+			// reported like parse errors, with the source line
+			return program, p, types, parser.MakeError(p, typeErr.Start.Line, name, typeErr.Start, typeErr.End, typeErr.Message)
+		}
+		return program, p, types, err
+	}
+
+	return program, p, types, nil
+}
+
+// Call calls a function declared by a loaded script or registered by the host.
+// Arguments are converted with ToValue, then to the declared types of the
+// parameters: a go int given to an i32 parameter becomes an i32 when it fits.
+func (vm *VM) Call(fnName string, args ...interface{}) (typechecker.RuntimeValue, error) {
+	return vm.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext is Call stopping the function once ctx is done
+func (vm *VM) CallContext(ctx context.Context, fnName string, args ...interface{}) (result typechecker.RuntimeValue, err error) {
+
+	defer recoverError(&err)
+
+	vm.start(ctx)
+
+	fn, err := vm.scope.GetRuntimeValue(fnName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params := paramTypes(fn)
+
+	values := make([]typechecker.RuntimeValue, len(args))
+
+	for i, arg := range args {
+		values[i], err = vm.ToValue(arg)
+		if err == nil && len(params) > 0 {
+			// the last type is the one of a variadic parameter, the extra arguments all have it
+			values[i], err = narrow(values[i], params[utils.Min(i, len(params)-1)])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of '%s': %w", i+1, fnName, err)
+		}
+	}
+
+	return typechecker.CallFunction(fn, values...)
+}
+
+// Get returns the current value of a variable visible to the loaded scripts
+func (vm *VM) Get(name string) (typechecker.RuntimeValue, error) {
+	return vm.scope.GetRuntimeValue(name)
+}
+
+// SetGlobal declares a constant visible to every script or replaces its value.
+// The value is converted with ToValue.
+func (vm *VM) SetGlobal(name string, value interface{}) error {
+
+	runtimeValue, err := vm.ToValue(value)
+
+	if err != nil {
+		return fmt.Errorf("global '%s': %w", name, err)
+	}
+
+	vm.host.SetVariable(name, runtimeValue, true)
+	vm.hostTypes.SetVar(name, typeOf(runtimeValue), true)
+
+	return nil
+}
+
+// RegisterNative makes a go function callable from scripts. Calls are checked
+// against the signature both statically and before the function runs.
+func (vm *VM) RegisterNative(name string, fn typechecker.FunctionCall, signature typechecker.NativeSignature) error {
+
+	if fn == nil {
+		return fmt.Errorf("native '%s' has no function", name)
+	}
+
+	native := typechecker.MakeNativeFUNCTION(fn, signature)
+
+	vm.host.SetVariable(name, native, true)
+	vm.hostTypes.SetVar(name, signature.FunctionType(), true)
+
+	return nil
+}
+
+// RegisterModule makes a native module importable by name, e.g. "app::db"
+func (vm *VM) RegisterModule(module typechecker.ModuleValue) error {
+
+	if err := vm.host.DeclareModule(module.Name, module); err != nil {
+		return err
+	}
+
+	return vm.hostTypes.DeclareModule(module.Name, builtins.ModuleType(module))
+}
+
+// recoverError turns the panics used to report errors while evaluating into a returned error
+func recoverError(err *error) {
+
+	recovered := recover()
+
+	if recovered == nil {
+		return
+	}
+
+	// parser.ErrorMessage is an error carrying the source position
+	switch e := recovered.(type) {
+	case error:
+		*err = e
+	default:
+		*err = fmt.Errorf("%v", e)
+	}
+}
+
+// typeOf returns the static type of a host value, nil when the checker can not use it
+func typeOf(value typechecker.RuntimeValue) ast.Type {
+
+	switch v := value.(type) {
+	case typechecker.IntegerValue:
+		return ast.IntegerType{Kind: v.Type, BitSize: v.Size, IsSigned: v.Type[0] == 'i'}
+	case typechecker.FloatValue:
+		return ast.FloatType{Kind: v.Type, BitSize: v.Size}
+	case typechecker.BooleanValue:
+		return ast.BoolType{Kind: ast.T_BOOLEAN}
+	case typechecker.StringValue:
+		return ast.StringType{Kind: ast.T_STRING}
+	case typechecker.CharacterValue:
+		return ast.CharType{Kind: ast.T_CHARACTER}
+	case typechecker.StructInstance:
+		return ast.StructType{Kind: ast.DATA_TYPE(v.StructName)}
+	case typechecker.MapValue:
+		return v.Type
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,448 @@
+package vm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"walrus/frontend/ast"
+	"walrus/typechecker"
+	"walrus/vm"
+)
+
+// load returns a VM with a script loaded, the test stops when it fails
+func load(t *testing.T, source string) *vm.VM {
+
+	t.Helper()
+
+	machine := vm.New()
+
+	if err := machine.LoadSource("test.wal", source); err != nil {
+		t.Fatalf("loading the script: %v", err)
+	}
+
+	return machine
+}
+
+func TestCall(t *testing.T) {
+
+	machine := load(t, `
+fn add(a: i32, b: i32) -> i32 {
+    ret a + b;
+}
+
+fn half(x: f32) -> f32 {
+    ret x / 2.0;
+}
+
+fn count(xs: ...u8) -> i64 {
+    ret len(xs);
+}
+`)
+
+	result, err := machine.Call("add", 1, 2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sum, ok := result.(typechecker.IntegerValue); !ok || sum.Value != 3 || sum.Type != ast.T_INTEGER32 {
+		t.Errorf("add(1, 2) = %#v, want the i32 3", result)
+	}
+
+	result, err = machine.Call("half", 3.0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if half, ok := result.(typechecker.FloatValue); !ok || half.Value != 1.5 {
+		t.Errorf("half(3.0) = %#v, want 1.5", result)
+	}
+
+	if _, err := machine.Call("count", 1, 2, 3); err != nil {
+		t.Errorf("count(1, 2, 3): %v", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		args []interface{}
+		want string
+	}{
+		{"add", []interface{}{1 << 40, 1}, "argument 1 of 'add': 1099511627776 does not fit in 'i32'"},
+		{"count", []interface{}{1, 256}, "argument 2 of 'count': 256 does not fit in 'u8'"},
+		{"add", []interface{}{"one", 2}, "expected type 'i32' but got 'str'"},
+		{"missing", nil, "missing"},
+	} {
+		if _, err := machine.Call(test.name, test.args...); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s%v: got %v, want an error containing %q", test.name, test.args, err, test.want)
+		}
+	}
+}
+
+func TestSetGlobal(t *testing.T) {
+
+	machine := vm.New()
+
+	if err := machine.SetGlobal("limit", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.SetGlobal("ages", map[string]int{"ada": 36, "alan": 41}); err != nil {
+		t.Fatal(err)
+	}
+
+	type User struct {
+		Name   string `walrus:"name"`
+		Secret string `walrus:"-"`
+	}
+
+	if err := machine.SetGlobal("user", User{Name: "ada", Secret: "hidden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for source, want := range map[string]interface{}{
+		`limit * 2;`:                  int64(20),
+		`ages["alan"] - ages["ada"];`: int64(5),
+		`user.name;`:                  "ada",
+		`keys(ages)[0];`:              "ada",
+		`"grace" in ages;`:            false,
+	} {
+		result, err := machine.Eval("test.wal", source)
+
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+
+		if got, _ := vm.FromValue(result); got != want {
+			t.Errorf("%s = %#v, want %#v", source, got, want)
+		}
+	}
+
+	// a global can be replaced, the scripts loaded later see the new value
+	machine.SetGlobal("limit", 3)
+
+	if result, _ := machine.Eval("test.wal", `limit;`); !reflect.DeepEqual(result, typechecker.MakeINT(3, 64, true)) {
+		t.Errorf("limit = %#v after replacing it, want 3", result)
+	}
+
+	if err := machine.SetGlobal("bad", map[float64]int{1.5: 1}); err == nil {
+		t.Error("a map with float keys was converted")
+	}
+}
+
+func TestEval(t *testing.T) {
+
+	machine := vm.New()
+
+	if result, err := machine.Eval("first.wal", "let x := 20;\nfn twice(n: i32) -> i32 {\n    ret n * 2;\n}\n"); err != nil || result != nil {
+		t.Fatalf("a script ending with a declaration gave %#v, %v", result, err)
+	}
+
+	// later scripts see the declarations of earlier ones
+	result, err := machine.Eval("second.wal", "twice(x) + 2;")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := vm.FromValue(result); got != int64(42) {
+		t.Errorf("twice(x) + 2 = %#v, want 42", got)
+	}
+
+	for _, test := range []struct {
+		source string
+		want   string
+	}{
+		{"let y := ;", "broken.wal:1:10"},
+		{`twice("a");`, "expects argument 1 to be of type 'i32' but got 'str'"},
+		{`let m := map{"a": 1};` + "\n" + `m["b"];`, `map has no key "b"`},
+	} {
+		if _, err := machine.Eval("broken.wal", test.source); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want an error containing %q", test.source, err, test.want)
+		}
+	}
+
+	// a failed script leaves the loaded ones alone
+	if _, err := machine.Eval("third.wal", "x;"); err != nil {
+		t.Errorf("x is gone after a failed script: %v", err)
+	}
+}
+
+func TestRegisterNative(t *testing.T) {
+
+	machine := vm.New()
+
+	calls := 0
+
+	err := machine.RegisterNative("double", func(args ...typechecker.RuntimeValue) (typechecker.RuntimeValue, error) {
+		calls++
+		n := args[0].(typechecker.IntegerValue)
+		return typechecker.MakeINT(n.Value*2, 64, true), nil
+	}, typechecker.NativeSignature{
+		Parameters: []ast.Type{ast.IntegerType{Kind: ast.T_INTEGER64, BitSize: 64, IsSigned: true}},
+		ReturnType: ast.IntegerType{Kind: ast.T_INTEGER64, BitSize: 64, IsSigned: true},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := machine.Eval("test.wal", "double(21);")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := vm.FromValue(result); got != int64(42) || calls != 1 {
+		t.Errorf("double(21) = %#v after %d calls, want 42 after 1", got, calls)
+	}
+
+	// the signature is checked before the native runs
+	if _, err := machine.Eval("test.wal", `double("a");`); err == nil || calls != 1 {
+		t.Errorf("double(\"a\") ran the native or passed: %v", err)
+	}
+
+	if result, err := machine.Call("double", 4); err != nil {
+		t.Error(err)
+	} else if got, _ := vm.FromValue(result); got != int64(8) {
+		t.Errorf("Call(double, 4) = %#v, want 8", got)
+	}
+
+	if err := machine.RegisterNative("nothing", nil, typechecker.NativeSignature{}); err == nil {
+		t.Error("a native without a function was registered")
+	}
+}
+
+func TestDecode(t *testing.T) {
+
+	type Point struct {
+		X    int    `walrus:"x"`
+		Y    int    `walrus:"y"`
+		Name string `walrus:"name"`
+	}
+
+	machine := load(t, `
+struct Point {
+    pub x: i32;
+    pub y: i32;
+    pub name: str;
+}
+
+fn origin() -> Point {
+    ret Point{x: 1, y: 2, name: "origin"};
+}
+
+fn scores() -> map[str]i32 {
+    ret map{"ada": 3, "alan": 5};
+}
+
+fn flags() -> map[i32]bool {
+    ret map{1: true, 2: false};
+}
+`)
+
+	result, err := machine.Call("origin")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var point Point
+
+	if err := vm.Decode(result, &point); err != nil {
+		t.Fatal(err)
+	}
+
+	if point != (Point{X: 1, Y: 2, Name: "origin"}) {
+		t.Errorf("decoded %+v", point)
+	}
+
+	result, err = machine.Call("scores")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scores map[string]int
+
+	if err := vm.Decode(result, &scores); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(scores, map[string]int{"ada": 3, "alan": 5}) {
+		t.Errorf("decoded %v", scores)
+	}
+
+	if plain, err := vm.FromValue(result); err != nil || !reflect.DeepEqual(plain, map[string]interface{}{"ada": int64(3), "alan": int64(5)}) {
+		t.Errorf("FromValue gave %#v, %v", plain, err)
+	}
+
+	result, err = machine.Call("flags")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var flags map[int8]bool
+
+	if err := vm.Decode(result, &flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(flags, map[int8]bool{1: true, 2: false}) {
+		t.Errorf("decoded %v", flags)
+	}
+
+	var small uint8
+
+	if err := vm.Decode(typechecker.MakeINT(300, 32, true), &small); err == nil {
+		t.Error("300 was decoded into an uint8")
+	}
+
+	if err := vm.Decode(typechecker.MakeSTRING("a"), &point); err == nil {
+		t.Error("a string was decoded into a struct")
+	}
+
+	if err := vm.Decode(typechecker.MakeSTRING("a"), point); err == nil {
+		t.Error("decoded into a value that is not a pointer")
+	}
+}
+
+func TestCancellation(t *testing.T) {
+
+	machine := load(t, `
+fn spin() {
+    let x := 1;
+    while x > 0 {
+        x = x + 1;
+    }
+}
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := machine.CallContext(ctx, "spin")
+
+	var runtimeErr *typechecker.RuntimeError
+
+	if !errors.As(err, &runtimeErr) || runtimeErr.Kind != typechecker.CANCELLED {
+		t.Fatalf("got %v, want a cancelled run", err)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("%v does not wrap the context error", err)
+	}
+
+	// a cancelled context stops a script before it gets far
+	done, stop := context.WithCancel(context.Background())
+	stop()
+
+	if _, err := machine.EvalContext(done, "test.wal", "spin();"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the script cancelled", err)
+	}
+
+	// the next run gets a fresh context
+	if _, err := machine.Eval("test.wal", "1 + 1;"); err != nil {
+		t.Errorf("the VM is unusable after a cancelled run: %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), bytes.Repeat([]byte("x"), 20000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		limits typechecker.Limits
+		source string
+		kind   typechecker.LIMIT_KIND
+	}{
+		{"steps", typechecker.Limits{MaxSteps: 1000}, "while true {}", typechecker.STEP_LIMIT},
+		{"call depth", typechecker.Limits{MaxCallDepth: 50}, "fn down(n: i32) -> i32 {\n    ret down(n + 1);\n}\ndown(0);", typechecker.CALL_DEPTH_LIMIT},
+		{"memory", typechecker.Limits{MaxMemory: 10000}, "while true {\n    [1, 2, 3];\n}", typechecker.MEMORY_LIMIT},
+		// natives count the values they build like the operators do
+		{"append", typechecker.Limits{MaxMemory: 10000}, "let xs: []i32 = [];\nlet i := 0;\nwhile i < 3000 {\n    xs = append(xs, i);\n    i = i + 1;\n}", typechecker.MEMORY_LIMIT},
+		{"keys", typechecker.Limits{MaxMemory: 10000}, "let m := map{1: 1, 2: 2, 3: 3};\nwhile true {\n    keys(m);\n}", typechecker.MEMORY_LIMIT},
+		{"fs.read", typechecker.Limits{MaxMemory: 10000}, "import \"core::fs\";\nfs.read(\"" + filepath.ToSlash(filepath.Join(dir, "big.txt")) + "\");", typechecker.MEMORY_LIMIT},
+	} {
+		machine := vm.New()
+		machine.Fs().AllowRead(dir)
+		machine.SetLimits(test.limits)
+
+		_, err := machine.Eval("test.wal", test.source)
+
+		var runtimeErr *typechecker.RuntimeError
+
+		if !errors.As(err, &runtimeErr) || runtimeErr.Kind != test.kind {
+			t.Errorf("%s: got %v, want the %s exceeded", test.name, err, test.kind)
+		}
+	}
+}
+
+func TestFilesystem(t *testing.T) {
+
+	dir := t.TempDir()
+
+	for name, size := range map[string]int{"small.txt": 10, "big.txt": 2000, "sub/large.txt": 4000, "sub/skip/huge.txt": 8000} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	machine := vm.New()
+
+	if err := machine.Fs().AllowRead(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.SetGlobal("dir", filepath.ToSlash(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		source string
+		want   []interface{}
+	}{
+		{
+			"import { glob } from \"core::fs\";\nlet names: []str = [];\nforeach f in glob(dir + \"/**/*.txt\") where f.size > 1024 {\n    names = append(names, f.name);\n}\nnames;",
+			[]interface{}{"big.txt", "large.txt", "huge.txt"},
+		},
+		{
+			// the visitor skips the directory named skip and everything below it
+			"import \"core::fs\";\nlet names: []str = [];\nfs.walk(dir, fn(entry: FileEntry) -> bool {\n    if !entry.isDir {\n        names = append(names, entry.name);\n    }\n    ret entry.name != \"skip\";\n});\nnames;",
+			[]interface{}{"big.txt", "small.txt", "large.txt"},
+		},
+		{
+			"let xs: []i32 = [];\nforeach i in 0..4 where i != 2 {\n    xs = append(xs, i);\n}\nforeach i in 3..3 {\n    xs = append(xs, i);\n}\nxs;",
+			[]interface{}{int64(0), int64(1), int64(3)},
+		},
+	} {
+		result, err := machine.Eval("test.wal", test.source)
+
+		if err != nil {
+			t.Errorf("%s\n%v", test.source, err)
+			continue
+		}
+
+		if got, _ := vm.FromValue(result); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s\ngave %#v, want %#v", test.source, got, test.want)
+		}
+	}
+}